@@ -1,18 +1,35 @@
 package main
 
 import (
-	"cinema/internal/audit"
 	"cinema/internal/auth"
 	"cinema/internal/cache"
+	"cinema/internal/concession"
 	"cinema/internal/config"
 	"cinema/internal/db"
+	"cinema/internal/giftcard"
+	"cinema/internal/groupbooking"
 	"cinema/internal/http/handler"
 	"cinema/internal/http/middleware"
+	"cinema/internal/loyalty"
 	"cinema/internal/model"
+	"cinema/internal/promo"
+	"cinema/internal/realtime"
+	"cinema/internal/refund"
 	"cinema/internal/repo"
 	"cinema/internal/seatlock"
+	"cinema/internal/seatrepair"
+	"cinema/internal/showtime"
+	"cinema/internal/transfer"
+	"cinema/internal/waitlist"
+	"cinema/internal/waitroom"
+	"cinema/internal/worker"
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -26,7 +43,9 @@ func main() {
 		panic(err)
 	}
 
-	rootCtx := context.Background()
+	// cancelled on SIGINT/SIGTERM -> graceful shutdown below
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// connect mongo
 	mongoConn, err := db.ConnectMongo(rootCtx, cfg.MongoURI)
@@ -49,25 +68,176 @@ func main() {
 	userRepo := repo.NewUserRepo(mongoConn.DB)
 	auditRepo := repo.NewAuditRepo(mongoConn.DB)
 	bookingRepo := repo.NewBookingRepo(mongoConn.DB)
+	promoRepo := repo.NewPromoRepo(mongoConn.DB)
+	concessionRepo := repo.NewConcessionRepo(mongoConn.DB)
+	loyaltyRepo := repo.NewLoyaltyRepo(mongoConn.DB)
+	giftCardRepo := repo.NewGiftCardRepo(mongoConn.DB)
+	transferRepo := repo.NewTransferRepo(mongoConn.DB)
+	showtimeRepo := repo.NewShowtimeRepo(mongoConn.DB)
+	refundJobRepo := repo.NewRefundJobRepo(mongoConn.DB)
+	{
+		ictx, cancel := context.WithTimeout(rootCtx, 5*time.Second)
+		if err := promoRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("promo indexes:", err)
+		}
+		if err := concessionRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("concession indexes:", err)
+		}
+		if err := loyaltyRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("loyalty indexes:", err)
+		}
+		if err := giftCardRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("gift card indexes:", err)
+		}
+		if err := transferRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("transfer indexes:", err)
+		}
+		if err := showtimeRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("showtime indexes:", err)
+		}
+		if err := refundJobRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("refund job indexes:", err)
+		}
+		if err := bookingRepo.EnsureIndexes(ictx); err != nil {
+			log.Println("booking indexes:", err)
+		}
+		cancel()
+	}
+
+	// SeatLock service (TTL, expiry warning, seat map + selection rules)
+	seatLockSvc, seatMap, err := seatlock.NewFromConfig(redisClient, cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	// showtime lifecycle + sales windows (the scheduler runs in the workers)
+	showtimeSvc := showtime.New(showtimeRepo, seatLockSvc)
+	// hall-wide seat blocks follow the showtime's hall
+	seatLockSvc.WithHalls(showtimeSvc.Hall)
+
+	// concessions: stock reserved with seat holds (stock sweep runs in the workers)
+	concessionSvc := concession.New(concessionRepo, seatLockSvc)
+
+	// loyalty: points + tiers (earning runs in the workers on booking-events)
+	loyaltySvc := loyalty.New(loyaltyRepo)
+
+	// waitlist: freed seats are offered to waiting users (offers run in the workers)
+	waitlistSvc := waitlist.New(redisClient, seatLockSvc, seatMap, time.Duration(cfg.WaitlistOfferSecs)*time.Second).
+		WithShowtimes(showtimeSvc)
+	showtimeSvc.OnSalesEnded(waitlistSvc.Clear)
 
-	// background workers
-	go audit.Run(rootCtx, redisClient, auditRepo)
-	go seatlock.StartTimeoutSweeper(rootCtx, redisClient)
+	// waiting room for high-demand on-sales (admission runs in the workers)
+	room := waitroom.New(redisClient, jwtSvc, time.Duration(cfg.WaitingRoomAdmissionSecs)*time.Second)
+
+	// payments-side services (also used by the refund job)
+	promoSvc := promo.New(promoRepo)
+	giftCardSvc := giftcard.New(giftCardRepo)
+
+	// bulk refunds of cancelled showtimes (the job runs in the workers)
+	refundSvc := refund.New(redisClient, seatLockSvc, bookingRepo, refundJobRepo, promoSvc, concessionSvc, loyaltySvc, giftCardSvc)
+
+	// seat support tools + Redis–Mongo reconciler (scheduled in the workers)
+	seatRepairSvc := seatrepair.New(redisClient, seatLockSvc, bookingRepo, showtimeRepo, auditRepo)
+
+	// ticket transfers (interrupted acceptances are finished in the workers)
+	transferSvc := transfer.New(redisClient, seatLockSvc, bookingRepo, transferRepo, userRepo, auditRepo)
+
+	// background workers (singletons: only the elected leader runs them).
+	// Set RUN_WORKERS=false when they run in cmd/worker instead.
+	workerDeps := worker.Deps{
+		Cfg:         cfg,
+		Redis:       redisClient,
+		Audits:      auditRepo,
+		Waitlist:    waitlistSvc,
+		Room:        room,
+		Concessions: concessionSvc,
+		Loyalty:     loyaltySvc,
+		Showtimes:   showtimeSvc,
+		Refunds:     refundSvc,
+		Reconciler:  seatRepairSvc,
+		Transfers:   transferSvc,
+	}
+	elector := worker.NewElector(workerDeps)
+
+	// lock/confirm stay gated until a rebuild writes seatstate:ready; the
+	// guard only runs on the worker leader, so rebuild here while there is none
+	go seatRepairSvc.Standby(rootCtx, elector.Leader)
+
+	var workersDone <-chan struct{}
+	if cfg.RunWorkers {
+		workersDone = worker.Start(rootCtx, elector, workerDeps)
+	}
+
+	// realtime hub: one Redis subscription per active showtime, shared by all tabs.
+	// It gets its own context so it outlives rootCtx until WebSockets are drained.
+	slowPolicy := realtime.DisconnectSlow
+	if cfg.WSSlowPolicy == "drop" {
+		slowPolicy = realtime.DropSlow
+	}
+	hub := realtime.NewHub(redisClient, cfg.WSSendQueue, slowPolicy)
+	hubCtx, hubCancel := context.WithCancel(context.Background())
+	defer hubCancel()
+	go hub.Run(hubCtx)
+
+	// live viewer counts + soft seat intents
+	presence := realtime.NewPresence(redisClient, hub)
+	go presence.Run(hubCtx)
 
 	// WebSocket handler
-	seatWS := handler.NewSeatWSHandler(redisClient, jwtSvc)
+	seatWS := handler.NewSeatWSHandler(hub, presence, seatLockSvc, jwtSvc, room)
+
+	// private per-user notifications (WS + SSE)
+	userEvents := handler.NewUserEventsHandler(hub, jwtSvc)
+
+	// SSE handler (same events/hub, for networks that block WebSockets)
+	seatSSE := handler.NewSeatSSEHandler(hub, seatLockSvc)
 
-	// SeatLock service + handler
-	seatTTL := time.Duration(cfg.SeatLockTTLSeconds) * time.Second
-	seatLockSvc := seatlock.New(redisClient, seatTTL)
-	seatLockHandler := handler.NewSeatLockHandler(seatLockSvc, cfg.SeatLockTTLSeconds)
+	// Group booking (split payment)
+	groupSvc := groupbooking.New(redisClient, seatLockSvc)
+
+	seatLockHandler := handler.NewSeatLockHandler(seatLockSvc, cfg.SeatLockTTLSeconds, room, loyaltySvc, showtimeSvc, groupSvc)
+
+	// Gift cards (stored value, ledger in Mongo)
+	giftCardHandler := handler.NewGiftCardHandler(giftCardSvc)
 
 	// Booking handler
-	bookingHandler := handler.NewBookingHandler(seatLockSvc, bookingRepo, redisClient)
+	bookingHandler := handler.NewBookingHandler(seatLockSvc, bookingRepo, redisClient, promoSvc, concessionSvc, loyaltySvc, giftCardSvc, showtimeSvc)
+
+	// Concessions (catalog, add-ons on a hold, pickup)
+	concessionHandler := handler.NewConcessionHandler(concessionSvc, concessionRepo)
+
+	// Tickets + transfers between users
+	ticketHandler := handler.NewTicketHandler(
+		jwtSvc,
+		bookingRepo,
+		userRepo,
+		transferSvc,
+		cfg.FrontendURL,
+	)
+
+	// Showtimes (public listing, admin scheduling + cancellation)
+	showtimeHandler := handler.NewShowtimeHandler(showtimeSvc, seatLockSvc, waitlistSvc, room, refundSvc)
+
+	// Admin seat blocks (per showtime or hall-wide)
+	seatBlockHandler := handler.NewSeatBlockHandler(seatLockSvc, showtimeSvc, seatMap)
+
+	// Waitlist handler
+	waitlistHandler := handler.NewWaitlistHandler(waitlistSvc, loyaltySvc)
+
+	// Loyalty handler
+	loyaltyHandler := handler.NewLoyaltyHandler(loyaltySvc)
+
+	// Group booking (split payment) handler
+	groupHandler := handler.NewGroupBookingHandler(groupSvc, cfg.FrontendURL)
+
+	// Waiting room handler
+	waitingRoomHandler := handler.NewWaitingRoomHandler(room, cfg.WaitingRoomCapacity)
 
 	// Admin handlers
 	adminBookingHandler := handler.NewAdminBookingHandler(bookingRepo)
 	adminAuditHandler := handler.NewAdminAuditHandler(auditRepo)
+	adminPromoHandler := handler.NewAdminPromoHandler(promoRepo)
+	adminSeatHandler := handler.NewAdminSeatHandler(seatRepairSvc)
 
 	// Google OAuth handler (ADMIN_EMAILS integrated via cfg.AdminEmails)
 	ga := handler.NewGoogleAuthHandler(userRepo, jwtSvc, cfg.FrontendURL, cfg.AdminEmails)
@@ -80,7 +250,7 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Last-Event-ID", "X-Admission-Token"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -92,13 +262,17 @@ func main() {
 
 		mongoOK := mongoConn.Client.Ping(ctx, nil) == nil
 		redisOK := redisClient.Ping(ctx).Err() == nil
+		leaderID, _ := elector.Leader(ctx)
 
 		c.JSON(http.StatusOK, gin.H{
-			"ok":       true,
-			"env":      cfg.AppEnv,
-			"port":     cfg.Port,
-			"mongo_ok": mongoOK,
-			"redis_ok": redisOK,
+			"ok":          true,
+			"env":         cfg.AppEnv,
+			"port":        cfg.Port,
+			"mongo_ok":    mongoOK,
+			"redis_ok":    redisOK,
+			"instance_id": elector.ID(),
+			"is_leader":   elector.IsLeader(),
+			"leader":      leaderID,
 		})
 	})
 
@@ -136,6 +310,9 @@ func main() {
 			})
 		})
 
+		// Loyalty points / tier
+		api.GET("/me/loyalty", middleware.AuthRequired(jwtSvc), loyaltyHandler.Me)
+
 		// Admin (guard ด้วย role=ADMIN)
 		admin := api.Group("/admin",
 			middleware.AuthRequired(jwtSvc),
@@ -144,27 +321,153 @@ func main() {
 		{
 			admin.GET("/bookings", adminBookingHandler.List)
 			admin.GET("/audit", adminAuditHandler.List)
+			admin.POST("/promos", adminPromoHandler.Create)
+			admin.GET("/promos", adminPromoHandler.List)
+			admin.PATCH("/promos/:promoId", adminPromoHandler.Update)
+			admin.DELETE("/promos/:promoId", adminPromoHandler.Deactivate)
+			admin.POST("/gift-cards", giftCardHandler.AdminIssue)
+			admin.GET("/gift-cards/:code", giftCardHandler.AdminGet)
+			admin.POST("/tickets/verify", ticketHandler.Verify)
+			admin.DELETE("/gift-cards/:code", giftCardHandler.AdminDeactivate)
+			admin.POST("/cinemas/:cinemaId/concessions", concessionHandler.Create)
+			admin.PATCH("/concessions/:itemId", concessionHandler.Update)
+			admin.POST("/concessions/pickup", concessionHandler.PickUp)
+			admin.POST("/showtimes", showtimeHandler.Create)
+			admin.GET("/showtimes", showtimeHandler.AdminList)
+			admin.PATCH("/showtimes/:showtimeId", showtimeHandler.Update)
+			admin.POST("/showtimes/:showtimeId/publish", showtimeHandler.Publish)
+			admin.POST("/showtimes/:showtimeId/cancel", showtimeHandler.Cancel)
+			admin.GET("/showtimes/:showtimeId/refunds", showtimeHandler.Refunds)
+			admin.GET("/showtimes/:showtimeId/blocks", seatBlockHandler.List)
+			admin.POST("/showtimes/:showtimeId/blocks", seatBlockHandler.Block)
+			admin.POST("/showtimes/:showtimeId/blocks/release", seatBlockHandler.Release)
+			admin.GET("/halls/:hall/blocks", seatBlockHandler.HallList)
+			admin.POST("/halls/:hall/blocks", seatBlockHandler.HallBlock)
+			admin.POST("/halls/:hall/blocks/release", seatBlockHandler.HallRelease)
+			admin.POST("/showtimes/:showtimeId/seats/force-release", adminSeatHandler.ForceRelease)
+			admin.POST("/showtimes/:showtimeId/seats/repair", adminSeatHandler.Repair)
+			admin.POST("/seats/reconcile", adminSeatHandler.Reconcile)
+			admin.GET("/seats/reconcile", adminSeatHandler.LastReconcile)
+			admin.POST("/seats/rebuild", adminSeatHandler.Rebuild)
+			admin.GET("/seats/rebuild", adminSeatHandler.RebuildStatus)
+			admin.PUT("/showtimes/:showtimeId/waiting-room", waitingRoomHandler.Open)
+			admin.DELETE("/showtimes/:showtimeId/waiting-room", waitingRoomHandler.Close)
 			admin.GET("/ping", func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"ok": true, "admin": true})
 			})
 		}
 
+		// Group bookings: invitees claim + pay their own seats
+		groups := api.Group("/groups/:groupId", middleware.AuthRequired(jwtSvc))
+		{
+			groups.GET("", groupHandler.Get)
+			groups.POST("/claim", groupHandler.Claim)
+		}
+
+		// Concessions catalog
+		api.GET("/cinemas/:cinemaId/concessions", middleware.AuthRequired(jwtSvc), concessionHandler.Catalog)
+
+		// Gift cards
+		api.POST("/gift-cards", middleware.AuthRequired(jwtSvc), giftCardHandler.Purchase)
+		api.GET("/gift-cards/:code", middleware.AuthRequired(jwtSvc), giftCardHandler.Balance)
+
+		// Bookings (owner)
+		bookings := api.Group("/bookings/:bookingId", middleware.AuthRequired(jwtSvc))
+		{
+			bookings.POST("/cancel", bookingHandler.Cancel)
+			bookings.POST("/exchange", bookingHandler.Exchange)
+			bookings.GET("/tickets", ticketHandler.List)
+			bookings.POST("/transfers", ticketHandler.Offer)
+		}
+
+		// Ticket transfers (recipient side)
+		api.GET("/me/transfers", middleware.AuthRequired(jwtSvc), ticketHandler.Incoming)
+		transfers := api.Group("/transfers/:transferId", middleware.AuthRequired(jwtSvc))
+		{
+			transfers.GET("", ticketHandler.Get)
+			transfers.POST("/accept", ticketHandler.Accept)
+			transfers.DELETE("", ticketHandler.Cancel)
+		}
+
 		// Showtime scoped routes
+		api.GET("/showtimes", middleware.AuthRequired(jwtSvc), showtimeHandler.List)
 		st := api.Group("/showtimes/:showtimeId", middleware.AuthRequired(jwtSvc))
 		{
+			st.GET("", showtimeHandler.Get)
+
 			// Seat lock
 			st.POST("/seats/lock", seatLockHandler.Lock)
 			st.DELETE("/seats/lock", seatLockHandler.Release)
-			st.GET("/seats/locks", seatLockHandler.ListLocks)
+			st.GET("/seats/locks", middleware.RequireRole(model.RoleAdmin), seatLockHandler.ListLocks)
 			st.GET("/seats/state", seatLockHandler.SeatState)
 
 			// Booking confirm
 			st.POST("/bookings/confirm", bookingHandler.Confirm)
+
+			// Concessions add-ons of the current hold
+			st.PUT("/concessions", concessionHandler.Reserve)
+			st.GET("/concessions", concessionHandler.Current)
+
+			// Group booking: organizer turns a hold into a group
+			st.POST("/groups", groupHandler.Create)
+
+			// Waiting room (queue ticket / admission token)
+			st.POST("/waiting-room", waitingRoomHandler.Enter)
+
+			// Waitlist (sold-out showtimes)
+			st.POST("/waitlist", waitlistHandler.Join)
+			st.GET("/waitlist", waitlistHandler.Status)
+			st.DELETE("/waitlist", waitlistHandler.Leave)
 		}
 	}
 
 	// WebSocket
 	r.GET("/ws/showtimes/:showtimeId/seats", seatWS.Seats)
 
-	_ = r.Run(":" + cfg.Port)
+	// Server-Sent Events (header auth)
+	r.GET("/sse/showtimes/:showtimeId/seats", middleware.AuthRequired(jwtSvc), seatSSE.Seats)
+
+	// private per-user events
+	r.GET("/ws/me/events", userEvents.WS)
+	r.GET("/sse/me/events", middleware.AuthRequired(jwtSvc), userEvents.SSE)
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+	// SSE streams are regular requests: end them so Shutdown doesn't wait on them
+	srv.RegisterOnShutdown(seatSSE.Drain)
+	srv.RegisterOnShutdown(userEvents.StopSSE)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("http server failed:", err)
+			stop()
+		}
+	}()
+
+	<-rootCtx.Done()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSecs)*time.Second)
+	defer cancel()
+
+	// 1) stop accepting HTTP, wait for in-flight requests
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("http shutdown:", err)
+	}
+
+	// 2) hijacked WebSockets are not covered by Shutdown: close them explicitly
+	seatWS.Drain(shutdownCtx)
+	userEvents.Drain(shutdownCtx)
+	hubCancel()
+
+	// 3) workers were cancelled with rootCtx; wait for audit flush
+	if cfg.RunWorkers {
+		select {
+		case <-workersDone:
+		case <-shutdownCtx.Done():
+			log.Println("workers shutdown timed out")
+		}
+	}
 }
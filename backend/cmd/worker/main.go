@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cinema/internal/auth"
+	"cinema/internal/cache"
+	"cinema/internal/concession"
+	"cinema/internal/config"
+	"cinema/internal/db"
+	"cinema/internal/giftcard"
+	"cinema/internal/loyalty"
+	"cinema/internal/promo"
+	"cinema/internal/refund"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"cinema/internal/seatrepair"
+	"cinema/internal/showtime"
+	"cinema/internal/transfer"
+	"cinema/internal/waitlist"
+	"cinema/internal/waitroom"
+	"cinema/internal/worker"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// worker runs the background workers (timeout sweeper/listener, audit, waitlist,
+// waiting room admission, concession stock sweep, loyalty points, showtime
+// scheduler, refund jobs, seat reconciler, seat state rebuild, transfer recovery)
+// without the HTTP API. Several replicas may run; leader election keeps
+// exactly one active.
+func main() {
+	// load config
+	cfg, err := config.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	// cancelled on SIGINT/SIGTERM
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// connect mongo
+	mongoConn, err := db.ConnectMongo(rootCtx, cfg.MongoURI)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = mongoConn.Client.Disconnect(context.Background()) }()
+
+	// connect redis
+	redisClient, err := cache.ConnectRedis(rootCtx, cfg.RedisAddr)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = redisClient.Close() }()
+
+	seatLockSvc, seatMap, err := seatlock.NewFromConfig(redisClient, cfg)
+	if err != nil {
+		panic(err)
+	}
+	showtimeSvc := showtime.New(repo.NewShowtimeRepo(mongoConn.DB), seatLockSvc)
+	seatLockSvc.WithHalls(showtimeSvc.Hall)
+	offerTTL := time.Duration(cfg.WaitlistOfferSecs) * time.Second
+	admitTTL := time.Duration(cfg.WaitingRoomAdmissionSecs) * time.Second
+
+	waitlistSvc := waitlist.New(redisClient, seatLockSvc, seatMap, offerTTL).WithShowtimes(showtimeSvc)
+	showtimeSvc.OnSalesEnded(waitlistSvc.Clear)
+
+	concessionSvc := concession.New(repo.NewConcessionRepo(mongoConn.DB), seatLockSvc)
+	loyaltySvc := loyalty.New(repo.NewLoyaltyRepo(mongoConn.DB))
+	bookingRepo := repo.NewBookingRepo(mongoConn.DB)
+	auditRepo := repo.NewAuditRepo(mongoConn.DB)
+	refundSvc := refund.New(
+		redisClient,
+		seatLockSvc,
+		bookingRepo,
+		repo.NewRefundJobRepo(mongoConn.DB),
+		promo.New(repo.NewPromoRepo(mongoConn.DB)),
+		concessionSvc,
+		loyaltySvc,
+		giftcard.New(repo.NewGiftCardRepo(mongoConn.DB)),
+	)
+
+	deps := worker.Deps{
+		Cfg:         cfg,
+		Redis:       redisClient,
+		Audits:      auditRepo,
+		Waitlist:    waitlistSvc,
+		Room:        waitroom.New(redisClient, auth.NewJWTService(cfg.JWTSecret), admitTTL),
+		Concessions: concessionSvc,
+		Loyalty:     loyaltySvc,
+		Showtimes:   showtimeSvc,
+		Refunds:     refundSvc,
+		Reconciler:  seatrepair.New(redisClient, seatLockSvc, bookingRepo, repo.NewShowtimeRepo(mongoConn.DB), auditRepo),
+		Transfers: transfer.New(
+			redisClient,
+			seatLockSvc,
+			bookingRepo,
+			repo.NewTransferRepo(mongoConn.DB),
+			repo.NewUserRepo(mongoConn.DB),
+			auditRepo,
+		),
+	}
+	elector := worker.NewElector(deps)
+	workersDone := worker.Start(rootCtx, elector, deps)
+
+	log.Printf("worker %s started", elector.ID())
+	<-rootCtx.Done()
+	log.Println("worker: shutting down")
+
+	select {
+	case <-workersDone:
+	case <-time.After(time.Duration(cfg.ShutdownTimeoutSecs) * time.Second):
+		log.Println("worker: shutdown timed out")
+	}
+}
@@ -33,6 +33,8 @@ type bookingEvent struct {
 	At        int64    `json:"at"`
 }
 
+// Run consumes seat/booking events into audit_logs until ctx is cancelled.
+// On cancel it unsubscribes and flushes messages already received before returning.
 func Run(ctx context.Context, rdb *redis.Client, audits *repo.AuditRepo) {
 	// pattern สำหรับ seat-events:*
 	ps := rdb.PSubscribe(ctx, "seat-events:*")
@@ -48,54 +50,93 @@ func Run(ctx context.Context, rdb *redis.Client, audits *repo.AuditRepo) {
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			// stop receiving, then flush what is already buffered
+			_ = ps.Close()
+			_ = bs.Close()
+			for {
+				select {
+				case msg, ok := <-seatCh:
+					if !ok {
+						seatCh = nil
+						continue
+					}
+					insertSeatEvent(ctx, audits, msg)
+				case msg, ok := <-bookCh:
+					if !ok {
+						bookCh = nil
+						continue
+					}
+					insertBookingEvent(ctx, audits, msg)
+				default:
+					return
+				}
+			}
 
 		case msg := <-seatCh:
-			var ev seatEvent
-			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
-				continue
-			}
+			insertSeatEvent(ctx, audits, msg)
 
-			// map เป็น audit type ให้ชัด
-			t := "seat." + strings.ToLower(ev.Type) // locked/released/booked/timeout
-			at := time.Unix(ev.At, 0)
-			if ev.At == 0 {
-				at = time.Now()
-			}
+		case msg := <-bookCh:
+			insertBookingEvent(ctx, audits, msg)
+		}
+	}
+}
 
-			_ = audits.Insert(ctx, &model.AuditLog{
-				Type:       t,
-				ShowtimeID: ev.ShowtimeID,
-				BookingID:  ev.BookingID,
-				UserID:     ev.Owner,
-				SeatIDs:    ev.SeatIDs,
-				RequestID:  ev.RequestID,
-				Payload:    json.RawMessage([]byte(msg.Payload)),
-				At:         at,
-			})
+// writes must survive worker cancellation so in-flight events are not lost
+func insertCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+}
 
-		case msg := <-bookCh:
-			var ev bookingEvent
-			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
-				continue
-			}
+func insertSeatEvent(ctx context.Context, audits *repo.AuditRepo, msg *redis.Message) {
+	var ev seatEvent
+	if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+		return
+	}
 
-			at := time.Unix(ev.At, 0)
-			if ev.At == 0 {
-				at = time.Now()
-			}
+	// map เป็น audit type ให้ชัด
+	t := "seat." + strings.ToLower(ev.Type) // locked/released/booked/timeout/blocked/unblocked
+	at := time.Unix(ev.At, 0)
+	if ev.At == 0 {
+		at = time.Now()
+	}
 
-			if err := audits.Insert(ctx, &model.AuditLog{
-				Type:       ev.Type, // booking.success
-				ShowtimeID: ev.Showtime,
-				BookingID:  ev.BookingID,
-				UserID:     ev.UserID,
-				SeatIDs:    ev.SeatIDs,
-				Payload:    json.RawMessage([]byte(msg.Payload)),
-				At:         at,
-			}); err != nil {
-				log.Println("audit insert failed:", err)
-			}
-		}
+	ictx, cancel := insertCtx(ctx)
+	defer cancel()
+
+	_ = audits.Insert(ictx, &model.AuditLog{
+		Type:       t,
+		ShowtimeID: ev.ShowtimeID,
+		BookingID:  ev.BookingID,
+		UserID:     ev.Owner,
+		SeatIDs:    ev.SeatIDs,
+		RequestID:  ev.RequestID,
+		Payload:    json.RawMessage([]byte(msg.Payload)),
+		At:         at,
+	})
+}
+
+func insertBookingEvent(ctx context.Context, audits *repo.AuditRepo, msg *redis.Message) {
+	var ev bookingEvent
+	if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+		return
+	}
+
+	at := time.Unix(ev.At, 0)
+	if ev.At == 0 {
+		at = time.Now()
+	}
+
+	ictx, cancel := insertCtx(ctx)
+	defer cancel()
+
+	if err := audits.Insert(ictx, &model.AuditLog{
+		Type:       ev.Type, // booking.success, booking.cancelled, booking.exchanged
+		ShowtimeID: ev.Showtime,
+		BookingID:  ev.BookingID,
+		UserID:     ev.UserID,
+		SeatIDs:    ev.SeatIDs,
+		Payload:    json.RawMessage([]byte(msg.Payload)),
+		At:         at,
+	}); err != nil {
+		log.Println("audit insert failed:", err)
 	}
 }
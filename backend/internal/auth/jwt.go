@@ -59,5 +59,117 @@ func (j *JWTService) Verify(tokenStr string) (*Claims, error) {
 	if !ok || !t.Valid {
 		return nil, errors.New("invalid token")
 	}
+	// session tokens carry no audience; scoped tokens (admission) are not logins
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// audience of waiting room admission tokens
+const admissionAudience = "admission"
+
+// AdmissionClaims admit one user to lock seats of one showtime.
+type AdmissionClaims struct {
+	ShowtimeID string `json:"showtime_id"`
+	jwt.RegisteredClaims
+}
+
+// SignAdmission issues a waiting room admission token valid until expiresAt.
+func (j *JWTService) SignAdmission(userID, showtimeID string, expiresAt time.Time) (string, error) {
+	claims := AdmissionClaims{
+		ShowtimeID: showtimeID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{admissionAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString(j.secret)
+}
+
+// VerifyAdmission checks an admission token for userID and showtimeID.
+func (j *JWTService) VerifyAdmission(tokenStr, userID, showtimeID string) error {
+	t, err := jwt.ParseWithClaims(
+		tokenStr,
+		&AdmissionClaims{},
+		func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return j.secret, nil
+		},
+		jwt.WithAudience(admissionAudience),
+	)
+	if err != nil {
+		return err
+	}
+
+	claims, ok := t.Claims.(*AdmissionClaims)
+	if !ok || !t.Valid {
+		return errors.New("invalid token")
+	}
+	if claims.Subject != userID || claims.ShowtimeID != showtimeID {
+		return errors.New("admission token not for this user/showtime")
+	}
+	return nil
+}
+
+// audience of booking tickets
+const ticketAudience = "ticket"
+
+// TicketClaims are one seat of one booking. Version must match the booking's
+// ticket version: bumping it (transfers) invalidates every older ticket.
+type TicketClaims struct {
+	BookingID  string `json:"booking_id"`
+	ShowtimeID string `json:"showtime_id"`
+	SeatID     string `json:"seat_id"`
+	Version    int    `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// SignTicket issues a ticket for userID. Tickets don't expire by time; they
+// are checked against the booking when scanned.
+func (j *JWTService) SignTicket(userID, bookingID, showtimeID, seatID string, version int) (string, error) {
+	claims := TicketClaims{
+		BookingID:  bookingID,
+		ShowtimeID: showtimeID,
+		SeatID:     seatID,
+		Version:    version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  userID,
+			Audience: jwt.ClaimStrings{ticketAudience},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString(j.secret)
+}
+
+// VerifyTicket checks a ticket's signature and returns its claims.
+func (j *JWTService) VerifyTicket(tokenStr string) (*TicketClaims, error) {
+	t, err := jwt.ParseWithClaims(
+		tokenStr,
+		&TicketClaims{},
+		func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return j.secret, nil
+		},
+		jwt.WithAudience(ticketAudience),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := t.Claims.(*TicketClaims)
+	if !ok || !t.Valid {
+		return nil, errors.New("invalid token")
+	}
 	return claims, nil
 }
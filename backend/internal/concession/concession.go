@@ -0,0 +1,220 @@
+package concession
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"context"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Food & drinks add-ons. Items are reserved against a seat hold (owner +
+// request id): stock is taken from the catalog right away and given back
+// when the hold ends without a booking (swept by Run), when the booking
+// fails, or when it is cancelled before pickup. Confirm turns the
+// reservation into part of the booking with a pickup code.
+
+// Error is a concession rejection; Code is the API error string.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string { return "concession: " + e.Code }
+
+var (
+	ErrNotHeld     = &Error{Code: "seats_not_held"}
+	ErrUnknownItem = &Error{Code: "concession_not_found"}
+	ErrOutOfStock  = &Error{Code: "concession_out_of_stock"}
+	ErrExpired     = &Error{Code: "concessions_expired"}
+)
+
+const (
+	MaxLines   = 10
+	MaxLineQty = 20
+)
+
+// Hold identifies the seat hold a reservation follows.
+type Hold struct {
+	CinemaID   string
+	ShowtimeID string
+	UserID     string
+	RequestID  string
+	SeatIDs    []string
+}
+
+// LineReq is one requested item.
+type LineReq struct {
+	ItemID primitive.ObjectID
+	Qty    int64
+}
+
+type Service struct {
+	repo  *repo.ConcessionRepo
+	seats *seatlock.Service
+}
+
+func New(r *repo.ConcessionRepo, seats *seatlock.Service) *Service {
+	return &Service{repo: r, seats: seats}
+}
+
+// Reserve replaces the hold's reservation with lines (none = clear it).
+func (s *Service) Reserve(ctx context.Context, h Hold, lines []LineReq) (*model.ConcessionReservation, error) {
+	holds, err := s.seats.Inspect(ctx, h.ShowtimeID, h.SeatIDs)
+	if err != nil {
+		return nil, err
+	}
+	var ttl time.Duration
+	for _, sh := range holds {
+		if sh.Owner != h.UserID || sh.RequestID != h.RequestID || sh.TTL <= 0 {
+			return nil, ErrNotHeld
+		}
+		if ttl == 0 || sh.TTL < ttl {
+			ttl = sh.TTL
+		}
+	}
+
+	// one reservation per hold: give back the previous one first
+	if prev, err := s.ForHold(ctx, h.ShowtimeID, h.UserID, h.RequestID); err != nil {
+		return nil, err
+	} else if prev != nil {
+		if err := s.release(ctx, prev, model.ConcessionHeld); err != nil {
+			return nil, err
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(lines))
+	for _, l := range lines {
+		ids = append(ids, l.ItemID)
+	}
+	items, err := s.repo.FindItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]model.ConcessionItem, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+
+	res := &model.ConcessionReservation{
+		CinemaID:   h.CinemaID,
+		ShowtimeID: h.ShowtimeID,
+		UserID:     h.UserID,
+		RequestID:  h.RequestID,
+		SeatIDs:    h.SeatIDs,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	for _, l := range lines {
+		it, ok := byID[l.ItemID]
+		if !ok || !it.Active || it.CinemaID != h.CinemaID {
+			return nil, ErrUnknownItem
+		}
+		res.Items = append(res.Items, model.ConcessionLine{
+			ItemID:    it.ID,
+			Name:      it.Name,
+			Qty:       l.Qty,
+			UnitPrice: it.Price,
+		})
+	}
+
+	// take stock line by line; undo on the first shortage
+	for i, l := range res.Items {
+		ok, err := s.repo.TakeStock(ctx, l.ItemID, l.Qty)
+		if err != nil || !ok {
+			s.returnStock(ctx, res.Items[:i])
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrOutOfStock
+		}
+	}
+
+	if err := s.repo.InsertReservation(ctx, res); err != nil {
+		s.returnStock(ctx, res.Items)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ForHold returns the hold's HELD reservation, or nil.
+func (s *Service) ForHold(ctx context.Context, showtimeID, userID, requestID string) (*model.ConcessionReservation, error) {
+	res, err := s.repo.FindHeld(ctx, showtimeID, userID, requestID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// Consume attaches a HELD reservation to bookingID with its pickup code.
+func (s *Service) Consume(ctx context.Context, res *model.ConcessionReservation, bookingID primitive.ObjectID, pickupCode string) error {
+	ok, err := s.repo.Transition(ctx, res.ID, model.ConcessionHeld, model.ConcessionConsumed, bson.M{
+		"booking_id":  bookingID,
+		"pickup_code": pickupCode,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrExpired
+	}
+	return nil
+}
+
+// ReleaseBooking gives back the stock of bookingID's add-ons (failed or
+// cancelled booking). Picked up orders are kept. Safe to call more than once.
+func (s *Service) ReleaseBooking(ctx context.Context, bookingID primitive.ObjectID) error {
+	res, err := s.repo.FindByBooking(ctx, bookingID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if res.Status != model.ConcessionConsumed {
+		return nil
+	}
+	return s.release(ctx, res, model.ConcessionConsumed)
+}
+
+// PickUp marks the order with code as handed over.
+func (s *Service) PickUp(ctx context.Context, code string) (*model.ConcessionReservation, error) {
+	return s.repo.PickUp(ctx, code)
+}
+
+func (s *Service) release(ctx context.Context, res *model.ConcessionReservation, from model.ConcessionResStatus) error {
+	ok, err := s.repo.Transition(ctx, res.ID, from, model.ConcessionReleased, nil)
+	if err != nil || !ok {
+		return err
+	}
+	s.returnStock(ctx, res.Items)
+	return nil
+}
+
+func (s *Service) returnStock(ctx context.Context, lines []model.ConcessionLine) {
+	for _, l := range lines {
+		_ = s.repo.ReturnStock(ctx, l.ItemID, l.Qty)
+	}
+}
+
+// unambiguous characters (no 0/O, 1/I)
+const pickupAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewPickupCode returns a random 8-character counter code.
+func NewPickupCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = pickupAlphabet[int(b[i])%len(pickupAlphabet)]
+	}
+	return string(b), nil
+}
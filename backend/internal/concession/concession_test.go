@@ -0,0 +1,63 @@
+package concession
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestReserveNeedsHold(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	seats := seatlock.New(rdb, time.Minute)
+	if err := seats.BeginRebuild(ctx, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := seats.LockSeats(ctx, "st1", []string{"A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	// rejected before the catalog is touched
+	s := New(nil, seats)
+
+	tests := []struct {
+		name string
+		hold Hold
+	}{
+		{name: "other user", hold: Hold{ShowtimeID: "st1", UserID: "u2", RequestID: "r1", SeatIDs: []string{"A1"}}},
+		{name: "other request", hold: Hold{ShowtimeID: "st1", UserID: "u1", RequestID: "r2", SeatIDs: []string{"A1"}}},
+		{name: "seat not locked", hold: Hold{ShowtimeID: "st1", UserID: "u1", RequestID: "r1", SeatIDs: []string{"A1", "A2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Reserve(ctx, tt.hold, nil); !errors.Is(err, ErrNotHeld) {
+				t.Fatalf("Reserve: err = %v, want %v", err, ErrNotHeld)
+			}
+		})
+	}
+}
+
+func TestNewPickupCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		code, err := NewPickupCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 8 || strings.Trim(code, pickupAlphabet) != "" {
+			t.Fatalf("code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 45 {
+		t.Fatalf("only %d distinct codes out of 50", len(seen))
+	}
+}
@@ -0,0 +1,64 @@
+package concession
+
+import (
+	"cinema/internal/model"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	sweepEvery = 10 * time.Second
+	sweepBatch = 200
+)
+
+// Run gives back the stock of reservations whose seat hold ended until ctx
+// is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *Service) sweep(ctx context.Context) {
+	held, err := s.repo.FindHeldBatch(ctx, sweepBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("concession sweep failed:", err)
+		}
+		return
+	}
+
+	for i := range held {
+		res := &held[i]
+		holds, err := s.seats.Inspect(ctx, res.ShowtimeID, res.SeatIDs)
+		if err != nil {
+			continue
+		}
+
+		// the hold lives while any of its seats is still locked by it
+		// (extended holds just move expires_at)
+		var ttl time.Duration
+		for _, h := range holds {
+			if h.Owner == res.UserID && h.RequestID == res.RequestID && h.TTL > ttl {
+				ttl = h.TTL
+			}
+		}
+		if ttl > 0 {
+			_ = s.repo.TouchHeld(ctx, res.ID, time.Now().Add(ttl))
+			continue
+		}
+
+		if err := s.release(ctx, res, model.ConcessionHeld); err != nil && ctx.Err() == nil {
+			log.Println("concession release failed:", res.ID.Hex(), err)
+		}
+	}
+}
@@ -17,6 +17,36 @@ type Config struct {
 	CORSOrigins        []string
 	SeatLockTTLSeconds int
 	AdminEmails        []string
+
+	// "expiring_soon" notification offset before lock TTL (0 = off)
+	SeatLockWarnBeforeSecs int
+
+	// waitlist: how long an offered hold waits to be claimed
+	WaitlistOfferSecs int
+
+	// waiting room: admission token lifetime + default admitted users per showtime
+	WaitingRoomAdmissionSecs int
+	WaitingRoomCapacity      int
+
+	// seat selection rules
+	SeatMap            string // "A=SSSS_SSSS;B=..." (empty = built-in demo hall)
+	SeatGapRuleEnabled bool
+
+	// lock expiry detection: "sweep" (poll every second) | "notify" (keyspace events)
+	SeatExpiryMode            string
+	SeatReconcileIntervalSecs int
+
+	// leader election for singleton background workers
+	InstanceID         string
+	LeaderLeaseSeconds int
+
+	// realtime fan-out (per-client send queue, slow consumer policy "disconnect"|"drop")
+	WSSendQueue  int
+	WSSlowPolicy string
+
+	// process
+	RunWorkers          bool // run background workers inside the API process
+	ShutdownTimeoutSecs int
 }
 
 func Load() (Config, error) {
@@ -29,6 +59,82 @@ func Load() (Config, error) {
 		return Config{}, fmt.Errorf("invalid SEAT_LOCK_TTL_SECONDS: %s", ttlStr)
 	}
 
+	warnStr := getenv("SEAT_LOCK_WARN_BEFORE_SECONDS", "60")
+	warnSec, err := strconv.Atoi(warnStr)
+	if err != nil || warnSec < 0 {
+		return Config{}, fmt.Errorf("invalid SEAT_LOCK_WARN_BEFORE_SECONDS: %s", warnStr)
+	}
+	if warnSec >= ttlSec {
+		if os.Getenv("SEAT_LOCK_WARN_BEFORE_SECONDS") != "" {
+			return Config{}, fmt.Errorf("SEAT_LOCK_WARN_BEFORE_SECONDS must be less than SEAT_LOCK_TTL_SECONDS")
+		}
+		warnSec = 0 // default offset doesn't fit a short TTL: no warning
+	}
+
+	offerStr := getenv("WAITLIST_OFFER_SECONDS", "120")
+	offerSec, err := strconv.Atoi(offerStr)
+	if err != nil || offerSec <= 0 {
+		return Config{}, fmt.Errorf("invalid WAITLIST_OFFER_SECONDS: %s", offerStr)
+	}
+
+	admissionStr := getenv("WAITING_ROOM_ADMISSION_SECONDS", "300")
+	admissionSec, err := strconv.Atoi(admissionStr)
+	if err != nil || admissionSec <= 0 {
+		return Config{}, fmt.Errorf("invalid WAITING_ROOM_ADMISSION_SECONDS: %s", admissionStr)
+	}
+
+	roomCapStr := getenv("WAITING_ROOM_CAPACITY", "200")
+	roomCap, err := strconv.Atoi(roomCapStr)
+	if err != nil || roomCap <= 0 {
+		return Config{}, fmt.Errorf("invalid WAITING_ROOM_CAPACITY: %s", roomCapStr)
+	}
+
+	gapRuleStr := getenv("SEAT_GAP_RULE_ENABLED", "true")
+	gapRule, err := strconv.ParseBool(gapRuleStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SEAT_GAP_RULE_ENABLED: %s", gapRuleStr)
+	}
+
+	expiryMode := strings.ToLower(getenv("SEAT_EXPIRY_MODE", "sweep"))
+	if expiryMode != "sweep" && expiryMode != "notify" {
+		return Config{}, fmt.Errorf("invalid SEAT_EXPIRY_MODE: %s", expiryMode)
+	}
+
+	reconcileStr := getenv("SEAT_RECONCILE_INTERVAL_SECONDS", "30")
+	reconcileSec, err := strconv.Atoi(reconcileStr)
+	if err != nil || reconcileSec <= 0 {
+		return Config{}, fmt.Errorf("invalid SEAT_RECONCILE_INTERVAL_SECONDS: %s", reconcileStr)
+	}
+
+	leaseStr := getenv("LEADER_LEASE_SECONDS", "15")
+	leaseSec, err := strconv.Atoi(leaseStr)
+	if err != nil || leaseSec < 3 {
+		return Config{}, fmt.Errorf("invalid LEADER_LEASE_SECONDS: %s", leaseStr)
+	}
+
+	runWorkersStr := getenv("RUN_WORKERS", "true")
+	runWorkers, err := strconv.ParseBool(runWorkersStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid RUN_WORKERS: %s", runWorkersStr)
+	}
+
+	shutdownStr := getenv("SHUTDOWN_TIMEOUT_SECONDS", "15")
+	shutdownSec, err := strconv.Atoi(shutdownStr)
+	if err != nil || shutdownSec <= 0 {
+		return Config{}, fmt.Errorf("invalid SHUTDOWN_TIMEOUT_SECONDS: %s", shutdownStr)
+	}
+
+	sendQueueStr := getenv("WS_SEND_QUEUE", "64")
+	sendQueue, err := strconv.Atoi(sendQueueStr)
+	if err != nil || sendQueue <= 0 {
+		return Config{}, fmt.Errorf("invalid WS_SEND_QUEUE: %s", sendQueueStr)
+	}
+
+	slowPolicy := strings.ToLower(getenv("WS_SLOW_POLICY", "disconnect"))
+	if slowPolicy != "disconnect" && slowPolicy != "drop" {
+		return Config{}, fmt.Errorf("invalid WS_SLOW_POLICY: %s", slowPolicy)
+	}
+
 	adminEmailsRaw := getenv("ADMIN_EMAILS", "")
 	adminEmails := normalizeEmails(splitCSV(adminEmailsRaw))
 
@@ -42,6 +148,27 @@ func Load() (Config, error) {
 		CORSOrigins:        splitCSV(corsOrigins),
 		SeatLockTTLSeconds: ttlSec,
 		AdminEmails:        adminEmails,
+
+		SeatLockWarnBeforeSecs: warnSec,
+		WaitlistOfferSecs:      offerSec,
+
+		WaitingRoomAdmissionSecs: admissionSec,
+		WaitingRoomCapacity:      roomCap,
+
+		SeatMap:            getenv("SEAT_MAP", ""),
+		SeatGapRuleEnabled: gapRule,
+
+		SeatExpiryMode:            expiryMode,
+		SeatReconcileIntervalSecs: reconcileSec,
+
+		InstanceID:         getenv("INSTANCE_ID", defaultInstanceID()),
+		LeaderLeaseSeconds: leaseSec,
+
+		WSSendQueue:  sendQueue,
+		WSSlowPolicy: slowPolicy,
+
+		RunWorkers:          runWorkers,
+		ShutdownTimeoutSecs: shutdownSec,
 	}
 
 	if cfg.MongoURI == "" {
@@ -60,6 +187,15 @@ func Load() (Config, error) {
 	return cfg, nil
 }
 
+// hostname is the container id under compose; pid disambiguates local runs
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "api"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 func getenv(key, fallback string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -0,0 +1,238 @@
+package giftcard
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Gift cards. The balance of a card is its ledger: every issue, redemption
+// and refund appends an entry with the next seq and the balance after it.
+// Appends race on the unique (card_id, seq) index, so two redemptions can't
+// both spend the same balance; the loser re-reads and retries.
+
+// Error is a gift card rejection; Code is the API error string.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string { return "giftcard: " + e.Code }
+
+var (
+	ErrNotFound         = &Error{Code: "gift_card_not_found"}
+	ErrInactive         = &Error{Code: "gift_card_inactive"}
+	ErrCurrencyMismatch = &Error{Code: "gift_card_currency_mismatch"}
+	ErrEmpty            = &Error{Code: "gift_card_empty"}
+	ErrInsufficient     = &Error{Code: "gift_card_insufficient_balance"}
+)
+
+const (
+	MaxAmount   = 50000 // per card, currency units
+	codeLen     = 16
+	maxAttempts = 5
+)
+
+// unambiguous characters (no 0/O, 1/I)
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NormalizeCode uppercases a code and drops spaces/dashes.
+func NormalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func newCode() (string, error) {
+	b := make([]byte, codeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b), nil
+}
+
+type Service struct {
+	repo *repo.GiftCardRepo
+}
+
+func New(r *repo.GiftCardRepo) *Service {
+	return &Service{repo: r}
+}
+
+// Issue creates a card with a fresh code and its ISSUE entry. The card is
+// inserted inactive and activated once the entry is written, so it is
+// never usable without a balance; if the entry fails the card is deleted.
+func (s *Service) Issue(ctx context.Context, amount int64, currency string, source model.GiftCardSource, issuedBy, paymentRef string) (*model.GiftCard, error) {
+	g := &model.GiftCard{
+		Currency:   currency,
+		Initial:    amount,
+		Source:     source,
+		IssuedBy:   issuedBy,
+		PaymentRef: paymentRef,
+	}
+
+	for attempt := 0; ; attempt++ {
+		code, err := newCode()
+		if err != nil {
+			return nil, err
+		}
+		g.Code = code
+		err = s.repo.CreateCard(ctx, g)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt+1 >= maxAttempts {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.AppendEntry(ctx, &model.GiftCardEntry{
+		CardID:       g.ID,
+		Seq:          1,
+		Kind:         model.GiftCardIssue,
+		Amount:       amount,
+		BalanceAfter: amount,
+	}); err != nil {
+		_ = s.repo.DeleteCard(context.WithoutCancel(ctx), g.ID)
+		return nil, err
+	}
+	return s.repo.SetActive(ctx, g.ID, true)
+}
+
+// Lookup returns the card with code and its balance.
+func (s *Service) Lookup(ctx context.Context, code string) (*model.GiftCard, int64, error) {
+	g, err := s.repo.FindByCode(ctx, NormalizeCode(code))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	last, err := s.last(ctx, g.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, last.BalanceAfter, nil
+}
+
+// Ledger returns all entries of a card, oldest first.
+func (s *Service) Ledger(ctx context.Context, cardID primitive.ObjectID) ([]model.GiftCardEntry, error) {
+	return s.repo.FindEntries(ctx, cardID)
+}
+
+func (s *Service) SetActive(ctx context.Context, cardID primitive.ObjectID, active bool) (*model.GiftCard, error) {
+	return s.repo.SetActive(ctx, cardID, active)
+}
+
+// Quote checks code can pay in currency and returns how much of due it
+// covers (its balance, at most due).
+func (s *Service) Quote(ctx context.Context, code, currency string, due int64) (*model.GiftCard, int64, error) {
+	g, balance, err := s.Lookup(ctx, code)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !g.Active {
+		return nil, 0, ErrInactive
+	}
+	if g.Currency != currency {
+		return nil, 0, ErrCurrencyMismatch
+	}
+	if balance <= 0 {
+		return nil, 0, ErrEmpty
+	}
+	return g, min(balance, due), nil
+}
+
+// Redeem takes amount off the card for bookingID.
+func (s *Service) Redeem(ctx context.Context, g *model.GiftCard, bookingID primitive.ObjectID, amount int64) error {
+	return s.append(ctx, g.ID, func(balance int64) (*model.GiftCardEntry, error) {
+		if balance < amount {
+			return nil, ErrInsufficient
+		}
+		return &model.GiftCardEntry{
+			Kind:      model.GiftCardRedeem,
+			Amount:    -amount,
+			BookingID: &bookingID,
+		}, nil
+	})
+}
+
+// RefundBooking puts back what bookingID took from a card, if anything.
+// Safe to call more than once.
+func (s *Service) RefundBooking(ctx context.Context, bookingID primitive.ObjectID) error {
+	red, err := s.repo.FindBookingEntry(ctx, bookingID, model.GiftCardRedeem)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.append(ctx, red.CardID, func(int64) (*model.GiftCardEntry, error) {
+		return &model.GiftCardEntry{
+			Kind:      model.GiftCardRefund,
+			Amount:    -red.Amount,
+			BookingID: &bookingID,
+		}, nil
+	})
+	if errors.Is(err, errAlreadyRecorded) {
+		return nil
+	}
+	return err
+}
+
+var errAlreadyRecorded = errors.New("booking entry already recorded")
+
+// append builds the next entry from the current balance and inserts it,
+// retrying when another writer took the seq first.
+func (s *Service) append(ctx context.Context, cardID primitive.ObjectID, next func(balance int64) (*model.GiftCardEntry, error)) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		last, err := s.last(ctx, cardID)
+		if err != nil {
+			return err
+		}
+		e, err := next(last.BalanceAfter)
+		if err != nil {
+			return err
+		}
+		e.CardID = cardID
+		e.Seq = last.Seq + 1
+		e.BalanceAfter = last.BalanceAfter + e.Amount
+
+		err = s.repo.AppendEntry(ctx, e)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		// lost the seq race, or this booking already has such an entry
+		if e.BookingID != nil {
+			if _, err := s.repo.FindBookingEntry(ctx, *e.BookingID, e.Kind); err == nil {
+				return errAlreadyRecorded
+			}
+		}
+	}
+	return errors.New("giftcard: too much contention")
+}
+
+func (s *Service) last(ctx context.Context, cardID primitive.ObjectID) (*model.GiftCardEntry, error) {
+	last, err := s.repo.LastEntry(ctx, cardID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// issued but ISSUE entry not written yet
+		return &model.GiftCardEntry{}, nil
+	}
+	return last, err
+}
@@ -0,0 +1,36 @@
+package giftcard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCode(t *testing.T) {
+	tests := map[string]string{
+		"abcd-efgh-jkmn-pqrs": "ABCDEFGHJKMNPQRS",
+		" ab cd ":             "ABCD",
+		"":                    "",
+	}
+	for in, want := range tests {
+		if got := NormalizeCode(in); got != want {
+			t.Errorf("NormalizeCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		code, err := newCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != codeLen {
+			t.Fatalf("len(%q) = %d, want %d", code, len(code), codeLen)
+		}
+		for _, r := range code {
+			if !strings.ContainsRune(codeAlphabet, r) {
+				t.Fatalf("code %q has %q outside the alphabet", code, r)
+			}
+		}
+	}
+}
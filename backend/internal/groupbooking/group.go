@@ -0,0 +1,247 @@
+package groupbooking
+
+import (
+	"cinema/internal/notify"
+	"cinema/internal/seatlock"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Group bookings with split payment. The organizer locks N seats as usual
+// and turns the hold into a group; participants open the invite link, claim
+// seats (the lock moves to them with the remaining TTL) and pay for them
+// with the normal bookings/confirm call, so every seat is finalized by
+// ConfirmSeatsBooked. Seats nobody claims or pays for expire with the hold;
+// re-locking any hold of the group (Touch) keeps the group alive with it.
+//
+//	group:<groupId>                          STRING Group JSON, expires with the hold
+//	grouphold:<showtimeId>:<owner>:<reqId>   STRING groupId of a hold in the group
+
+var (
+	ErrNotFound    = errors.New("group not found")
+	ErrInvalidCode = errors.New("invalid invite code")
+	ErrNotHeld     = errors.New("seats not held by organizer")
+	ErrNotInGroup  = errors.New("seat not in group")
+	ErrSeatTaken   = errors.New("seat already claimed")
+)
+
+func groupKey(groupID string) string {
+	return fmt.Sprintf("group:%s", groupID)
+}
+
+func holdKey(showtimeID, owner, requestID string) string {
+	return fmt.Sprintf("grouphold:%s:%s:%s", showtimeID, owner, requestID)
+}
+
+type Group struct {
+	ID         string   `json:"id"`
+	ShowtimeID string   `json:"showtime_id"`
+	Organizer  string   `json:"organizer"`
+	RequestID  string   `json:"request_id"` // organizer's hold
+	SeatIDs    []string `json:"seat_ids"`
+	InviteCode string   `json:"invite_code"`
+	ExpiresAt  int64    `json:"expires_at"` // unix seconds, end of the hold
+	CreatedAt  int64    `json:"created_at"`
+}
+
+// Seat states as seen by a group member.
+const (
+	SeatOpen     = "open"     // still held by the organizer, claimable
+	SeatClaimed  = "claimed"  // held by a participant, awaiting payment
+	SeatPaid     = "paid"     // booked
+	SeatReleased = "released" // hold expired or released
+)
+
+type SeatStatus struct {
+	SeatID string `json:"seat_id"`
+	State  string `json:"state"`
+	Mine   bool   `json:"mine,omitempty"`
+}
+
+type Service struct {
+	rdb   *redis.Client
+	seats *seatlock.Service
+}
+
+func New(rdb *redis.Client, seats *seatlock.Service) *Service {
+	return &Service{rdb: rdb, seats: seats}
+}
+
+// ClaimRequestID is the request id a participant confirms their seats with.
+func ClaimRequestID(groupID, userID string) string {
+	return "group-" + groupID + "-" + userID
+}
+
+// Create turns the organizer's hold (owner:requestID on every seat) into a group.
+func (s *Service) Create(ctx context.Context, showtimeID, organizer, requestID string, seatIDs []string) (*Group, error) {
+	holds, err := s.seats.Inspect(ctx, showtimeID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var ttl time.Duration
+	for _, h := range holds {
+		if h.Owner != organizer || h.RequestID != requestID || h.TTL <= 0 {
+			return nil, ErrNotHeld
+		}
+		if ttl == 0 || h.TTL < ttl {
+			ttl = h.TTL
+		}
+	}
+
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	g := &Group{
+		ID:         uuid.NewString(),
+		ShowtimeID: showtimeID,
+		Organizer:  organizer,
+		RequestID:  requestID,
+		SeatIDs:    seatIDs,
+		InviteCode: hex.EncodeToString(code),
+		ExpiresAt:  now.Add(ttl).Unix(),
+		CreatedAt:  now.Unix(),
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	// the group is only useful while the hold lives
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, groupKey(g.ID), b, ttl)
+	pipe.Set(ctx, holdKey(showtimeID, organizer, requestID), g.ID, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// KEYS: group key, hold key
+// ARGV: ttlMs, expires_at (unix seconds)
+// The hold key follows its hold; the group only ever gets longer.
+var luaTouch = redis.NewScript(`
+local pttl = redis.call("PTTL", KEYS[1])
+if pttl < 0 then
+  return 0
+end
+redis.call("PEXPIRE", KEYS[2], ARGV[1])
+if pttl >= tonumber(ARGV[1]) then
+  return 0
+end
+local g = cjson.decode(redis.call("GET", KEYS[1]))
+g["expires_at"] = tonumber(ARGV[2])
+redis.call("SET", KEYS[1], cjson.encode(g), "PX", ARGV[1])
+return 1
+`)
+
+// Touch is called after owner re-locked their hold (owner:requestID) for
+// ttl: if the hold belongs to a group, the group lives at least as long.
+func (s *Service) Touch(ctx context.Context, showtimeID, owner, requestID string, ttl time.Duration) error {
+	hk := holdKey(showtimeID, owner, requestID)
+	groupID, err := s.rdb.Get(ctx, hk).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl).Unix()
+	return luaTouch.Run(ctx, s.rdb, []string{groupKey(groupID), hk}, ttl.Milliseconds(), expiresAt).Err()
+}
+
+// Get loads a group for userID: the organizer, or anyone with the invite code.
+func (s *Service) Get(ctx context.Context, groupID, userID, code string) (*Group, error) {
+	raw, err := s.rdb.Get(ctx, groupKey(groupID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var g Group
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, err
+	}
+	if userID != g.Organizer && subtle.ConstantTimeCompare([]byte(code), []byte(g.InviteCode)) != 1 {
+		return nil, ErrInvalidCode
+	}
+	return &g, nil
+}
+
+// Seats reports each group seat's state for viewer.
+func (s *Service) Seats(ctx context.Context, g *Group, viewer string) ([]SeatStatus, error) {
+	holds, err := s.seats.Inspect(ctx, g.ShowtimeID, g.SeatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]SeatStatus, 0, len(holds))
+	for _, h := range holds {
+		st := SeatStatus{SeatID: h.SeatID}
+		switch {
+		case h.BookingID != "":
+			st.State = SeatPaid
+		case h.Owner == g.Organizer && h.RequestID == g.RequestID:
+			st.State = SeatOpen
+			st.Mine = viewer == g.Organizer
+		case h.RequestID == ClaimRequestID(g.ID, h.Owner):
+			st.State = SeatClaimed
+			st.Mine = viewer == h.Owner
+		default:
+			st.State = SeatReleased
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Claim moves open seats of the group to userID, who then pays for them with
+// bookings/confirm and the returned request id.
+func (s *Service) Claim(ctx context.Context, g *Group, userID string, seatIDs []string) (string, error) {
+	inGroup := make(map[string]struct{}, len(g.SeatIDs))
+	for _, sid := range g.SeatIDs {
+		inGroup[sid] = struct{}{}
+	}
+	for _, sid := range seatIDs {
+		if _, ok := inGroup[sid]; !ok {
+			return "", ErrNotInGroup
+		}
+	}
+
+	rid := ClaimRequestID(g.ID, userID)
+	ok, _, err := s.seats.TransferLocks(ctx, g.ShowtimeID, seatIDs, g.Organizer, g.RequestID, userID, rid)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrSeatTaken
+	}
+
+	// the claimed seats keep the remaining TTL: so does the new hold's key
+	if ttl, err := s.rdb.PTTL(ctx, groupKey(g.ID)).Result(); err == nil && ttl > 0 {
+		s.rdb.Set(ctx, holdKey(g.ShowtimeID, userID, rid), g.ID, ttl)
+	}
+
+	notify.Publish(ctx, s.rdb, notify.UserEvent{
+		Type:       notify.GroupSeatClaimed,
+		UserID:     g.Organizer,
+		ShowtimeID: g.ShowtimeID,
+		SeatIDs:    seatIDs,
+		RequestID:  g.RequestID,
+	})
+	return rid, nil
+}
@@ -0,0 +1,167 @@
+package groupbooking
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newGroupService(t *testing.T) (*Service, *seatlock.Service, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	seats := seatlock.New(rdb, time.Minute)
+	if err := seats.BeginRebuild(context.Background(), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	return New(rdb, seats), seats, mr
+}
+
+func TestCreateNeedsOrganizerHold(t *testing.T) {
+	ctx := context.Background()
+	svc, seats, _ := newGroupService(t)
+
+	if ok, _, err := seats.LockSeats(ctx, "st1", []string{"A1"}, "org", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	if _, err := svc.Create(ctx, "st1", "org", "r1", []string{"A1", "A2"}); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Create with an unheld seat: err = %v", err)
+	}
+	if _, err := svc.Create(ctx, "st1", "org", "r2", []string{"A1"}); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Create with another request: err = %v", err)
+	}
+}
+
+func TestClaimAndSeats(t *testing.T) {
+	ctx := context.Background()
+	svc, seats, _ := newGroupService(t)
+	ids := []string{"B1", "B2", "B3"}
+
+	if ok, _, err := seats.LockSeats(ctx, "st1", ids, "org", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	g, err := svc.Create(ctx, "st1", "org", "r1", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Get(ctx, g.ID, "guest", "wrong"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Get with a bad code: err = %v", err)
+	}
+	joined, err := svc.Get(ctx, g.ID, "guest", g.InviteCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Claim(ctx, joined, "guest", []string{"C1"}); !errors.Is(err, ErrNotInGroup) {
+		t.Fatalf("Claim outside the group: err = %v", err)
+	}
+	rid, err := svc.Claim(ctx, joined, "guest", []string{"B1"})
+	if err != nil || rid != ClaimRequestID(g.ID, "guest") {
+		t.Fatalf("Claim = %q, %v", rid, err)
+	}
+	if _, err := svc.Claim(ctx, joined, "late", []string{"B1"}); !errors.Is(err, ErrSeatTaken) {
+		t.Fatalf("Claim of a claimed seat: err = %v", err)
+	}
+	// the organizer lets one go
+	if err := seats.ReleaseSeats(ctx, "st1", []string{"B3"}, "org"); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := svc.Seats(ctx, g, "guest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SeatStatus{
+		{SeatID: "B1", State: SeatClaimed, Mine: true},
+		{SeatID: "B2", State: SeatOpen},
+		{SeatID: "B3", State: SeatReleased},
+	}
+	for i := range want {
+		if st[i] != want[i] {
+			t.Fatalf("Seats = %+v, want %+v", st, want)
+		}
+	}
+}
+
+func TestTouchExtendsGroupWithHold(t *testing.T) {
+	ctx := context.Background()
+	svc, seats, mr := newGroupService(t)
+	ids := []string{"A1", "A2"}
+
+	if ok, _, err := seats.LockSeats(ctx, "st1", ids, "org", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	g, err := svc.Create(ctx, "st1", "org", "r1", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// re-lock for longer: the group follows
+	if ok, _, err := seats.LockSeatsWithOptions(ctx, "st1", ids, "org", "r1", seatlock.LockOptions{TTL: 5 * time.Minute}); err != nil || !ok {
+		t.Fatalf("relock: ok=%v err=%v", ok, err)
+	}
+	if err := svc.Touch(ctx, "st1", "org", "r1", 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(groupKey(g.ID)); ttl <= time.Minute {
+		t.Fatalf("group ttl = %v, want extended", ttl)
+	}
+	got, err := svc.Get(ctx, g.ID, "org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ExpiresAt <= g.ExpiresAt || got.InviteCode != g.InviteCode || len(got.SeatIDs) != 2 {
+		t.Fatalf("group after touch = %+v, was %+v", got, g)
+	}
+
+	// a shorter re-lock never shortens the group
+	if err := svc.Touch(ctx, "st1", "org", "r1", 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(groupKey(g.ID)); ttl <= time.Minute {
+		t.Fatalf("group ttl = %v after shorter touch", ttl)
+	}
+}
+
+func TestTouchClaimedHold(t *testing.T) {
+	ctx := context.Background()
+	svc, seats, mr := newGroupService(t)
+	ids := []string{"B1", "B2"}
+
+	if ok, _, err := seats.LockSeats(ctx, "st1", ids, "org", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	g, err := svc.Create(ctx, "st1", "org", "r1", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rid, err := svc.Claim(ctx, g, "guest", []string{"B1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Touch(ctx, "st1", "guest", rid, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(groupKey(g.ID)); ttl <= time.Minute {
+		t.Fatalf("group ttl = %v, want extended by the participant", ttl)
+	}
+}
+
+func TestTouchOutsideGroup(t *testing.T) {
+	svc, _, mr := newGroupService(t)
+	if err := svc.Touch(context.Background(), "st1", "someone", "r9", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if keys := mr.Keys(); len(keys) != 1 { // just the ready key
+		t.Fatalf("keys = %v", keys)
+	}
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"cinema/internal/promo"
+	"cinema/internal/repo"
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var promoCodeRe = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type AdminPromoHandler struct {
+	promos *repo.PromoRepo
+}
+
+func NewAdminPromoHandler(promos *repo.PromoRepo) *AdminPromoHandler {
+	return &AdminPromoHandler{promos: promos}
+}
+
+type createPromoReq struct {
+	Code           string          `json:"code"`
+	Description    string          `json:"description"`
+	Kind           model.PromoKind `json:"kind"`
+	Value          int64           `json:"value"`
+	MinSeats       int             `json:"min_seats"`
+	MovieIDs       []string        `json:"movie_ids"`
+	ShowtimeIDs    []string        `json:"showtime_ids"`
+	Weekdays       []int           `json:"weekdays"`
+	ValidFrom      *time.Time      `json:"valid_from"`
+	ValidTo        *time.Time      `json:"valid_to"`
+	MaxRedemptions int64           `json:"max_redemptions"`
+	PerUserLimit   int64           `json:"per_user_limit"`
+	Active         *bool           `json:"active"` // default true
+}
+
+// validatePromo returns an error code, or "" if p is valid.
+func validatePromo(p *model.Promo) string {
+	switch {
+	case !promoCodeRe.MatchString(p.Code):
+		return "invalid_code"
+	case p.Kind != model.PromoPercent && p.Kind != model.PromoFixed:
+		return "invalid_kind"
+	case p.Value <= 0 || (p.Kind == model.PromoPercent && p.Value > 100):
+		return "invalid_value"
+	case p.MinSeats < 0 || p.MaxRedemptions < 0 || p.PerUserLimit < 0:
+		return "invalid_limit"
+	case p.ValidFrom != nil && p.ValidTo != nil && !p.ValidFrom.Before(*p.ValidTo):
+		return "invalid_window"
+	}
+	for _, d := range p.Weekdays {
+		if d < 0 || d > 6 {
+			return "invalid_weekdays"
+		}
+	}
+	return ""
+}
+
+// POST /api/admin/promos
+func (h *AdminPromoHandler) Create(c *gin.Context) {
+	var req createPromoReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	p := &model.Promo{
+		Code:           promo.NormalizeCode(req.Code),
+		Description:    req.Description,
+		Kind:           req.Kind,
+		Value:          req.Value,
+		MinSeats:       req.MinSeats,
+		MovieIDs:       req.MovieIDs,
+		ShowtimeIDs:    req.ShowtimeIDs,
+		Weekdays:       req.Weekdays,
+		ValidFrom:      req.ValidFrom,
+		ValidTo:        req.ValidTo,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		Active:         req.Active == nil || *req.Active,
+		CreatedBy:      c.GetString(middleware.CtxUserID),
+	}
+	if code := validatePromo(p); code != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": code})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.promos.Create(ctx, p); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "promo_code_exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_create_failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "promo": p})
+}
+
+// GET /api/admin/promos?active=&limit=&skip=
+func (h *AdminPromoHandler) List(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	var f repo.AdminPromoFilter
+	if v := c.Query("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_active"})
+			return
+		}
+		f.Active = b
+		f.HasActive = true
+	}
+	if v := c.Query("limit"); v != "" {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		f.Limit = n
+	}
+	if v := c.Query("skip"); v != "" {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		f.Skip = n
+	}
+
+	items, total, err := h.promos.FindAdmin(ctx, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":    true,
+		"total": total,
+		"items": items,
+	})
+}
+
+// only these fields can change after creation (code/kind/value are fixed
+// so past redemptions stay meaningful)
+type updatePromoReq struct {
+	Description    *string    `json:"description"`
+	Active         *bool      `json:"active"`
+	MinSeats       *int       `json:"min_seats"`
+	MovieIDs       *[]string  `json:"movie_ids"`
+	ShowtimeIDs    *[]string  `json:"showtime_ids"`
+	Weekdays       *[]int     `json:"weekdays"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to"`
+	MaxRedemptions *int64     `json:"max_redemptions"`
+	PerUserLimit   *int64     `json:"per_user_limit"`
+}
+
+// PATCH /api/admin/promos/:promoId
+func (h *AdminPromoHandler) Update(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("promoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_promo_id"})
+		return
+	}
+
+	var req updatePromoReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	cur, err := h.promos.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "promo_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	// apply to a copy and validate the result as a whole
+	set := bson.M{}
+	next := *cur
+	if req.Description != nil {
+		next.Description, set["description"] = *req.Description, *req.Description
+	}
+	if req.Active != nil {
+		next.Active, set["active"] = *req.Active, *req.Active
+	}
+	if req.MinSeats != nil {
+		next.MinSeats, set["min_seats"] = *req.MinSeats, *req.MinSeats
+	}
+	if req.MovieIDs != nil {
+		next.MovieIDs, set["movie_ids"] = *req.MovieIDs, *req.MovieIDs
+	}
+	if req.ShowtimeIDs != nil {
+		next.ShowtimeIDs, set["showtime_ids"] = *req.ShowtimeIDs, *req.ShowtimeIDs
+	}
+	if req.Weekdays != nil {
+		next.Weekdays, set["weekdays"] = *req.Weekdays, *req.Weekdays
+	}
+	if req.ValidFrom != nil {
+		next.ValidFrom, set["valid_from"] = req.ValidFrom, *req.ValidFrom
+	}
+	if req.ValidTo != nil {
+		next.ValidTo, set["valid_to"] = req.ValidTo, *req.ValidTo
+	}
+	if req.MaxRedemptions != nil {
+		next.MaxRedemptions, set["max_redemptions"] = *req.MaxRedemptions, *req.MaxRedemptions
+	}
+	if req.PerUserLimit != nil {
+		next.PerUserLimit, set["per_user_limit"] = *req.PerUserLimit, *req.PerUserLimit
+	}
+	if len(set) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "nothing_to_update"})
+		return
+	}
+	if code := validatePromo(&next); code != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": code})
+		return
+	}
+
+	p, err := h.promos.Update(ctx, id, set)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "promo": p})
+}
+
+// DELETE /api/admin/promos/:promoId
+// Deactivates the promo; redemptions keep referring to it.
+func (h *AdminPromoHandler) Deactivate(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("promoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_promo_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	p, err := h.promos.Update(ctx, id, bson.M{"active": false})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "promo_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "promo": p})
+}
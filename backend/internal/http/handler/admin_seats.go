@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/seatrepair"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminSeatHandler struct {
+	repair *seatrepair.Service
+}
+
+func NewAdminSeatHandler(repair *seatrepair.Service) *AdminSeatHandler {
+	return &AdminSeatHandler{repair: repair}
+}
+
+type forceReleaseReq struct {
+	SeatIDs []string `json:"seat_ids,omitempty"`
+	Owner   string   `json:"owner,omitempty"` // user id: all of their locks
+	Reason  string   `json:"reason"`          // required unless dry_run
+	DryRun  bool     `json:"dry_run,omitempty"`
+}
+
+// POST /api/admin/showtimes/:showtimeId/seats/force-release
+// Removes locks whoever holds them (seat_ids and/or owner). dry_run lists
+// what would be released.
+func (h *AdminSeatHandler) ForceRelease(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+
+	var req forceReleaseReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	var seatIDs []string
+	if len(req.SeatIDs) > 0 {
+		ids, ok := normalizeSeatIDs(req.SeatIDs)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+			return
+		}
+		seatIDs = ids
+	}
+	owner := strings.TrimSpace(req.Owner)
+	if len(seatIDs) == 0 && owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "seat_ids_or_owner_required"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" && !req.DryRun {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_reason"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	locks, err := h.repair.ForceRelease(ctx, showtimeID, seatIDs, owner, c.GetString(middleware.CtxUserID), reason, req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "release_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"showtime_id": showtimeID,
+		"dry_run":     req.DryRun,
+		"released":    locks,
+	})
+}
+
+type repairSeatsReq struct {
+	Reason string `json:"reason"` // required unless dry_run
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// POST /api/admin/showtimes/:showtimeId/seats/repair
+// Makes the seatbooked: markers match the showtime's BOOKED bookings.
+func (h *AdminSeatHandler) Repair(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+
+	var req repairSeatsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" && !req.DryRun {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_reason"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	rep, err := h.repair.Repair(ctx, showtimeID, c.GetString(middleware.CtxUserID), reason, req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "repair_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "report": rep})
+}
+
+type reconcileReq struct {
+	ShowtimeID string `json:"showtime_id,omitempty"` // empty = every active showtime
+	DryRun     bool   `json:"dry_run,omitempty"`
+}
+
+// POST /api/admin/seats/reconcile
+// Runs the Redis–Mongo reconciler now (it also runs on a schedule in the
+// workers). Takes a few seconds: fixes are confirmed by a second check.
+func (h *AdminSeatHandler) Reconcile(c *gin.Context) {
+	var req reconcileReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	var showtimeIDs []string
+	if id := strings.TrimSpace(req.ShowtimeID); id != "" {
+		if !showtimeIDRe.MatchString(id) {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_showtime_id"})
+			return
+		}
+		showtimeIDs = []string{id}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	run, err := h.repair.Reconcile(ctx, showtimeIDs, "admin", c.GetString(middleware.CtxUserID), req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "reconcile_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "run": run})
+}
+
+// GET /api/admin/seats/reconcile
+// The last reconciler run (scheduled or on demand).
+func (h *AdminSeatHandler) LastReconcile(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	run, err := h.repair.LastRun(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "run": run})
+}
+
+type rebuildSeatsReq struct {
+	ShowtimeID string `json:"showtime_id,omitempty"` // empty = every upcoming showtime
+}
+
+// POST /api/admin/seats/rebuild
+// Rebuilds missing seatbooked: markers from BOOKED bookings in Mongo. Lock
+// and confirm of a showtime wait until its markers are back. The workers do
+// this on their own when Redis lost its data; while seat state is not ready,
+// a one-showtime request rebuilds every showtime so the gate can lift.
+func (h *AdminSeatHandler) Rebuild(c *gin.Context) {
+	var req rebuildSeatsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	var showtimeIDs []string
+	if id := strings.TrimSpace(req.ShowtimeID); id != "" {
+		if !showtimeIDRe.MatchString(id) {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_showtime_id"})
+			return
+		}
+		showtimeIDs = []string{id}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	run, err := h.repair.Rebuild(ctx, showtimeIDs, "admin", c.GetString(middleware.CtxUserID))
+	if errors.Is(err, seatrepair.ErrRebuildRunning) {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "rebuild_running"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "rebuild_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "run": run})
+}
+
+// GET /api/admin/seats/rebuild
+// Whether seat state is ready, the showtimes still gated, and the last run.
+func (h *AdminSeatHandler) RebuildStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	ready, pending, err := h.repair.RebuildState(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+	run, err := h.repair.LastRebuild(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "ready": ready, "pending": pending, "run": run})
+}
@@ -1,12 +1,21 @@
 package handler
 
 import (
+	"cinema/internal/concession"
+	"cinema/internal/giftcard"
 	"cinema/internal/http/middleware"
+	"cinema/internal/loyalty"
 	"cinema/internal/model"
+	"cinema/internal/notify"
+	"cinema/internal/promo"
 	"cinema/internal/repo"
 	"cinema/internal/seatlock"
+	"cinema/internal/showtime"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -15,27 +24,62 @@ import (
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type BookingHandler struct {
-	seatLock *seatlock.Service
-	bookings *repo.BookingRepo
-	rdb      *redis.Client
+	seatLock    *seatlock.Service
+	bookings    *repo.BookingRepo
+	rdb         *redis.Client
+	promos      *promo.Service
+	concessions *concession.Service
+	loyalty     *loyalty.Service
+	giftCards   *giftcard.Service
+	showtimes   *showtime.Service
 }
 
-func NewBookingHandler(seatLock *seatlock.Service, bookings *repo.BookingRepo, rdb *redis.Client) *BookingHandler {
-	return &BookingHandler{seatLock: seatLock, bookings: bookings, rdb: rdb}
+func NewBookingHandler(
+	seatLock *seatlock.Service,
+	bookings *repo.BookingRepo,
+	rdb *redis.Client,
+	promos *promo.Service,
+	concessions *concession.Service,
+	loyaltySvc *loyalty.Service,
+	giftCards *giftcard.Service,
+	showtimes *showtime.Service,
+) *BookingHandler {
+	return &BookingHandler{
+		seatLock:    seatLock,
+		bookings:    bookings,
+		rdb:         rdb,
+		promos:      promos,
+		concessions: concessions,
+		loyalty:     loyaltySvc,
+		giftCards:   giftCards,
+		showtimes:   showtimes,
+	}
 }
 
 type confirmBookingReq struct {
 	SeatIDs   []string `json:"seat_ids"`
 	RequestID string   `json:"request_id"`
+	PromoCode string   `json:"promo_code,omitempty"`
+	// loyalty points to spend (PointsPerUnit points = 1 currency unit off)
+	RedeemPoints int64 `json:"redeem_points,omitempty"`
+	// gift card paying (part of) the total; the provider charges the rest
+	GiftCardCode string `json:"gift_card_code,omitempty"`
 }
 
+// mock pricing: 100 THB per seat
+const (
+	seatPrice     = 100
+	priceCurrency = "THB"
+)
+
 func bookingEventsChannel() string { return "booking-events" }
 
 type BookingEvent struct {
-	Type      string   `json:"type"` // "booking.success"
+	Type      string   `json:"type"` // "booking.success" | "booking.cancelled" | "booking.exchanged"
 	BookingID string   `json:"booking_id"`
 	Showtime  string   `json:"showtime_id"`
 	UserID    string   `json:"user_id"`
@@ -43,6 +87,15 @@ type BookingEvent struct {
 	Amount    int64    `json:"amount"`
 	Currency  string   `json:"currency"`
 	At        int64    `json:"at"`
+
+	// booking.exchanged: seats given up and the price difference
+	FromSeatIDs []string `json:"from_seat_ids,omitempty"`
+	Difference  int64    `json:"difference,omitempty"`
+}
+
+// seatsPrice is the list price of seatIDs.
+func seatsPrice(seatIDs []string) int64 {
+	return int64(len(seatIDs)) * seatPrice
 }
 
 func (h *BookingHandler) Confirm(c *gin.Context) {
@@ -64,6 +117,10 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_request_id"})
 		return
 	}
+	if req.RedeemPoints < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_redeem_points"})
+		return
+	}
 
 	uid, err := primitive.ObjectIDFromHex(owner)
 	if err != nil {
@@ -71,22 +128,115 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 		return
 	}
 
-	// mock pricing: 100 THB per seat
-	amount := int64(len(seatIDs)) * 100
-	currency := "THB"
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
 
-	booking := &model.Booking{
-		ID:         primitive.NewObjectID(),
-		ShowtimeID: showtimeID,
-		UserID:     uid,
-		SeatIDs:    seatIDs,
-		Amount:     amount,
-		Currency:   currency,
-		RequestID:  strings.TrimSpace(req.RequestID),
+	// sales window (showtime lifecycle); nil st = unmanaged showtime
+	st, err := h.showtimes.CheckSales(ctx, showtimeID, time.Now())
+	if !writeShowtimeError(c, err) {
+		return
+	}
+	if !seatStateReady(ctx, c, h.seatLock, showtimeID) {
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-	defer cancel()
+	pricing := &model.PriceBreakdown{Subtotal: seatsPrice(seatIDs)}
+	pricing.Total = pricing.Subtotal
+	currency := priceCurrency
+
+	// promo: validate before anything is written
+	var promoApplied *model.Promo
+	var discount int64
+	if code := promo.NormalizeCode(req.PromoCode); code != "" {
+		now := time.Now()
+		target := promo.Target{
+			ShowtimeID: showtimeID,
+			Day:        now,
+			Seats:      len(seatIDs),
+			Subtotal:   pricing.Subtotal,
+			At:         now,
+		}
+		if st != nil {
+			target.MovieID = st.MovieID
+			target.Day = st.StartsAt
+		}
+		p, d, err := h.promos.Quote(ctx, code, target)
+		if !writePromoError(c, err) {
+			return
+		}
+		promoApplied, discount = p, d
+		pricing.Add(model.PriceLine{Kind: "promo", Code: p.Code, Label: p.Description, Amount: -d})
+	}
+
+	// member tier discount (seats)
+	account, err := h.loyalty.Account(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	if pct := loyalty.BenefitsOf(account.Tier).DiscountPercent; pct > 0 {
+		pricing.Add(model.PriceLine{
+			Kind:   "member",
+			Code:   string(account.Tier),
+			Label:  fmt.Sprintf("%s member %d%%", account.Tier, pct),
+			Amount: -pricing.Subtotal * pct / 100,
+		})
+	}
+
+	// concessions reserved with this hold (PUT /concessions)
+	rid := strings.TrimSpace(req.RequestID)
+	addOns, err := h.concessions.ForHold(ctx, showtimeID, owner, rid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	var bookingConcessions *model.BookingConcessions
+	if addOns != nil {
+		for _, l := range addOns.Items {
+			pricing.Add(model.PriceLine{Kind: "concession", Code: l.ItemID.Hex(), Label: l.Name, Amount: l.Qty * l.UnitPrice})
+		}
+		code, err := concession.NewPickupCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "pickup_code_failed"})
+			return
+		}
+		bookingConcessions = &model.BookingConcessions{CinemaID: addOns.CinemaID, Items: addOns.Items, PickupCode: code}
+	}
+
+	// loyalty points against what is left to pay
+	var pointsSpent int64
+	if req.RedeemPoints > 0 {
+		points, off := loyalty.PointsFor(req.RedeemPoints, pricing.Total)
+		if points > 0 {
+			pointsSpent = points
+			pricing.Add(model.PriceLine{Kind: "points", Label: fmt.Sprintf("%d points", points), Amount: -off})
+		}
+	}
+
+	// gift card: covers up to its balance, the payment provider the rest
+	var card *model.GiftCard
+	var cardAmount int64
+	if code := giftcard.NormalizeCode(req.GiftCardCode); code != "" && pricing.Total > 0 {
+		g, cover, err := h.giftCards.Quote(ctx, code, currency, pricing.Total)
+		if !writeGiftCardError(c, err) {
+			return
+		}
+		card, cardAmount = g, cover
+		pricing.Add(model.PriceLine{Kind: "gift_card", Code: maskCode(g.Code), Label: "Gift card", Amount: -cover})
+	}
+	amount := pricing.Total
+
+	booking := &model.Booking{
+		ID:          primitive.NewObjectID(),
+		ShowtimeID:  showtimeID,
+		UserID:      uid,
+		SeatIDs:     seatIDs,
+		Amount:      amount,
+		Currency:    currency,
+		RequestID:   rid,
+		Pricing:     pricing,
+		Concessions: bookingConcessions,
+	}
 
 	// 1) create PENDING
 	if err := h.bookings.CreatePending(ctx, booking); err != nil {
@@ -94,7 +244,53 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 		return
 	}
 
-	// 2) mock payment success
+	// 1b) redeem promo atomically (limits); undone if the booking fails below
+	if promoApplied != nil {
+		if err := h.promos.Redeem(ctx, promoApplied, owner, booking.ID, discount); err != nil {
+			h.failBooking(ctx, booking, "promo_failed")
+			writePromoError(c, err)
+			return
+		}
+	}
+
+	// 1c) spend loyalty points (atomic balance check)
+	if pointsSpent > 0 {
+		if err := h.loyalty.Redeem(ctx, owner, booking.ID, pointsSpent); err != nil {
+			if errors.Is(err, loyalty.ErrInsufficientPoints) {
+				h.failBooking(ctx, booking, "insufficient_points")
+				c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "insufficient_points"})
+				return
+			}
+			h.failBooking(ctx, booking, "db_update_failed")
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+			return
+		}
+	}
+
+	// 1d) gift card (balance re-checked on the ledger)
+	if card != nil {
+		if err := h.giftCards.Redeem(ctx, card, booking.ID, cardAmount); err != nil {
+			h.failBooking(ctx, booking, "gift_card_failed")
+			writeGiftCardError(c, err)
+			return
+		}
+	}
+
+	// 1e) concessions: reservation becomes part of the booking
+	if addOns != nil {
+		if err := h.concessions.Consume(ctx, addOns, booking.ID, bookingConcessions.PickupCode); err != nil {
+			if errors.Is(err, concession.ErrExpired) {
+				h.failBooking(ctx, booking, "concessions_expired")
+				c.JSON(http.StatusConflict, gin.H{"ok": false, "error": concession.ErrExpired.Code})
+				return
+			}
+			h.failBooking(ctx, booking, "db_update_failed")
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+			return
+		}
+	}
+
+	// 2) mock payment success (amount = what the gift card did not cover)
 	paymentRef := "mock_" + uuid.NewString()
 
 	// 3) finalize Redis: LOCKED -> BOOKED (atomic)
@@ -106,13 +302,18 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 		booking.RequestID,
 		booking.ID.Hex(),
 	)
+	if errors.Is(err, seatlock.ErrRebuilding) {
+		h.failBooking(ctx, booking, reason)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": "seats_rebuilding"})
+		return
+	}
 	if err != nil {
-		_ = h.bookings.MarkFailed(ctx, booking.ID)
+		h.failBooking(ctx, booking, "confirm_failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "confirm_failed"})
 		return
 	}
 	if !okBooked {
-		_ = h.bookings.MarkFailed(ctx, booking.ID)
+		h.failBooking(ctx, booking, reason)
 		c.JSON(http.StatusConflict, gin.H{
 			"ok":         false,
 			"error":      "seats_unavailable",
@@ -124,7 +325,12 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 
 	// 4) mark BOOKED
 	if err := h.bookings.MarkBooked(ctx, booking.ID, paymentRef); err != nil {
-		_ = h.bookings.MarkFailed(ctx, booking.ID)
+		// Redis already booked the seats: free them before giving anything
+		// back. If that fails too, the booking stays PENDING with its
+		// redemptions and the reconciler confirms it (its seats are marked).
+		if _, rerr := h.seatLock.ReleaseBooked(ctx, showtimeID, seatIDs, owner, booking.ID.Hex()); rerr == nil {
+			h.failBooking(ctx, booking, "db_update_failed")
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
 		return
 	}
@@ -143,6 +349,14 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 	if b, e := json.Marshal(ev); e == nil {
 		_ = h.rdb.Publish(ctx, bookingEventsChannel(), b).Err()
 	}
+	notify.Publish(ctx, h.rdb, notify.UserEvent{
+		Type:       notify.PaymentSucceeded,
+		UserID:     owner,
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		RequestID:  booking.RequestID,
+		BookingID:  booking.ID.Hex(),
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"ok": true,
@@ -152,8 +366,175 @@ func (h *BookingHandler) Confirm(c *gin.Context) {
 			"seat_ids":    seatIDs,
 			"amount":      amount,
 			"currency":    currency,
+			"pricing":     pricing,
+			"concessions": bookingConcessions,
 			"status":      model.BookingBooked,
 			"payment_ref": paymentRef,
 		},
 	})
 }
+
+// writePromoError answers a promo rejection; false if err != nil.
+func writePromoError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var pe *promo.Error
+	if errors.As(err, &pe) {
+		status := http.StatusBadRequest
+		if pe == promo.ErrExhausted || pe == promo.ErrUserLimit {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"ok": false, "error": pe.Code})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "promo_failed"})
+	return false
+}
+
+// writeGiftCardError answers a gift card rejection; false if err != nil.
+func writeGiftCardError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var ge *giftcard.Error
+	if errors.As(err, &ge) {
+		status := http.StatusBadRequest
+		switch ge {
+		case giftcard.ErrNotFound:
+			status = http.StatusNotFound
+		case giftcard.ErrInsufficient:
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"ok": false, "error": ge.Code})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "gift_card_failed"})
+	return false
+}
+
+// maskCode keeps the last 4 characters of a code for display.
+func maskCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return strings.Repeat("*", len(code)-4) + code[len(code)-4:]
+}
+
+// failBooking marks the booking FAILED, gives back its promo, points, gift
+// card amount and concession stock and tells the user.
+func (h *BookingHandler) failBooking(ctx context.Context, b *model.Booking, reason string) {
+	_ = h.bookings.MarkFailed(ctx, b.ID)
+	_ = h.giftCards.RefundBooking(ctx, b.ID)
+	_ = h.promos.Reverse(ctx, b.ID)
+	_ = h.loyalty.RefundRedemption(ctx, b.ID)
+	_ = h.concessions.ReleaseBooking(ctx, b.ID)
+	h.notifyPaymentFailed(ctx, b, reason)
+}
+
+// private notification to the booking's user (best-effort)
+func (h *BookingHandler) notifyPaymentFailed(ctx context.Context, b *model.Booking, reason string) {
+	notify.Publish(ctx, h.rdb, notify.UserEvent{
+		Type:       notify.PaymentFailed,
+		UserID:     b.UserID.Hex(),
+		ShowtimeID: b.ShowtimeID,
+		SeatIDs:    b.SeatIDs,
+		RequestID:  b.RequestID,
+		BookingID:  b.ID.Hex(),
+		Reason:     reason,
+	})
+}
+
+// POST /api/bookings/:bookingId/cancel
+// Owner cancels a BOOKED booking: seats go back on sale, promo uses, points,
+// gift card amounts and concession stock are given back.
+func (h *BookingHandler) Cancel(c *gin.Context) {
+	owner := c.GetString(middleware.CtxUserID)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("bookingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_booking_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	b, err := h.bookings.FindByID(ctx, bookingID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && b.UserID.Hex() != owner) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "booking_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	// no cancelling once sales are over (show about to start, started or
+	// cancelled: the refund job takes those)
+	if _, err := h.showtimes.CheckSales(ctx, b.ShowtimeID, time.Now()); !writeShowtimeError(c, err) {
+		return
+	}
+
+	// 1) BOOKED -> CANCELLED (conditional, so only one cancel wins)
+	b, err = h.bookings.MarkCancelled(ctx, bookingID, model.CancelByUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "booking_not_cancellable"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+
+	// 2-6) the booking is cancelled now and can't be cancelled again, so
+	// every give-back runs even if one fails (each is safe to repeat)
+	unwind := []struct {
+		step string
+		run  func() error
+	}{
+		// seats back on sale in Redis
+		{"release_seats", func() error {
+			_, err := h.seatLock.ReleaseBooked(ctx, b.ShowtimeID, b.SeatIDs, owner, b.ID.Hex())
+			return err
+		}},
+		// promo redemption (if any)
+		{"promo_reverse", func() error { return h.promos.Reverse(ctx, b.ID) }},
+		// concession stock (unless already picked up)
+		{"concession_release", func() error { return h.concessions.ReleaseBooking(ctx, b.ID) }},
+		// points spent; points earned are taken back by the loyalty
+		// consumer on booking.cancelled
+		{"points_refund", func() error { return h.loyalty.RefundRedemption(ctx, b.ID) }},
+		// gift card amount back to the card
+		{"gift_card_refund", func() error { return h.giftCards.RefundBooking(ctx, b.ID) }},
+	}
+	for _, u := range unwind {
+		if err := u.run(); err != nil {
+			log.Printf("cancel booking %s: %s failed: %v", b.ID.Hex(), u.step, err)
+		}
+	}
+
+	// 7) publish (best-effort)
+	ev := BookingEvent{
+		Type:      "booking.cancelled",
+		BookingID: b.ID.Hex(),
+		Showtime:  b.ShowtimeID,
+		UserID:    owner,
+		SeatIDs:   b.SeatIDs,
+		Amount:    b.Amount,
+		Currency:  b.Currency,
+		At:        time.Now().Unix(),
+	}
+	if raw, e := json.Marshal(ev); e == nil {
+		_ = h.rdb.Publish(ctx, bookingEventsChannel(), raw).Err()
+	}
+	notify.Publish(ctx, h.rdb, notify.UserEvent{
+		Type:       notify.BookingCancelled,
+		UserID:     owner,
+		ShowtimeID: b.ShowtimeID,
+		SeatIDs:    b.SeatIDs,
+		BookingID:  b.ID.Hex(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "booking": b})
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"cinema/internal/seatlock"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type exchangeBookingReq struct {
+	SeatIDs    []string `json:"seat_ids"`     // seats of the booking to give up
+	NewSeatIDs []string `json:"new_seat_ids"` // seats to move to
+}
+
+// lua failure reason -> API error
+var exchangeErrors = map[string]string{
+	"already_booked": "seat_already_booked",
+	"missing_lock":   "lock_expired",
+	"not_owner":      "seat_locked",
+	"not_booked":     "booking_changed",
+}
+
+// POST /api/bookings/:bookingId/exchange
+// Moves a BOOKED booking from some of its seats to others, keeping the
+// booking id: the new seats are locked, then swapped for the old ones in
+// one Redis step. A price difference is charged or refunded (mock payment).
+func (h *BookingHandler) Exchange(c *gin.Context) {
+	owner := c.GetString(middleware.CtxUserID)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("bookingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_booking_id"})
+		return
+	}
+
+	var req exchangeBookingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	from, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return
+	}
+	to, ok := normalizeSeatIDs(req.NewSeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_new_seat_ids"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	b, err := h.bookings.FindByID(ctx, bookingID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && b.UserID.Hex() != owner) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "booking_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	if b.Status != model.BookingBooked {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "booking_not_exchangeable"})
+		return
+	}
+	for _, sid := range from {
+		if !slices.Contains(b.SeatIDs, sid) {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "seat_not_in_booking", "seat_id": sid})
+			return
+		}
+	}
+
+	if _, err := h.showtimes.CheckSales(ctx, b.ShowtimeID, time.Now()); !writeShowtimeError(c, err) {
+		return
+	}
+	if !seatStateReady(ctx, c, h.seatLock, b.ShowtimeID) {
+		return
+	}
+
+	// seats in both lists stay as they are
+	release := make([]string, 0, len(from))
+	for _, sid := range from {
+		if !slices.Contains(to, sid) {
+			release = append(release, sid)
+		}
+	}
+	acquire := make([]string, 0, len(to))
+	for _, sid := range to {
+		if slices.Contains(b.SeatIDs, sid) && !slices.Contains(from, sid) {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "seat_already_in_booking", "seat_id": sid})
+			return
+		}
+		if !slices.Contains(from, sid) {
+			acquire = append(acquire, sid)
+		}
+	}
+	if len(release) == 0 || len(acquire) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "nothing_to_exchange"})
+		return
+	}
+
+	newSeats := make([]string, 0, len(b.SeatIDs)-len(release)+len(acquire))
+	for _, sid := range b.SeatIDs {
+		if !slices.Contains(release, sid) {
+			newSeats = append(newSeats, sid)
+		}
+	}
+	newSeats = append(newSeats, acquire...)
+
+	// 1) lock the new seats (selection rules apply as for any hold); booked
+	// seats would pass the lock script, so they are turned away first
+	free, err := h.seatLock.FreeSeats(ctx, b.ShowtimeID, acquire)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+	for _, sid := range acquire {
+		if !free[sid] {
+			c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seat_unavailable", "seat_id": sid})
+			return
+		}
+	}
+	rid := "exchange-" + uuid.NewString()
+	locked, conflicted, err := h.seatLock.LockSeatsWithOptions(ctx, b.ShowtimeID, acquire, owner, rid, seatlock.LockOptions{})
+	var violation *seatlock.RuleViolation
+	if errors.As(err, &violation) {
+		c.JSON(violationStatus(violation), gin.H{
+			"ok":      false,
+			"error":   violation.Code,
+			"rule":    violation.Rule,
+			"seat_id": violation.SeatID,
+		})
+		return
+	}
+	if errors.Is(err, seatlock.ErrRebuilding) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": "seats_rebuilding"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "lock_failed"})
+		return
+	}
+	if !locked {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seat_locked", "seat_id": conflicted})
+		return
+	}
+
+	// 2) price difference at list price; a refund never exceeds what was paid
+	pricing := &model.PriceBreakdown{Subtotal: b.Amount, Total: b.Amount}
+	if b.Pricing != nil {
+		p := *b.Pricing
+		p.Lines = slices.Clone(p.Lines)
+		pricing = &p
+	}
+	pricing.Add(model.PriceLine{
+		Kind:   "exchange",
+		Label:  strings.Join(release, ",") + " -> " + strings.Join(acquire, ","),
+		Amount: seatsPrice(acquire) - seatsPrice(release),
+	})
+	difference := pricing.Total - b.Amount
+
+	// 3) mock payment: charge or refund the difference
+	paymentRef := ""
+	switch {
+	case difference > 0:
+		paymentRef = "mock_" + uuid.NewString()
+	case difference < 0:
+		paymentRef = "mock_refund_" + uuid.NewString()
+	}
+
+	// 4) Redis: new seats LOCKED -> BOOKED, old seats freed (atomic)
+	okSwap, conflicted, reason, err := h.seatLock.ExchangeBooked(ctx, b.ShowtimeID, acquire, release, owner, rid, b.ID.Hex())
+	if err != nil || !okSwap {
+		_ = h.seatLock.ReleaseSeats(ctx, b.ShowtimeID, acquire, owner)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "exchange_failed"})
+			return
+		}
+		code, known := exchangeErrors[reason]
+		if !known {
+			code = reason
+		}
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": code, "seat_id": conflicted})
+		return
+	}
+
+	// 5) Mongo (conditional on the seats read above)
+	updated, err := h.bookings.ApplyExchange(ctx, b.ID, b.SeatIDs, newSeats, pricing.Total, pricing, model.BookingExchange{
+		FromSeatIDs: release,
+		ToSeatIDs:   acquire,
+		Difference:  difference,
+		PaymentRef:  paymentRef,
+		At:          time.Now(),
+	})
+	if err != nil {
+		// put the old seats back, unless the booking was cancelled meanwhile
+		// (then the new seats just go back on sale)
+		if cur, ferr := h.bookings.FindByID(ctx, b.ID); ferr == nil && cur.Status != model.BookingBooked {
+			_, _ = h.seatLock.ReleaseBooked(ctx, b.ShowtimeID, acquire, owner, b.ID.Hex())
+		} else {
+			_, _ = h.seatLock.RevertExchange(ctx, b.ShowtimeID, acquire, release, owner, b.ID.Hex())
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "booking_changed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	b = updated
+
+	// 6) publish (best-effort)
+	ev := BookingEvent{
+		Type:        "booking.exchanged",
+		BookingID:   b.ID.Hex(),
+		Showtime:    b.ShowtimeID,
+		UserID:      owner,
+		SeatIDs:     b.SeatIDs,
+		Amount:      b.Amount,
+		Currency:    b.Currency,
+		At:          time.Now().Unix(),
+		FromSeatIDs: release,
+		Difference:  difference,
+	}
+	if raw, e := json.Marshal(ev); e == nil {
+		_ = h.rdb.Publish(ctx, bookingEventsChannel(), raw).Err()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"booking":     b,
+		"difference":  difference,
+		"payment_ref": paymentRef,
+	})
+}
@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"cinema/internal/concession"
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ConcessionHandler struct {
+	svc   *concession.Service
+	items *repo.ConcessionRepo
+}
+
+func NewConcessionHandler(svc *concession.Service, items *repo.ConcessionRepo) *ConcessionHandler {
+	return &ConcessionHandler{svc: svc, items: items}
+}
+
+// GET /api/cinemas/:cinemaId/concessions
+func (h *ConcessionHandler) Catalog(c *gin.Context) {
+	cinemaID := c.Param("cinemaId")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	// admins also see inactive items
+	all := c.GetString(middleware.CtxRole) == string(model.RoleAdmin)
+	items, err := h.items.FindCatalog(ctx, cinemaID, all)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":        true,
+		"cinema_id": cinemaID,
+		"items":     items,
+	})
+}
+
+type concessionLineReq struct {
+	ItemID string `json:"item_id"`
+	Qty    int64  `json:"qty"`
+}
+
+type reserveConcessionsReq struct {
+	CinemaID  string              `json:"cinema_id"`
+	SeatIDs   []string            `json:"seat_ids"`
+	RequestID string              `json:"request_id"`
+	Items     []concessionLineReq `json:"items"` // empty = remove add-ons
+}
+
+// PUT /api/showtimes/:showtimeId/concessions
+// Sets the add-ons of the caller's current hold (seat_ids + request_id as
+// locked). Stock is reserved until confirm or until the hold ends.
+func (h *ConcessionHandler) Reserve(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	owner := c.GetString(middleware.CtxUserID)
+
+	var req reserveConcessionsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	seatIDs, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return
+	}
+	rid := strings.TrimSpace(req.RequestID)
+	if rid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_request_id"})
+		return
+	}
+	cinemaID := strings.TrimSpace(req.CinemaID)
+	if cinemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_cinema_id"})
+		return
+	}
+	if len(req.Items) > concession.MaxLines {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "too_many_items"})
+		return
+	}
+
+	// merge duplicate items
+	qty := make(map[primitive.ObjectID]int64, len(req.Items))
+	lines := make([]concession.LineReq, 0, len(req.Items))
+	for _, it := range req.Items {
+		id, err := primitive.ObjectIDFromHex(it.ItemID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_item_id"})
+			return
+		}
+		if _, seen := qty[id]; !seen {
+			lines = append(lines, concession.LineReq{ItemID: id})
+		}
+		qty[id] += it.Qty
+	}
+	for i := range lines {
+		lines[i].Qty = qty[lines[i].ItemID]
+		if lines[i].Qty < 1 || lines[i].Qty > concession.MaxLineQty {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_qty"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	res, err := h.svc.Reserve(ctx, concession.Hold{
+		CinemaID:   cinemaID,
+		ShowtimeID: showtimeID,
+		UserID:     owner,
+		RequestID:  rid,
+		SeatIDs:    seatIDs,
+	}, lines)
+	if err != nil {
+		var ce *concession.Error
+		if errors.As(err, &ce) {
+			status := http.StatusBadRequest
+			switch ce {
+			case concession.ErrNotHeld, concession.ErrOutOfStock:
+				status = http.StatusConflict
+			case concession.ErrUnknownItem:
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"ok": false, "error": ce.Code})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "reserve_failed"})
+		return
+	}
+
+	resp := gin.H{"ok": true, "showtime_id": showtimeID, "reservation": res}
+	if res != nil {
+		resp["total"] = res.Total()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/showtimes/:showtimeId/concessions?request_id=
+func (h *ConcessionHandler) Current(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	owner := c.GetString(middleware.CtxUserID)
+
+	rid := strings.TrimSpace(c.Query("request_id"))
+	if rid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_request_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	res, err := h.svc.ForHold(ctx, showtimeID, owner, rid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	resp := gin.H{"ok": true, "showtime_id": showtimeID, "reservation": res}
+	if res != nil {
+		resp["total"] = res.Total()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type createConcessionReq struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Kind        model.ConcessionKind `json:"kind"` // default ITEM
+	Price       int64                `json:"price"`
+	Stock       int64                `json:"stock"`
+	Active      *bool                `json:"active"` // default true
+}
+
+// POST /api/admin/cinemas/:cinemaId/concessions
+func (h *ConcessionHandler) Create(c *gin.Context) {
+	var req createConcessionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	it := &model.ConcessionItem{
+		CinemaID:    strings.TrimSpace(c.Param("cinemaId")),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Kind:        req.Kind,
+		Price:       req.Price,
+		Stock:       req.Stock,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if it.Kind == "" {
+		it.Kind = model.ConcessionItemKind
+	}
+	switch {
+	case it.CinemaID == "":
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_cinema_id"})
+		return
+	case it.Name == "":
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_name"})
+		return
+	case it.Kind != model.ConcessionItemKind && it.Kind != model.ConcessionComboKind:
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_kind"})
+		return
+	case it.Price < 0:
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_price"})
+		return
+	case it.Stock < 0:
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_stock"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.items.CreateItem(ctx, it); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_create_failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "item": it})
+}
+
+type updateConcessionReq struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Price       *int64  `json:"price"`
+	Stock       *int64  `json:"stock"` // sets available stock (reserved units excluded)
+	Active      *bool   `json:"active"`
+}
+
+// PATCH /api/admin/concessions/:itemId
+func (h *ConcessionHandler) Update(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_item_id"})
+		return
+	}
+
+	var req updateConcessionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	set := bson.M{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_name"})
+			return
+		}
+		set["name"] = name
+	}
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+	if req.Price != nil {
+		if *req.Price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_price"})
+			return
+		}
+		set["price"] = *req.Price
+	}
+	if req.Stock != nil {
+		if *req.Stock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_stock"})
+			return
+		}
+		set["stock"] = *req.Stock
+	}
+	if req.Active != nil {
+		set["active"] = *req.Active
+	}
+	if len(set) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "nothing_to_update"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	it, err := h.items.UpdateItem(ctx, id, set)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "concession_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "item": it})
+}
+
+type pickupReq struct {
+	Code string `json:"code"`
+}
+
+// POST /api/admin/concessions/pickup
+// Counter staff hand over an order by its pickup code (once).
+func (h *ConcessionHandler) PickUp(c *gin.Context) {
+	var req pickupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_code"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	res, err := h.svc.PickUp(ctx, code)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// unknown, cancelled or already picked up
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "pickup_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "order": res})
+}
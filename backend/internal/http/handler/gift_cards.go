@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"cinema/internal/giftcard"
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GiftCardHandler struct {
+	svc *giftcard.Service
+}
+
+func NewGiftCardHandler(svc *giftcard.Service) *GiftCardHandler {
+	return &GiftCardHandler{svc: svc}
+}
+
+type issueGiftCardReq struct {
+	Amount int64 `json:"amount"`
+}
+
+func validGiftCardAmount(amount int64) bool {
+	return amount > 0 && amount <= giftcard.MaxAmount
+}
+
+// POST /api/gift-cards
+// Buys a card (mock payment, like bookings); the code is only shown here.
+func (h *GiftCardHandler) Purchase(c *gin.Context) {
+	uid := c.GetString(middleware.CtxUserID)
+
+	var req issueGiftCardReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	if !validGiftCardAmount(req.Amount) {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_amount"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	paymentRef := "mock_" + uuid.NewString()
+	g, err := h.svc.Issue(ctx, req.Amount, priceCurrency, model.GiftCardPurchase, uid, paymentRef)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "issue_failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "gift_card": g, "balance": g.Initial})
+}
+
+// GET /api/gift-cards/:code
+// Balance check for whoever holds the code.
+func (h *GiftCardHandler) Balance(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	g, balance, err := h.svc.Lookup(ctx, c.Param("code"))
+	if !writeGiftCardError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":       true,
+		"code":     maskCode(g.Code),
+		"currency": g.Currency,
+		"balance":  balance,
+		"active":   g.Active,
+	})
+}
+
+// POST /api/admin/gift-cards
+func (h *GiftCardHandler) AdminIssue(c *gin.Context) {
+	adminID := c.GetString(middleware.CtxUserID)
+
+	var req issueGiftCardReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	if !validGiftCardAmount(req.Amount) {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_amount"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	g, err := h.svc.Issue(ctx, req.Amount, priceCurrency, model.GiftCardAdmin, adminID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "issue_failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "gift_card": g, "balance": g.Initial})
+}
+
+// GET /api/admin/gift-cards/:code
+// Card with its full ledger.
+func (h *GiftCardHandler) AdminGet(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	g, balance, err := h.svc.Lookup(ctx, c.Param("code"))
+	if !writeGiftCardError(c, err) {
+		return
+	}
+	ledger, err := h.svc.Ledger(ctx, g.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":        true,
+		"gift_card": g,
+		"balance":   balance,
+		"ledger":    ledger,
+	})
+}
+
+// DELETE /api/admin/gift-cards/:code
+// Deactivates the card (no further redemptions); refunds still land on it.
+func (h *GiftCardHandler) AdminDeactivate(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	g, _, err := h.svc.Lookup(ctx, c.Param("code"))
+	if !writeGiftCardError(c, err) {
+		return
+	}
+	g, err = h.svc.SetActive(ctx, g.ID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_update_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "gift_card": g})
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"cinema/internal/groupbooking"
+	"cinema/internal/http/middleware"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupBookingHandler struct {
+	svc         *groupbooking.Service
+	frontendURL string
+}
+
+func NewGroupBookingHandler(svc *groupbooking.Service, frontendURL string) *GroupBookingHandler {
+	return &GroupBookingHandler{svc: svc, frontendURL: strings.TrimRight(frontendURL, "/")}
+}
+
+type createGroupReq struct {
+	SeatIDs   []string `json:"seat_ids"`
+	RequestID string   `json:"request_id"` // the organizer's lock
+}
+
+func (h *GroupBookingHandler) inviteURL(g *groupbooking.Group) string {
+	q := url.Values{}
+	q.Set("group", g.ID)
+	q.Set("code", g.InviteCode)
+	return h.frontendURL + "/?" + q.Encode()
+}
+
+// POST /api/showtimes/:showtimeId/groups
+func (h *GroupBookingHandler) Create(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	uid := c.GetString(middleware.CtxUserID)
+
+	var req createGroupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	seatIDs, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok || len(seatIDs) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return
+	}
+	rid := strings.TrimSpace(req.RequestID)
+	if rid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_request_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	g, err := h.svc.Create(ctx, showtimeID, uid, rid, seatIDs)
+	if errors.Is(err, groupbooking.ErrNotHeld) {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seats_not_held"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "group_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"group_id":    g.ID,
+		"showtime_id": g.ShowtimeID,
+		"seat_ids":    g.SeatIDs,
+		"invite_code": g.InviteCode,
+		"invite_url":  h.inviteURL(g),
+		"expires_at":  g.ExpiresAt,
+	})
+}
+
+// load group for the caller (organizer, or ?code= / body code)
+func (h *GroupBookingHandler) load(ctx context.Context, c *gin.Context, code string) (*groupbooking.Group, bool) {
+	g, err := h.svc.Get(ctx, c.Param("groupId"), c.GetString(middleware.CtxUserID), code)
+	switch {
+	case errors.Is(err, groupbooking.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "group_not_found"})
+		return nil, false
+	case errors.Is(err, groupbooking.ErrInvalidCode):
+		c.JSON(http.StatusForbidden, gin.H{"ok": false, "error": "invalid_invite_code"})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "group_failed"})
+		return nil, false
+	}
+	return g, true
+}
+
+// GET /api/groups/:groupId?code=
+func (h *GroupBookingHandler) Get(c *gin.Context) {
+	uid := c.GetString(middleware.CtxUserID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	g, ok := h.load(ctx, c, strings.TrimSpace(c.Query("code")))
+	if !ok {
+		return
+	}
+
+	seats, err := h.svc.Seats(ctx, g, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "group_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":           true,
+		"group_id":     g.ID,
+		"showtime_id":  g.ShowtimeID,
+		"is_organizer": uid == g.Organizer,
+		"seats":        seats,
+		"expires_at":   g.ExpiresAt,
+	})
+}
+
+type claimGroupReq struct {
+	Code    string   `json:"code"`
+	SeatIDs []string `json:"seat_ids"`
+}
+
+// POST /api/groups/:groupId/claim
+// Then pay with POST /api/showtimes/:showtimeId/bookings/confirm
+// {seat_ids, request_id} using the returned request_id.
+func (h *GroupBookingHandler) Claim(c *gin.Context) {
+	uid := c.GetString(middleware.CtxUserID)
+
+	var req claimGroupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	seatIDs, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	g, ok := h.load(ctx, c, strings.TrimSpace(req.Code))
+	if !ok {
+		return
+	}
+
+	rid, err := h.svc.Claim(ctx, g, uid, seatIDs)
+	switch {
+	case errors.Is(err, groupbooking.ErrNotInGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "seat_not_in_group"})
+		return
+	case errors.Is(err, groupbooking.ErrSeatTaken):
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seat_not_available"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "claim_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"group_id":    g.ID,
+		"showtime_id": g.ShowtimeID,
+		"seat_ids":    seatIDs,
+		"request_id":  rid,
+		"expires_at":  g.ExpiresAt,
+	})
+}
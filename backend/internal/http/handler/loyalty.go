@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/loyalty"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	svc *loyalty.Service
+}
+
+func NewLoyaltyHandler(svc *loyalty.Service) *LoyaltyHandler {
+	return &LoyaltyHandler{svc: svc}
+}
+
+// GET /api/me/loyalty?limit=
+// Balance, tier + benefits, progress to the next tier and latest ledger entries.
+func (h *LoyaltyHandler) Me(c *gin.Context) {
+	uid := c.GetString(middleware.CtxUserID)
+
+	var limit int64
+	if v := c.Query("limit"); v != "" {
+		limit, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	acc, err := h.svc.Account(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	entries, err := h.svc.History(ctx, uid, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	resp := gin.H{
+		"ok":              true,
+		"points":          acc.Points,
+		"lifetime_points": acc.Lifetime,
+		"tier":            acc.Tier,
+		"benefits":        loyalty.BenefitsOf(acc.Tier),
+		"points_per_unit": loyalty.PointsPerUnit,
+		"ledger":          entries,
+	}
+	if next, at := loyalty.NextTier(acc.Tier); next != "" {
+		resp["next_tier"] = gin.H{"tier": next, "points_needed": max(at-acc.Lifetime, 0)}
+	}
+	c.JSON(http.StatusOK, resp)
+}
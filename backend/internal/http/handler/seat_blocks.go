@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/seatlock"
+	"cinema/internal/showtime"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeatBlockHandler lets admins take seats off sale (house holds, broken
+// seats, press) for one showtime or for every showtime in a hall.
+type SeatBlockHandler struct {
+	seatLock  *seatlock.Service
+	showtimes *showtime.Service
+	seatMap   *seatlock.SeatMap
+}
+
+func NewSeatBlockHandler(seatLock *seatlock.Service, showtimes *showtime.Service, seatMap *seatlock.SeatMap) *SeatBlockHandler {
+	return &SeatBlockHandler{seatLock: seatLock, showtimes: showtimes, seatMap: seatMap}
+}
+
+type seatBlockReq struct {
+	SeatIDs []string `json:"seat_ids"`
+	Reason  string   `json:"reason,omitempty"` // required to block
+}
+
+// blockRequest reads and checks the body; seats must exist in the seat map.
+func (h *SeatBlockHandler) blockRequest(c *gin.Context, needReason bool) ([]string, string, bool) {
+	var req seatBlockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return nil, "", false
+	}
+	seatIDs, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return nil, "", false
+	}
+	for _, sid := range seatIDs {
+		if !h.seatMap.Has(sid) {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "unknown_seat", "seat_id": sid})
+			return nil, "", false
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if needReason && reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_reason"})
+		return nil, "", false
+	}
+	return seatIDs, reason, true
+}
+
+func hallParam(c *gin.Context) (string, bool) {
+	hall := strings.TrimSpace(c.Param("hall"))
+	if hall == "" || len(hall) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_hall"})
+		return "", false
+	}
+	return hall, true
+}
+
+// GET /api/admin/showtimes/:showtimeId/blocks
+// Blocks in force for the showtime, its hall's included (those carry "hall").
+func (h *SeatBlockHandler) List(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	blocks, err := h.seatLock.Blocks(ctx, showtimeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "list_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "showtime_id": showtimeID, "blocks": blocks})
+}
+
+// POST /api/admin/showtimes/:showtimeId/blocks
+// Blocks free seats of one showtime; all or nothing.
+func (h *SeatBlockHandler) Block(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	if !showtimeIDRe.MatchString(showtimeID) {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_showtime_id"})
+		return
+	}
+	seatIDs, reason, ok := h.blockRequest(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	okBlock, conflicted, state, err := h.seatLock.BlockSeats(ctx, showtimeID, seatIDs, reason, c.GetString(middleware.CtxUserID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "block_failed"})
+		return
+	}
+	if !okBlock {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seat_" + state, "seat_id": conflicted})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "blocked": seatIDs})
+}
+
+// POST /api/admin/showtimes/:showtimeId/blocks/release
+// Puts blocked seats back on sale ("released" lists those now free; seats
+// still blocked for the hall are not).
+func (h *SeatBlockHandler) Release(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	seatIDs, _, ok := h.blockRequest(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	freed, err := h.seatLock.UnblockSeats(ctx, showtimeID, seatIDs, c.GetString(middleware.CtxUserID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "release_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "released": freed})
+}
+
+// GET /api/admin/halls/:hall/blocks
+func (h *SeatBlockHandler) HallList(c *gin.Context) {
+	hall, ok := hallParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	blocks, err := h.seatLock.HallBlocks(ctx, hall)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "list_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "hall": hall, "blocks": blocks})
+}
+
+// POST /api/admin/halls/:hall/blocks
+// Blocks seats for every showtime in the hall, including future ones. Seats
+// already held or booked for a showtime keep that hold.
+func (h *SeatBlockHandler) HallBlock(c *gin.Context) {
+	hall, ok := hallParam(c)
+	if !ok {
+		return
+	}
+	seatIDs, reason, ok := h.blockRequest(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	showtimeIDs, err := h.showtimes.HallShowtimes(ctx, hall)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	if err := h.seatLock.BlockHallSeats(ctx, hall, seatIDs, reason, c.GetString(middleware.CtxUserID), showtimeIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "block_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "blocked": seatIDs, "showtimes": showtimeIDs})
+}
+
+// POST /api/admin/halls/:hall/blocks/release
+func (h *SeatBlockHandler) HallRelease(c *gin.Context) {
+	hall, ok := hallParam(c)
+	if !ok {
+		return
+	}
+	seatIDs, _, ok := h.blockRequest(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	showtimeIDs, err := h.showtimes.HallShowtimes(ctx, hall)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	removed, err := h.seatLock.UnblockHallSeats(ctx, hall, seatIDs, c.GetString(middleware.CtxUserID), showtimeIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "release_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "released": removed})
+}
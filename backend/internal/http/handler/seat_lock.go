@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"cinema/internal/groupbooking"
 	"cinema/internal/http/middleware"
+	"cinema/internal/loyalty"
+	"cinema/internal/model"
 	"cinema/internal/seatlock"
+	"cinema/internal/showtime"
+	"cinema/internal/waitroom"
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"regexp"
 	"sort"
@@ -17,14 +24,20 @@ import (
 type SeatLockHandler struct {
 	svc        *seatlock.Service
 	ttlSeconds int
+	room       *waitroom.Service
+	loyalty    *loyalty.Service
+	showtimes  *showtime.Service
+	groups     *groupbooking.Service
 }
 
-func NewSeatLockHandler(svc *seatlock.Service, ttlSeconds int) *SeatLockHandler {
-	return &SeatLockHandler{svc: svc, ttlSeconds: ttlSeconds}
+func NewSeatLockHandler(svc *seatlock.Service, ttlSeconds int, room *waitroom.Service, loyaltySvc *loyalty.Service, showtimes *showtime.Service, groups *groupbooking.Service) *SeatLockHandler {
+	return &SeatLockHandler{svc: svc, ttlSeconds: ttlSeconds, room: room, loyalty: loyaltySvc, showtimes: showtimes, groups: groups}
 }
 
 type lockReq struct {
 	SeatIDs []string `json:"seat_ids"`
+	// admin only: skip seat selection rules (e.g. single-seat gap)
+	BypassRules bool `json:"bypass_rules,omitempty"`
 }
 
 var seatIDRe = regexp.MustCompile(`^[A-Z]{1,3}[0-9]{1,3}$`)
@@ -73,6 +86,12 @@ func (h *SeatLockHandler) Lock(c *gin.Context) {
 		return
 	}
 
+	isAdmin := c.GetString(middleware.CtxRole) == string(model.RoleAdmin)
+	if req.BypassRules && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"ok": false, "error": "bypass_rules_forbidden"})
+		return
+	}
+
 	// request-id (optional header, otherwise generate)
 	rid := strings.TrimSpace(c.GetHeader("X-Request-Id"))
 	if rid == "" {
@@ -82,7 +101,60 @@ func (h *SeatLockHandler) Lock(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
 	defer cancel()
 
-	okLock, conflicted, err := h.svc.LockSeats(ctx, showtimeID, seatIDs, owner, rid)
+	// sales window (showtime lifecycle)
+	if _, err := h.showtimes.CheckSales(ctx, showtimeID, time.Now()); !writeShowtimeError(c, err) {
+		return
+	}
+	if !seatStateReady(ctx, c, h.svc, showtimeID) {
+		return
+	}
+
+	// waiting room: while active, only admitted users may lock
+	if !isAdmin {
+		admission := strings.TrimSpace(c.GetHeader("X-Admission-Token"))
+		required, err := h.room.CheckAdmission(ctx, showtimeID, owner, admission)
+		if required && admission == "" {
+			c.JSON(http.StatusForbidden, gin.H{"ok": false, "error": "admission_required"})
+			return
+		}
+		if required && err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"ok": false, "error": "invalid_admission_token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "lock_failed"})
+			return
+		}
+	}
+
+	// member tiers hold seats longer
+	ttlSeconds := h.ttlSeconds
+	benefits, err := h.loyalty.Benefits(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "lock_failed"})
+		return
+	}
+	ttlSeconds += benefits.LockTTLBonusSecs
+
+	ttl := time.Duration(ttlSeconds) * time.Second
+	okLock, conflicted, err := h.svc.LockSeatsWithOptions(ctx, showtimeID, seatIDs, owner, rid, seatlock.LockOptions{
+		BypassRules: req.BypassRules,
+		TTL:         ttl,
+	})
+	var violation *seatlock.RuleViolation
+	if errors.As(err, &violation) {
+		c.JSON(violationStatus(violation), gin.H{
+			"ok":      false,
+			"error":   violation.Code,
+			"rule":    violation.Rule,
+			"seat_id": violation.SeatID,
+		})
+		return
+	}
+	if errors.Is(err, seatlock.ErrRebuilding) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": "seats_rebuilding"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "lock_failed"})
 		return
@@ -96,10 +168,15 @@ func (h *SeatLockHandler) Lock(c *gin.Context) {
 		return
 	}
 
+	// re-locking a group hold extends the group with it
+	if err := h.groups.Touch(ctx, showtimeID, owner, rid, ttl); err != nil {
+		log.Printf("group touch %s %s:%s: %v", showtimeID, owner, rid, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"ok":          true,
 		"locked":      seatIDs,
-		"ttl_seconds": h.ttlSeconds,
+		"ttl_seconds": ttlSeconds,
 		"request_id":  rid,
 	})
 }
@@ -131,7 +208,7 @@ func (h *SeatLockHandler) Release(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true, "released": seatIDs})
 }
 
-// Debug/dev endpoint
+// Debug/dev endpoint (admin only: exposes lock owners)
 func (h *SeatLockHandler) ListLocks(c *gin.Context) {
 	showtimeID := c.Param("showtimeId")
 
@@ -153,17 +230,12 @@ func (h *SeatLockHandler) ListLocks(c *gin.Context) {
 
 func (h *SeatLockHandler) SeatState(c *gin.Context) {
 	showtimeID := c.Param("showtimeId")
+	viewer := c.GetString(middleware.CtxUserID)
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
 	defer cancel()
 
-	locks, err := h.svc.ListLocks(ctx, showtimeID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "list_failed"})
-		return
-	}
-
-	booked, err := h.svc.ListBookedSeats(ctx, showtimeID)
+	snap, err := h.svc.Snapshot(ctx, showtimeID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "list_failed"})
 		return
@@ -172,7 +244,32 @@ func (h *SeatLockHandler) SeatState(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"ok":          true,
 		"showtime_id": showtimeID,
-		"locks":       locks,
-		"booked":      booked,
+		"seq":         snap.Seq,                        // pass as ?since= when connecting the WebSocket
+		"locks":       publicLocks(snap.Locks, viewer), // no owner ids, only "mine"
+		"booked":      snap.Booked,
+		"blocked":     snap.Blocked,
 	})
 }
+
+// seatStateReady answers 503 seats_rebuilding while the showtime's booked
+// markers are being rebuilt from Mongo (after Redis lost its data).
+func seatStateReady(ctx context.Context, c *gin.Context, seats *seatlock.Service, showtimeID string) bool {
+	rebuilding, err := seats.Rebuilding(ctx, showtimeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return false
+	}
+	if rebuilding {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": "seats_rebuilding"})
+		return false
+	}
+	return true
+}
+
+// violationStatus: unknown seat ids are a bad request, the rest a conflict.
+func violationStatus(v *seatlock.RuleViolation) int {
+	if v.Code == "invalid_seat" {
+		return http.StatusBadRequest
+	}
+	return http.StatusConflict
+}
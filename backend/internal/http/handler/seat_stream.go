@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Shared by the WebSocket and SSE seat streams.
+
+func seatEventsChannel(showtimeID string) string {
+	return "seat-events:" + showtimeID
+}
+
+// =====================
+// Public (redacted) seat payloads
+// =====================
+
+// The showtime channel must not reveal who holds a seat: owner becomes a
+// per-viewer "mine" flag; request/booking ids are only kept for the owner.
+
+type publicLock struct {
+	SeatID     string `json:"seat_id"`
+	TTLSeconds int64  `json:"ttl_seconds"`
+	Mine       bool   `json:"mine"`
+}
+
+type publicSeatEvent struct {
+	Seq        int64    `json:"seq,omitempty"`
+	Type       string   `json:"type"`
+	ShowtimeID string   `json:"showtime_id"`
+	SeatIDs    []string `json:"seat_ids"`
+	Mine       bool     `json:"mine"`
+	RequestID  string   `json:"request_id,omitempty"`
+	BookingID  string   `json:"booking_id,omitempty"`
+	At         int64    `json:"at"`
+}
+
+func publicLocks(locks []seatlock.LockInfo, viewer string) []publicLock {
+	out := make([]publicLock, 0, len(locks))
+	for _, l := range locks {
+		out = append(out, publicLock{
+			SeatID:     l.SeatID,
+			TTLSeconds: l.TTLSeconds,
+			Mine:       viewer != "" && l.Owner == viewer,
+		})
+	}
+	return out
+}
+
+// publicSeatFrame redacts one published SeatEvent for viewer.
+func publicSeatFrame(payload []byte, viewer string) []byte {
+	var ev seatlock.SeatEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil
+	}
+
+	mine := viewer != "" && ev.Owner == viewer
+	pub := publicSeatEvent{
+		Seq:        ev.Seq,
+		Type:       ev.Type,
+		ShowtimeID: ev.ShowtimeID,
+		SeatIDs:    ev.SeatIDs,
+		Mine:       mine,
+		At:         ev.At,
+	}
+	if mine {
+		pub.RequestID = ev.RequestID
+		pub.BookingID = ev.BookingID
+	}
+
+	b, err := json.Marshal(pub)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// seatCatchUp returns what a (re)connecting client needs before live events:
+// the events after since when the log still covers them, otherwise one
+// "snapshot" frame. seq is the last seq covered by the frames.
+// Callers join the hub first so live events racing with this are queued,
+// then skip queued events with seq <= seq.
+func seatCatchUp(ctx context.Context, svc *seatlock.Service, showtimeID, sinceStr, viewer string) (frames [][]byte, seq int64, err error) {
+	if sinceStr != "" {
+		if since, perr := strconv.ParseInt(sinceStr, 10, 64); perr == nil {
+			events, ok, err := svc.EventsSince(ctx, showtimeID, since)
+			if err != nil {
+				return nil, 0, err
+			}
+			if ok {
+				last := since
+				out := make([][]byte, 0, len(events))
+				for _, ev := range events {
+					last = seatlock.EventSeq(ev)
+					if f := publicSeatFrame(ev, viewer); f != nil {
+						out = append(out, f)
+					}
+				}
+				return out, last, nil
+			}
+		}
+	}
+
+	snap, err := svc.Snapshot(ctx, showtimeID)
+	if err != nil {
+		return nil, 0, err
+	}
+	b, err := json.Marshal(gin.H{
+		"type":        "snapshot",
+		"showtime_id": showtimeID,
+		"seq":         snap.Seq,
+		"locks":       publicLocks(snap.Locks, viewer),
+		"booked":      snap.Booked,
+		"blocked":     snap.Blocked,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return [][]byte{b}, snap.Seq, nil
+}
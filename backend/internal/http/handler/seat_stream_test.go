@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"cinema/internal/seatlock"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPublicSeatFrame(t *testing.T) {
+	payload, err := json.Marshal(seatlock.SeatEvent{
+		Seq:        7,
+		Type:       "booked",
+		ShowtimeID: "st1",
+		SeatIDs:    []string{"A1"},
+		Owner:      "user-1",
+		RequestID:  "r1",
+		BookingID:  "b1",
+		At:         100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		viewer string
+		mine   bool
+	}{
+		{name: "owner", viewer: "user-1", mine: true},
+		{name: "someone else", viewer: "user-2"},
+		{name: "anonymous", viewer: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := publicSeatFrame(payload, tt.viewer)
+			if strings.Contains(string(frame), "user-1") {
+				t.Fatalf("frame leaks the owner: %s", frame)
+			}
+
+			var got publicSeatEvent
+			if err := json.Unmarshal(frame, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Seq != 7 || got.Type != "booked" || got.SeatIDs[0] != "A1" || got.Mine != tt.mine {
+				t.Fatalf("frame = %+v", got)
+			}
+			// request/booking ids only go back to their owner
+			if tt.mine != (got.RequestID == "r1" && got.BookingID == "b1") {
+				t.Fatalf("ids = %q/%q for mine=%v", got.RequestID, got.BookingID, tt.mine)
+			}
+		})
+	}
+
+	if publicSeatFrame([]byte("not json"), "user-1") != nil {
+		t.Fatal("bad payload not dropped")
+	}
+}
+
+func TestPublicLocks(t *testing.T) {
+	locks := []seatlock.LockInfo{
+		{SeatID: "A1", Owner: "user-1", TTLSeconds: 30},
+		{SeatID: "A2", Owner: "user-2", TTLSeconds: 40},
+	}
+	got := publicLocks(locks, "user-1")
+	if len(got) != 2 || !got[0].Mine || got[1].Mine || got[1].TTLSeconds != 40 {
+		t.Fatalf("publicLocks = %+v", got)
+	}
+	if got := publicLocks(locks, ""); got[0].Mine || got[1].Mine {
+		t.Fatalf("anonymous viewer owns a lock: %+v", got)
+	}
+}
@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"cinema/internal/refund"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"cinema/internal/showtime"
+	"cinema/internal/waitlist"
+	"cinema/internal/waitroom"
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// same charset as the ids used in seat keys and channels
+var showtimeIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type ShowtimeHandler struct {
+	svc      *showtime.Service
+	seatLock *seatlock.Service
+	waitlist *waitlist.Service
+	room     *waitroom.Service
+	refunds  *refund.Service
+}
+
+func NewShowtimeHandler(
+	svc *showtime.Service,
+	seatLock *seatlock.Service,
+	waitlistSvc *waitlist.Service,
+	room *waitroom.Service,
+	refunds *refund.Service,
+) *ShowtimeHandler {
+	return &ShowtimeHandler{
+		svc:      svc,
+		seatLock: seatLock,
+		waitlist: waitlistSvc,
+		room:     room,
+		refunds:  refunds,
+	}
+}
+
+func writeShowtimeError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var se *showtime.Error
+	if errors.As(err, &se) {
+		status := http.StatusConflict
+		switch se {
+		case showtime.ErrNotFound:
+			status = http.StatusNotFound
+		case showtime.ErrInvalidSchedule:
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"ok": false, "error": se.Code})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+	return false
+}
+
+// published = anything past DRAFT
+var publishedStatuses = []model.ShowtimeStatus{
+	model.ShowtimeOnSale,
+	model.ShowtimeSalesClosed,
+	model.ShowtimeStarted,
+	model.ShowtimeCancelled,
+}
+
+// showtimeFilter reads ?movie_id=&status=&from=&to=&limit=&skip= (times RFC3339).
+func showtimeFilter(c *gin.Context) (repo.ShowtimeFilter, bool) {
+	f := repo.ShowtimeFilter{MovieID: strings.TrimSpace(c.Query("movie_id"))}
+	if v := c.Query("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			f.Statuses = append(f.Statuses, model.ShowtimeStatus(strings.ToUpper(strings.TrimSpace(s))))
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_from"})
+			return f, false
+		}
+		f.From = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_to"})
+			return f, false
+		}
+		f.To = &t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		f.Limit = n
+	}
+	if v := c.Query("skip"); v != "" {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		f.Skip = n
+	}
+	return f, true
+}
+
+func (h *ShowtimeHandler) list(c *gin.Context, f repo.ShowtimeFilter) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	items, total, err := h.svc.List(ctx, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "total": total, "items": items})
+}
+
+// GET /api/showtimes?movie_id=&from=&to=
+// Published showtimes only.
+func (h *ShowtimeHandler) List(c *gin.Context) {
+	f, ok := showtimeFilter(c)
+	if !ok {
+		return
+	}
+	allowed := f.Statuses[:0]
+	for _, s := range f.Statuses {
+		if s != model.ShowtimeDraft {
+			allowed = append(allowed, s)
+		}
+	}
+	f.Statuses = allowed
+	if len(f.Statuses) == 0 {
+		f.Statuses = publishedStatuses
+	}
+	h.list(c, f)
+}
+
+// GET /api/showtimes/:showtimeId
+// Includes whether seats can be bought right now.
+func (h *ShowtimeHandler) Get(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	st, err := h.svc.Get(ctx, c.Param("showtimeId"))
+	isAdmin := c.GetString(middleware.CtxRole) == string(model.RoleAdmin)
+	if err == nil && st.Status == model.ShowtimeDraft && !isAdmin {
+		err = showtime.ErrNotFound
+	}
+	if !writeShowtimeError(c, err) {
+		return
+	}
+
+	resp := gin.H{"ok": true, "showtime": st, "on_sale": true}
+	var se *showtime.Error
+	if errors.As(showtime.SalesError(st, time.Now()), &se) {
+		resp["on_sale"] = false
+		resp["reason"] = se.Code
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type createShowtimeReq struct {
+	ID           string     `json:"id"`
+	MovieID      string     `json:"movie_id"`
+	CinemaID     string     `json:"cinema_id"`
+	Hall         string     `json:"hall"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	SalesOpenAt  *time.Time `json:"sales_open_at"`  // default: now
+	SalesCloseAt *time.Time `json:"sales_close_at"` // default: starts_at
+}
+
+// POST /api/admin/showtimes
+// Creates a DRAFT showtime; publish it to start selling.
+func (h *ShowtimeHandler) Create(c *gin.Context) {
+	var req createShowtimeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	if !showtimeIDRe.MatchString(req.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_showtime_id"})
+		return
+	}
+	if strings.TrimSpace(req.MovieID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_movie_id"})
+		return
+	}
+
+	st := &model.Showtime{
+		ID:        req.ID,
+		MovieID:   strings.TrimSpace(req.MovieID),
+		CinemaID:  strings.TrimSpace(req.CinemaID),
+		Hall:      strings.TrimSpace(req.Hall),
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: c.GetString(middleware.CtxUserID),
+	}
+	if req.SalesOpenAt != nil {
+		st.SalesOpenAt = *req.SalesOpenAt
+	}
+	if req.SalesCloseAt != nil {
+		st.SalesCloseAt = *req.SalesCloseAt
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if !writeShowtimeError(c, h.svc.Create(ctx, st)) {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "showtime": st})
+}
+
+// GET /api/admin/showtimes?movie_id=&status=&from=&to=
+func (h *ShowtimeHandler) AdminList(c *gin.Context) {
+	f, ok := showtimeFilter(c)
+	if !ok {
+		return
+	}
+	h.list(c, f)
+}
+
+type scheduleShowtimeReq struct {
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	SalesOpenAt  *time.Time `json:"sales_open_at"`
+	SalesCloseAt *time.Time `json:"sales_close_at"`
+}
+
+// PATCH /api/admin/showtimes/:showtimeId
+// Reschedules a DRAFT or ON_SALE showtime.
+func (h *ShowtimeHandler) Update(c *gin.Context) {
+	var req scheduleShowtimeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	st, err := h.svc.Schedule(ctx, c.Param("showtimeId"), req.StartsAt, req.EndsAt, req.SalesOpenAt, req.SalesCloseAt)
+	if !writeShowtimeError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "showtime": st})
+}
+
+// POST /api/admin/showtimes/:showtimeId/publish
+func (h *ShowtimeHandler) Publish(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	st, err := h.svc.Publish(ctx, c.Param("showtimeId"))
+	if !writeShowtimeError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "showtime": st})
+}
+
+type cancelShowtimeReq struct {
+	Reason string `json:"reason"`
+}
+
+// POST /api/admin/showtimes/:showtimeId/cancel
+// Stops sales, drops every seat hold, waitlist and waiting room of the
+// showtime, and starts the refund job that cancels its bookings. Calling it
+// again on a cancelled showtime redoes these steps and returns the job.
+func (h *ShowtimeHandler) Cancel(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	adminID := c.GetString(middleware.CtxUserID)
+
+	var req cancelShowtimeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "missing_reason"})
+		return
+	}
+	if !showtimeIDRe.MatchString(showtimeID) {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_showtime_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// 1) no more locks or confirms
+	st, err := h.svc.Cancel(ctx, showtimeID, adminID, reason)
+	if !writeShowtimeError(c, err) {
+		return
+	}
+
+	// 2) nobody waits for seats that won't be sold
+	if err := h.waitlist.Clear(ctx, showtimeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+	if err := h.room.Close(ctx, showtimeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis_failed"})
+		return
+	}
+
+	// 3) active holds end now
+	released, err := h.seatLock.ReleaseAllLocks(ctx, showtimeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "release_failed"})
+		return
+	}
+
+	// 4) bookings are cancelled + refunded in the background
+	job, err := h.refunds.Start(ctx, showtimeID, adminID, reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "refund_job_failed"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ok":             true,
+		"showtime":       st,
+		"locks_released": released,
+		"refund_job":     job,
+	})
+}
+
+// GET /api/admin/showtimes/:showtimeId/refunds
+// Progress of the showtime's refund job.
+func (h *ShowtimeHandler) Refunds(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	job, err := h.refunds.Job(ctx, c.Param("showtimeId"))
+	if errors.Is(err, refund.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "refund_job_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "refund_job": job})
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"cinema/internal/realtime"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeat keeps proxies from closing an idle stream
+const sseHeartbeat = 15 * time.Second
+
+// startSSE writes the event-stream headers and the reconnect delay hint.
+func startSSE(w gin.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: don't buffer the stream
+	w.WriteHeader(http.StatusOK)
+
+	// reconnect delay hint for EventSource
+	_, _ = fmt.Fprint(w, "retry: 2000\n\n")
+	w.Flush()
+}
+
+// one SSE frame; id = seq so the browser sends it back as Last-Event-ID
+func writeSSE(w gin.ResponseWriter, seq int64, data []byte) error {
+	var err error
+	if seq > 0 {
+		_, err = fmt.Fprintf(w, "id: %d\n", seq)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	w.Flush()
+	return err
+}
+
+// pumpSSE writes the client's messages as frames, with heartbeats, until
+// ctx ends, quit is closed, the hub drops the client or a write fails.
+// frame maps a payload to the event id and data to send (nil = skip).
+func pumpSSE(ctx context.Context, w gin.ResponseWriter, client *realtime.Client, quit <-chan struct{}, frame func(payload []byte) (int64, []byte)) {
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-quit:
+			return
+		case <-client.Done():
+			// too slow (or hub closed): EventSource reconnects with Last-Event-ID
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case payload := <-client.Send():
+			seq, data := frame(payload)
+			if data == nil {
+				continue
+			}
+			if err := writeSSE(w, seq, data); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/realtime"
+	"cinema/internal/seatlock"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeatSSEHandler streams seat events over Server-Sent Events for clients
+// that cannot use WebSockets. Same payloads and hub as SeatWSHandler.
+type SeatSSEHandler struct {
+	hub *realtime.Hub
+	svc *seatlock.Service
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+func NewSeatSSEHandler(hub *realtime.Hub, svc *seatlock.Service) *SeatSSEHandler {
+	return &SeatSSEHandler{hub: hub, svc: svc, quit: make(chan struct{})}
+}
+
+// Drain ends every open stream; http.Server.Shutdown would otherwise wait
+// for them until its deadline. Use with srv.RegisterOnShutdown.
+func (h *SeatSSEHandler) Drain() {
+	h.quitOnce.Do(func() { close(h.quit) })
+}
+
+// GET /sse/showtimes/:showtimeId/seats  (Authorization: Bearer <JWT>)
+// Resume: Last-Event-ID header (or ?since=<seq>).
+func (h *SeatSSEHandler) Seats(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	viewer := c.GetString(middleware.CtxUserID)
+
+	since := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if since == "" {
+		since = strings.TrimSpace(c.Query("since"))
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// shared subscription (one per showtime per process)
+	client, err := h.hub.Join(ctx, seatEventsChannel(showtimeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "subscribe_failed"})
+		return
+	}
+	defer h.hub.Leave(client)
+
+	frames, lastSeq, err := seatCatchUp(ctx, h.svc, showtimeID, since, viewer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "resync_failed"})
+		return
+	}
+
+	w := c.Writer
+	startSSE(w)
+
+	for _, f := range frames {
+		if err := writeSSE(w, seatlock.EventSeq(f), f); err != nil {
+			return
+		}
+	}
+
+	pumpSSE(ctx, w, client, h.quit, func(payload []byte) (int64, []byte) {
+		seq := seatlock.EventSeq(payload)
+		if seq != 0 {
+			if seq <= lastSeq {
+				return 0, nil
+			}
+			lastSeq = seq
+		}
+		return seq, publicSeatFrame(payload, viewer)
+	})
+}
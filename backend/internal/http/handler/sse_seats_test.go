@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bufio"
+	"cinema/internal/realtime"
+	"cinema/internal/seatlock"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+type sseFrame struct {
+	id   string
+	data string
+}
+
+// readFrame reads the next SSE event, skipping comments and retry hints.
+func readFrame(t *testing.T, r *bufio.Reader) sseFrame {
+	t.Helper()
+	var f sseFrame
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if f.data != "" {
+				return f
+			}
+		case strings.HasPrefix(line, "id: "):
+			f.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			f.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestSeatSSEResumeAndDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	hub := realtime.NewHub(rdb, 0, realtime.DisconnectSlow)
+	hubCtx, stopHub := context.WithCancel(ctx)
+	defer stopHub()
+	go hub.Run(hubCtx)
+
+	svc := seatlock.New(rdb, time.Minute)
+	if err := svc.BeginRebuild(ctx, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, sid := range []string{"A1", "A2"} { // seq 1, 2
+		if ok, _, err := svc.LockSeats(ctx, "st1", []string{sid}, "u1", "r-"+sid); err != nil || !ok {
+			t.Fatalf("lock %s: ok=%v err=%v", sid, ok, err)
+		}
+	}
+
+	h := NewSeatSSEHandler(hub, svc)
+	r := gin.New()
+	r.GET("/sse/showtimes/:showtimeId/seats", h.Seats)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/sse/showtimes/st1/seats", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body := bufio.NewReader(resp.Body)
+
+	// replay after Last-Event-ID, then live events
+	if f := readFrame(t, body); f.id != "2" || !strings.Contains(f.data, `"A2"`) {
+		t.Fatalf("replayed frame = %+v, want seq 2", f)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A3"}, "u1", "r-A3"); err != nil || !ok {
+		t.Fatalf("lock A3: ok=%v err=%v", ok, err)
+	}
+	if f := readFrame(t, body); f.id != "3" || !strings.Contains(f.data, `"A3"`) {
+		t.Fatalf("live frame = %+v, want seq 3", f)
+	}
+
+	// shutdown ends the stream
+	h.Drain()
+	if _, err := body.ReadString('\n'); err == nil {
+		t.Fatal("stream still open after Drain")
+	}
+}
+
+func TestSeatSSESnapshotWithoutResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	hub := realtime.NewHub(rdb, 0, realtime.DisconnectSlow)
+	h := NewSeatSSEHandler(hub, seatlock.New(rdb, time.Minute))
+	r := gin.New()
+	r.GET("/sse/showtimes/:showtimeId/seats", h.Seats)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/sse/showtimes/st1/seats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	defer h.Drain()
+
+	if f := readFrame(t, bufio.NewReader(resp.Body)); !strings.Contains(f.data, `"type":"snapshot"`) {
+		t.Fatalf("first frame = %+v, want a snapshot", f)
+	}
+}
@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"cinema/internal/auth"
+	"cinema/internal/http/middleware"
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"cinema/internal/transfer"
+	"context"
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TicketHandler serves signed tickets and seat transfers between users.
+type TicketHandler struct {
+	jwtSvc      *auth.JWTService
+	bookings    *repo.BookingRepo
+	users       *repo.UserRepo
+	transfers   *transfer.Service
+	frontendURL string
+}
+
+func NewTicketHandler(
+	jwtSvc *auth.JWTService,
+	bookings *repo.BookingRepo,
+	users *repo.UserRepo,
+	transfers *transfer.Service,
+	frontendURL string,
+) *TicketHandler {
+	return &TicketHandler{
+		jwtSvc:      jwtSvc,
+		bookings:    bookings,
+		users:       users,
+		transfers:   transfers,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+type ticketOut struct {
+	SeatID string `json:"seat_id"`
+	Ticket string `json:"ticket"`
+}
+
+// tickets signs one ticket per seat at the booking's current version.
+func (h *TicketHandler) tickets(b *model.Booking) ([]ticketOut, error) {
+	out := make([]ticketOut, 0, len(b.SeatIDs))
+	for _, sid := range b.SeatIDs {
+		t, err := h.jwtSvc.SignTicket(b.UserID.Hex(), b.ID.Hex(), b.ShowtimeID, sid, b.TicketVersion)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ticketOut{SeatID: sid, Ticket: t})
+	}
+	return out, nil
+}
+
+// GET /api/bookings/:bookingId/tickets
+func (h *TicketHandler) List(c *gin.Context) {
+	owner := c.GetString(middleware.CtxUserID)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("bookingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_booking_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	b, err := h.bookings.FindByID(ctx, bookingID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && b.UserID.Hex() != owner) {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "booking_not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	if b.Status != model.BookingBooked {
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "booking_not_booked"})
+		return
+	}
+
+	tickets, err := h.tickets(b)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "sign_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "booking_id": b.ID.Hex(), "tickets": tickets})
+}
+
+type verifyTicketReq struct {
+	Ticket string `json:"ticket"`
+}
+
+// POST /api/admin/tickets/verify
+// Door check: signature, then the booking must still hold the seat for the
+// same user at the same ticket version.
+func (h *TicketHandler) Verify(c *gin.Context) {
+	var req verifyTicketReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	claims, err := h.jwtSvc.VerifyTicket(strings.TrimSpace(req.Ticket))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "valid": false, "reason": "invalid_signature"})
+		return
+	}
+	bookingID, err := primitive.ObjectIDFromHex(claims.BookingID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "valid": false, "reason": "invalid_booking_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	b, err := h.bookings.FindByID(ctx, bookingID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+
+	reason := ""
+	switch {
+	case b == nil:
+		reason = "booking_not_found"
+	case b.Status != model.BookingBooked:
+		reason = "booking_" + strings.ToLower(string(b.Status))
+	case b.UserID.Hex() != claims.Subject || !slices.Contains(b.SeatIDs, claims.SeatID):
+		reason = "seat_not_in_booking"
+	case b.TicketVersion != claims.Version:
+		reason = "ticket_superseded"
+	}
+	if reason != "" {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "valid": false, "reason": reason})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"valid":       true,
+		"booking_id":  claims.BookingID,
+		"showtime_id": claims.ShowtimeID,
+		"seat_id":     claims.SeatID,
+		"user_id":     claims.Subject,
+	})
+}
+
+type offerTransferReq struct {
+	SeatIDs []string `json:"seat_ids"`
+	Email   string   `json:"email,omitempty"` // empty = claim link only
+}
+
+func (h *TicketHandler) claimURL(t *model.TicketTransfer) string {
+	q := url.Values{}
+	q.Set("transfer", t.ID.Hex())
+	q.Set("token", t.Token)
+	return h.frontendURL + "/?" + q.Encode()
+}
+
+func writeTransferError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var te *transfer.Error
+	if errors.As(err, &te) {
+		status := http.StatusConflict
+		switch te {
+		case transfer.ErrNotFound, transfer.ErrNotOwner:
+			status = http.StatusNotFound
+		case transfer.ErrSeatNotInBkg, transfer.ErrSelf:
+			status = http.StatusBadRequest
+		case transfer.ErrNotRecipient:
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"ok": false, "error": te.Code})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "transfer_failed"})
+	return false
+}
+
+// POST /api/bookings/:bookingId/transfers
+func (h *TicketHandler) Offer(c *gin.Context) {
+	owner := c.GetString(middleware.CtxUserID)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("bookingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_booking_id"})
+		return
+	}
+
+	var req offerTransferReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	seatIDs, ok := normalizeSeatIDs(req.SeatIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_ids"})
+		return
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_email"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	t, err := h.transfers.Offer(ctx, bookingID, owner, seatIDs, req.Email)
+	if !writeTransferError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ok":        true,
+		"transfer":  t,
+		"claim_url": h.claimURL(t),
+	})
+}
+
+// viewer loads the calling user (their email identifies addressed transfers).
+func (h *TicketHandler) viewer(ctx context.Context, c *gin.Context) (*model.User, bool) {
+	u, err := h.users.FindByID(ctx, c.GetString(middleware.CtxUserID))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "invalid_user"})
+		return nil, false
+	}
+	return u, true
+}
+
+// GET /api/me/transfers
+// Pending transfers addressed to the caller's email.
+func (h *TicketHandler) Incoming(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	u, ok := h.viewer(ctx, c)
+	if !ok {
+		return
+	}
+	items, err := h.transfers.Incoming(ctx, u.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "db_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "items": items})
+}
+
+func transferIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("transferId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_transfer_id"})
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// GET /api/transfers/:transferId?token=
+func (h *TicketHandler) Get(c *gin.Context) {
+	id, ok := transferIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	u, ok := h.viewer(ctx, c)
+	if !ok {
+		return
+	}
+	t, err := h.transfers.Get(ctx, id, u, c.Query("token"))
+	if !writeTransferError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "transfer": t})
+}
+
+type acceptTransferReq struct {
+	Token string `json:"token,omitempty"` // claim link token (not needed when addressed by email)
+}
+
+// POST /api/transfers/:transferId/accept
+func (h *TicketHandler) Accept(c *gin.Context) {
+	id, ok := transferIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req acceptTransferReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	u, ok := h.viewer(ctx, c)
+	if !ok {
+		return
+	}
+	b, err := h.transfers.Accept(ctx, id, u, req.Token)
+	if !writeTransferError(c, err) {
+		return
+	}
+
+	tickets, err := h.tickets(b)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "sign_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "booking": b, "tickets": tickets})
+}
+
+// DELETE /api/transfers/:transferId
+func (h *TicketHandler) Cancel(c *gin.Context) {
+	id, ok := transferIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.transfers.Cancel(ctx, id, c.GetString(middleware.CtxUserID)); !writeTransferError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
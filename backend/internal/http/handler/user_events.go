@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"cinema/internal/auth"
+	"cinema/internal/http/middleware"
+	"cinema/internal/notify"
+	"cinema/internal/realtime"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// UserEventsHandler streams a user's private notifications (hold expiring /
+// expired, payment result, booking cancelled) over WebSocket or SSE.
+type UserEventsHandler struct {
+	hub    *realtime.Hub
+	jwtSvc *auth.JWTService
+
+	wsConns
+
+	sseQuit     chan struct{}
+	sseQuitOnce sync.Once
+}
+
+func NewUserEventsHandler(hub *realtime.Hub, jwtSvc *auth.JWTService) *UserEventsHandler {
+	return &UserEventsHandler{hub: hub, jwtSvc: jwtSvc, sseQuit: make(chan struct{})}
+}
+
+// StopSSE ends every open SSE stream (srv.RegisterOnShutdown).
+func (h *UserEventsHandler) StopSSE() {
+	h.sseQuitOnce.Do(func() { close(h.sseQuit) })
+}
+
+// GET /ws/me/events?token=JWT
+func (h *UserEventsHandler) WS(c *gin.Context) {
+	token := strings.TrimSpace(c.Query("token"))
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "missing_token"})
+		return
+	}
+
+	claims, err := h.jwtSvc.Verify(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "invalid_token"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	if !h.track(conn, cancel) {
+		closeWS(conn, websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	defer h.untrack(conn)
+
+	client, err := h.hub.Join(ctx, notify.Channel(claims.UserID))
+	if err != nil {
+		closeWS(conn, websocket.CloseInternalServerErr, "subscribe failed")
+		return
+	}
+	defer h.hub.Leave(client)
+
+	_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	conn.SetPongHandler(func(string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+
+	// read loop (แค่ไว้ detect disconnect)
+	go func() {
+		for {
+			if _, _, e := conn.ReadMessage(); e != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	_ = conn.WriteJSON(gin.H{"type": "hello"})
+
+	ticker := time.NewTicker(20 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(2*time.Second))
+		case <-client.Done():
+			closeWS(conn, websocket.CloseTryAgainLater, "slow consumer")
+			return
+		case payload := <-client.Send():
+			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// GET /sse/me/events  (Authorization: Bearer <JWT>)
+func (h *UserEventsHandler) SSE(c *gin.Context) {
+	userID := c.GetString(middleware.CtxUserID)
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	client, err := h.hub.Join(ctx, notify.Channel(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "subscribe_failed"})
+		return
+	}
+	defer h.hub.Leave(client)
+
+	startSSE(c.Writer)
+	pumpSSE(ctx, c.Writer, client, h.sseQuit, func(payload []byte) (int64, []byte) {
+		return 0, payload
+	})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/waitroom"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitingRoomHandler struct {
+	room            *waitroom.Service
+	defaultCapacity int
+}
+
+func NewWaitingRoomHandler(room *waitroom.Service, defaultCapacity int) *WaitingRoomHandler {
+	return &WaitingRoomHandler{room: room, defaultCapacity: defaultCapacity}
+}
+
+// POST /api/showtimes/:showtimeId/waiting-room
+// Takes (or refreshes) a queue ticket; once admitted returns the admission
+// token to send as X-Admission-Token on POST /seats/lock.
+func (h *WaitingRoomHandler) Enter(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	uid := c.GetString(middleware.CtxUserID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	st, err := h.room.Enter(ctx, showtimeID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waiting_room_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"showtime_id": showtimeID,
+		"room":        st,
+	})
+}
+
+type openRoomReq struct {
+	Capacity int `json:"capacity"` // concurrently admitted users (0 = default)
+}
+
+// PUT /api/admin/showtimes/:showtimeId/waiting-room
+func (h *WaitingRoomHandler) Open(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+
+	var req openRoomReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	if req.Capacity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_capacity"})
+		return
+	}
+	capacity := req.Capacity
+	if capacity == 0 {
+		capacity = h.defaultCapacity
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.room.Open(ctx, showtimeID, capacity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waiting_room_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"showtime_id": showtimeID,
+		"capacity":    capacity,
+	})
+}
+
+// DELETE /api/admin/showtimes/:showtimeId/waiting-room
+func (h *WaitingRoomHandler) Close(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.room.Close(ctx, showtimeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waiting_room_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "showtime_id": showtimeID})
+}
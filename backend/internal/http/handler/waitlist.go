@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"cinema/internal/http/middleware"
+	"cinema/internal/loyalty"
+	"cinema/internal/showtime"
+	"cinema/internal/waitlist"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistHandler struct {
+	svc     *waitlist.Service
+	loyalty *loyalty.Service
+}
+
+func NewWaitlistHandler(svc *waitlist.Service, loyaltySvc *loyalty.Service) *WaitlistHandler {
+	return &WaitlistHandler{svc: svc, loyalty: loyaltySvc}
+}
+
+type joinWaitlistReq struct {
+	PartySize int    `json:"party_size"`
+	SeatType  string `json:"seat_type"` // seat map row type, "" / "any" = no preference
+}
+
+// POST /api/showtimes/:showtimeId/waitlist
+func (h *WaitlistHandler) Join(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	uid := c.GetString(middleware.CtxUserID)
+
+	var req joinWaitlistReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_body"})
+		return
+	}
+	if req.PartySize <= 0 || req.PartySize > waitlist.MaxPartySize {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_party_size"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	benefits, err := h.loyalty.Benefits(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waitlist_failed"})
+		return
+	}
+
+	pos, err := h.svc.Join(ctx, showtimeID, uid, req.PartySize, strings.ToLower(strings.TrimSpace(req.SeatType)), benefits.WaitlistPriority)
+	var se *showtime.Error
+	switch {
+	case errors.As(err, &se):
+		writeShowtimeError(c, err)
+		return
+	case errors.Is(err, waitlist.ErrInvalidSeatType):
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid_seat_type"})
+		return
+	case errors.Is(err, waitlist.ErrOfferPending):
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "offer_pending"})
+		return
+	case errors.Is(err, waitlist.ErrSeatsAvailable):
+		// not sold out for this party: lock seats directly instead
+		c.JSON(http.StatusConflict, gin.H{"ok": false, "error": "seats_available"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waitlist_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"showtime_id": showtimeID,
+		"position":    pos,
+	})
+}
+
+// GET /api/showtimes/:showtimeId/waitlist
+func (h *WaitlistHandler) Status(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	uid := c.GetString(middleware.CtxUserID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	st, err := h.svc.Status(ctx, showtimeID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waitlist_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":          true,
+		"showtime_id": showtimeID,
+		"position":    st.Position,
+		"entry":       st.Entry,
+		"offer":       st.Offer,
+	})
+}
+
+// DELETE /api/showtimes/:showtimeId/waitlist
+func (h *WaitlistHandler) Leave(c *gin.Context) {
+	showtimeID := c.Param("showtimeId")
+	uid := c.GetString(middleware.CtxUserID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.svc.Leave(ctx, showtimeID, uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "waitlist_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// ตอน dev ให้ผ่านก่อน (prod ควรเช็ค origin whitelist)
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsConns tracks live WebSocket connections so they can be closed with a
+// close frame on shutdown (http.Server.Shutdown ignores hijacked conns).
+type wsConns struct {
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]context.CancelFunc
+	draining bool
+	wg       sync.WaitGroup
+}
+
+func (t *wsConns) track(conn *websocket.Conn, cancel context.CancelFunc) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	if t.conns == nil {
+		t.conns = make(map[*websocket.Conn]context.CancelFunc)
+	}
+	t.conns[conn] = cancel
+	t.wg.Add(1)
+	return true
+}
+
+func (t *wsConns) untrack(conn *websocket.Conn) {
+	t.mu.Lock()
+	delete(t.conns, conn)
+	t.mu.Unlock()
+	t.wg.Done()
+}
+
+// Drain sends a "going away" close frame to every connection, stops their
+// loops and waits for them (or ctx) to finish. New upgrades are refused.
+func (t *wsConns) Drain(ctx context.Context) {
+	t.mu.Lock()
+	t.draining = true
+	for conn, cancel := range t.conns {
+		closeWS(conn, websocket.CloseGoingAway, "server shutting down")
+		cancel()
+	}
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// closeWS sends a close frame (best-effort).
+func closeWS(conn *websocket.Conn, code int, text string) {
+	closeMsg := websocket.FormatCloseMessage(code, text)
+	_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+}
@@ -2,37 +2,135 @@ package handler
 
 import (
 	"cinema/internal/auth"
+	"cinema/internal/realtime"
+	"cinema/internal/seatlock"
+	"cinema/internal/waitroom"
 	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/redis/go-redis/v9"
 )
 
 type SeatWSHandler struct {
-	rdb    *redis.Client
-	jwtSvc *auth.JWTService
+	hub      *realtime.Hub
+	presence *realtime.Presence
+	svc      *seatlock.Service
+	jwtSvc   *auth.JWTService
+	room     *waitroom.Service
+
+	wsConns
+}
+
+func NewSeatWSHandler(
+	hub *realtime.Hub,
+	presence *realtime.Presence,
+	svc *seatlock.Service,
+	jwtSvc *auth.JWTService,
+	room *waitroom.Service,
+) *SeatWSHandler {
+	return &SeatWSHandler{
+		hub:      hub,
+		presence: presence,
+		svc:      svc,
+		jwtSvc:   jwtSvc,
+		room:     room,
+	}
 }
 
-func NewSeatWSHandler(rdb *redis.Client, jwtSvc *auth.JWTService) *SeatWSHandler {
-	return &SeatWSHandler{rdb: rdb, jwtSvc: jwtSvc}
+// client -> server messages
+//
+//	{"type":"presence"}                           count me as a viewer
+//	{"type":"considering","seat_ids":["A1","A2"]} soft intent, [] clears
+type wsClientMsg struct {
+	Type    string   `json:"type"`
+	SeatIDs []string `json:"seat_ids"`
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	// ตอน dev ให้ผ่านก่อน (prod ควรเช็ค origin whitelist)
-	CheckOrigin: func(r *http.Request) bool { return true },
+// per connection: 5 msg/s, burst 10
+const (
+	wsMsgRate  = 5
+	wsMsgBurst = 10
+)
+
+// handleClientMsg applies one client message; the returned reply (if any)
+// goes through the writer loop.
+func (h *SeatWSHandler) handleClientMsg(ctx context.Context, showtimeID, connID string, lim *realtime.Limiter, raw []byte) []byte {
+	if !lim.Allow() {
+		b, _ := json.Marshal(gin.H{"type": "error", "error": "rate_limited"})
+		return b
+	}
+
+	var msg wsClientMsg
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		b, _ := json.Marshal(gin.H{"type": "error", "error": "invalid_message"})
+		return b
+	}
+
+	switch msg.Type {
+	case "presence":
+		_ = h.presence.Announce(ctx, showtimeID, connID)
+	case "considering":
+		var seatIDs []string // empty = clear
+		if len(msg.SeatIDs) > 0 {
+			ids, ok := normalizeSeatIDs(msg.SeatIDs)
+			if !ok || len(ids) > realtime.MaxIntentSeats {
+				b, _ := json.Marshal(gin.H{"type": "error", "error": "invalid_seat_ids"})
+				return b
+			}
+			seatIDs = ids
+		}
+		_ = h.presence.Intent(ctx, showtimeID, connID, seatIDs)
+	default:
+		b, _ := json.Marshal(gin.H{"type": "error", "error": "unknown_type"})
+		return b
+	}
+	return nil
 }
 
-func seatEventsChannel(showtimeID string) string {
-	return "seat-events:" + showtimeID
+// waiting room position refresh (also keeps the ticket alive)
+const queuePushEvery = 2 * time.Second
+
+type queueMsg struct {
+	Type string `json:"type"` // "queue"
+	*waitroom.Status
 }
 
-// GET /ws/showtimes/:showtimeId/seats?token=JWT
+// pushQueue refreshes the viewer's waiting room ticket and writes its status
+// when it changed since prev.
+func (h *SeatWSHandler) pushQueue(ctx context.Context, conn *websocket.Conn, showtimeID, userID string, prev *waitroom.Status) *waitroom.Status {
+	st, err := h.room.Enter(ctx, showtimeID, userID)
+	if err != nil {
+		return prev
+	}
+	if prev != nil && st.Active == prev.Active && st.Position == prev.Position && st.Admitted == prev.Admitted {
+		return prev
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_ = conn.WriteJSON(queueMsg{Type: "queue", Status: st})
+	return st
+}
+
+// resync writes the catch-up frames (replay or snapshot) and returns the seq
+// the client is at.
+func (h *SeatWSHandler) resync(ctx context.Context, conn *websocket.Conn, showtimeID, sinceStr, viewer string) (int64, error) {
+	frames, seq, err := seatCatchUp(ctx, h.svc, showtimeID, sinceStr, viewer)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range frames {
+		if err := conn.WriteMessage(websocket.TextMessage, f); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// GET /ws/showtimes/:showtimeId/seats?token=JWT[&since=<seq>]
 func (h *SeatWSHandler) Seats(c *gin.Context) {
 	showtimeID := c.Param("showtimeId")
 	token := strings.TrimSpace(c.Query("token"))
@@ -42,7 +140,7 @@ func (h *SeatWSHandler) Seats(c *gin.Context) {
 	}
 
 	// verify JWT
-	_, err := h.jwtSvc.Verify(token)
+	claims, err := h.jwtSvc.Verify(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "invalid_token"})
 		return
@@ -57,9 +155,31 @@ func (h *SeatWSHandler) Seats(c *gin.Context) {
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	// subscribe redis pubsub
-	pubsub := h.rdb.Subscribe(ctx, seatEventsChannel(showtimeID))
-	defer func() { _ = pubsub.Close() }()
+	if !h.track(conn, cancel) {
+		closeWS(conn, websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	defer h.untrack(conn)
+
+	// shared subscription (one per showtime per process)
+	client, err := h.hub.Join(ctx, seatEventsChannel(showtimeID))
+	if err != nil {
+		closeWS(conn, websocket.CloseInternalServerErr, "subscribe failed")
+		return
+	}
+	defer h.hub.Leave(client)
+
+	// viewer counts + soft intents from other viewers
+	presenceClient, err := h.hub.Join(ctx, realtime.PresenceChannel(showtimeID))
+	if err != nil {
+		closeWS(conn, websocket.CloseInternalServerErr, "subscribe failed")
+		return
+	}
+	defer h.hub.Leave(presenceClient)
+
+	// opaque id shown to other viewers instead of the user id
+	connID := uuid.NewString()
+	defer h.presence.Leave(context.Background(), showtimeID, connID)
 
 	// ping/pong กันหลุดง่าย
 	_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -68,20 +188,46 @@ func (h *SeatWSHandler) Seats(c *gin.Context) {
 		return nil
 	})
 
-	// read loop (แค่ไว้ detect disconnect)
+	// read loop: detect disconnect + presence/intent messages
+	conn.SetReadLimit(4096)
+	replies := make(chan []byte, 8)
 	go func() {
+		lim := realtime.NewLimiter(wsMsgRate, wsMsgBurst)
 		for {
-			if _, _, e := conn.ReadMessage(); e != nil {
+			_, raw, e := conn.ReadMessage()
+			if e != nil {
 				cancel()
 				return
 			}
+			if reply := h.handleClientMsg(ctx, showtimeID, connID, lim, raw); reply != nil {
+				select {
+				case replies <- reply:
+				default:
+				}
+			}
 		}
 	}()
 
-	// optional: ส่ง hello
-	_ = conn.WriteJSON(gin.H{"type": "hello", "showtime_id": showtimeID})
+	// optional: ส่ง hello (viewer_id = ของเราเอง ไว้กรอง intent ตัวเอง)
+	_ = conn.WriteJSON(gin.H{"type": "hello", "showtime_id": showtimeID, "viewer_id": connID})
+
+	// joined before resync, so live events racing with it wait in the queue
+	lastSeq, err := h.resync(ctx, conn, showtimeID, strings.TrimSpace(c.Query("since")), claims.UserID)
+	if err != nil {
+		closeWS(conn, websocket.CloseInternalServerErr, "resync failed")
+		return
+	}
 
-	ch := pubsub.Channel()
+	// waiting room: entering the seat page takes a ticket; position is
+	// pushed until the admission token arrives
+	var queueTick <-chan time.Time
+	var roomStatus *waitroom.Status
+	if active, _ := h.room.Active(ctx, showtimeID); active {
+		qt := time.NewTicker(queuePushEvery)
+		defer qt.Stop()
+		queueTick = qt.C
+		roomStatus = h.pushQueue(ctx, conn, showtimeID, claims.UserID, nil)
+	}
 
 	ticker := time.NewTicker(20 * time.Second)
 	defer ticker.Stop()
@@ -93,9 +239,43 @@ func (h *SeatWSHandler) Seats(c *gin.Context) {
 		case <-ticker.C:
 			// ws ping
 			_ = conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(2*time.Second))
-		case msg := <-ch:
-			// msg.Payload เป็น JSON string จาก seatlock.publish()
-			_ = conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload))
+		case <-queueTick:
+			roomStatus = h.pushQueue(ctx, conn, showtimeID, claims.UserID, roomStatus)
+		case <-client.Done():
+			// too slow to keep up (or hub closed): client should reconnect + resync
+			closeWS(conn, websocket.CloseTryAgainLater, "slow consumer")
+			return
+		case <-presenceClient.Done():
+			closeWS(conn, websocket.CloseTryAgainLater, "slow consumer")
+			return
+		case reply := <-replies:
+			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, reply); err != nil {
+				return
+			}
+		case payload := <-presenceClient.Send():
+			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case payload := <-client.Send():
+			// already covered by snapshot/replay
+			if seq := seatlock.EventSeq(payload); seq != 0 {
+				if seq <= lastSeq {
+					continue
+				}
+				lastSeq = seq
+			}
+
+			// payload เป็น JSON string จาก seatlock.publish() -> ตัด owner ออก
+			payload = publicSeatFrame(payload, claims.UserID)
+			if payload == nil {
+				continue
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
 		}
 	}
 }
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"cinema/internal/auth"
+	"cinema/internal/model"
+	"cinema/internal/realtime"
+	"cinema/internal/seatlock"
+	"cinema/internal/waitroom"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSeatWSDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	jwtSvc := auth.NewJWTService("test-secret-test-secret-test-secret")
+	token, err := jwtSvc.Sign("u1", model.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := realtime.NewHub(rdb, 0, realtime.DisconnectSlow)
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	defer stopHub()
+	go hub.Run(hubCtx)
+
+	h := NewSeatWSHandler(hub, realtime.NewPresence(rdb, hub), seatlock.New(rdb, time.Minute), jwtSvc, waitroom.New(rdb, jwtSvc, time.Minute))
+	r := gin.New()
+	r.GET("/ws/showtimes/:showtimeId/seats", h.Seats)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/showtimes/st1/seats?token=" + token
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		return conn
+	}
+	goingAway := func(conn *websocket.Conn) {
+		t.Helper()
+		for {
+			_, _, err := conn.ReadMessage()
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) {
+				if ce.Code != websocket.CloseGoingAway {
+					t.Fatalf("close code = %d, want going away", ce.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read: %v, want a close frame", err)
+			}
+		}
+	}
+
+	conn := dial()
+	if _, msg, err := conn.ReadMessage(); err != nil || !strings.Contains(string(msg), "hello") {
+		t.Fatalf("first message = %s, %v", msg, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h.Drain(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("Drain waited for the deadline")
+	}
+	goingAway(conn)
+
+	// no new connections once draining
+	goingAway(dial())
+}
@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Elector is a Redis lease based leader election.
+// One instance holds key "leader:<name>" (value = instance id) with a TTL and
+// renews it; if renewal fails or ctx is cancelled it steps down.
+type Elector struct {
+	rdb        *redis.Client
+	key        string
+	id         string
+	ttl        time.Duration
+	renewEvery time.Duration
+
+	mu       sync.RWMutex
+	isLeader bool
+}
+
+func New(rdb *redis.Client, name, instanceID string, ttl time.Duration) *Elector {
+	return &Elector{
+		rdb:        rdb,
+		key:        leaderKey(name),
+		id:         instanceID,
+		ttl:        ttl,
+		renewEvery: ttl / 3,
+	}
+}
+
+func leaderKey(name string) string {
+	return fmt.Sprintf("leader:%s", name)
+}
+
+// renew only if we still own the lease
+var luaRenew = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// release only if we still own the lease
+var luaRelease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ID returns this instance id.
+func (e *Elector) ID() string { return e.id }
+
+// IsLeader reports whether this instance currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isLeader
+}
+
+// Leader returns the instance id holding the lease ("" if none).
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	v, err := e.rdb.Get(ctx, e.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return v, err
+}
+
+func (e *Elector) setLeader(v bool) {
+	e.mu.Lock()
+	e.isLeader = v
+	e.mu.Unlock()
+}
+
+// Run campaigns until ctx is cancelled. Every time this instance becomes
+// leader, work is called with a context that is cancelled on step-down;
+// Run waits for work to return before campaigning again.
+func (e *Elector) Run(ctx context.Context, work func(ctx context.Context)) {
+	ticker := time.NewTicker(e.renewEvery)
+	defer ticker.Stop()
+
+	for {
+		ok, err := e.rdb.SetNX(ctx, e.key, e.id, e.ttl).Result()
+		if err == nil && ok {
+			e.lead(ctx, work)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, work func(ctx context.Context)) {
+	log.Printf("leader: %s acquired %s", e.id, e.key)
+	e.setLeader(true)
+
+	workCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		work(workCtx)
+	}()
+
+	ticker := time.NewTicker(e.renewEvery)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-ticker.C:
+			n, err := luaRenew.Run(ctx, e.rdb, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+			if err != nil || n == 0 {
+				log.Printf("leader: %s lost %s", e.id, e.key)
+				break loop
+			}
+		}
+	}
+
+	// step down: stop work first, then give the lease back
+	e.setLeader(false)
+	cancel()
+	<-done
+
+	relCtx, relCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer relCancel()
+	_, _ = luaRelease.Run(relCtx, e.rdb, []string{e.key}, e.id).Result()
+}
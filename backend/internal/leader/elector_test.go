@@ -0,0 +1,88 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, mr
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// blocks until its context is cancelled, like the workers
+func work(ctx context.Context) { <-ctx.Done() }
+
+func TestOneLeaderAndHandover(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	a := New(rdb, "test", "a", 300*time.Millisecond)
+	b := New(rdb, "test", "b", 300*time.Millisecond)
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { defer close(doneA); a.Run(ctxA, work) }()
+	waitFor(t, "a to lead", a.IsLeader)
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	go b.Run(ctxB, work)
+
+	time.Sleep(400 * time.Millisecond) // past the lease TTL: a keeps renewing
+	if b.IsLeader() {
+		t.Fatal("b leads while a holds the lease")
+	}
+	if id, err := b.Leader(context.Background()); err != nil || id != "a" {
+		t.Fatalf("Leader = %q, %v; want a", id, err)
+	}
+
+	// a steps down and gives the lease back
+	stopA()
+	<-doneA
+	waitFor(t, "b to lead", b.IsLeader)
+}
+
+func TestStepDownOnLostLease(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	a := New(rdb, "test", "a", 300*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan struct{})
+	go a.Run(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	waitFor(t, "a to lead", a.IsLeader)
+
+	// someone else owns the key now: a must stop its work at the next renew
+	mr.Set(leaderKey("test"), "intruder")
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("work not stopped after losing the lease")
+	}
+	if a.IsLeader() {
+		t.Fatal("a still reports leader")
+	}
+	if v, _ := mr.Get(leaderKey("test")); v != "intruder" {
+		t.Fatalf("lease = %q, a released someone else's lease", v)
+	}
+}
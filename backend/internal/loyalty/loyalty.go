@@ -0,0 +1,215 @@
+package loyalty
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Loyalty points and membership tiers. Points are earned from
+// booking.success events (see Run), spent against a booking's total at
+// confirm, and reversed when a booking is cancelled. Every movement is a
+// loyalty_ledger entry; loyalty_accounts holds the running balance.
+
+const (
+	SpendPerPoint = 10 // currency units paid per point earned
+	PointsPerUnit = 10 // points per currency unit off when redeeming
+)
+
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// Benefits are what a tier gives its members.
+type Benefits struct {
+	LockTTLBonusSecs int   `json:"lock_ttl_bonus_seconds,omitempty"`
+	WaitlistPriority bool  `json:"waitlist_priority,omitempty"`
+	DiscountPercent  int64 `json:"discount_percent,omitempty"` // off the seats subtotal
+}
+
+type tierRule struct {
+	Tier     model.LoyaltyTier
+	MinPts   int64 // lifetime points
+	Benefits Benefits
+}
+
+// highest first
+var tiers = []tierRule{
+	{model.TierGold, 5000, Benefits{LockTTLBonusSecs: 300, WaitlistPriority: true, DiscountPercent: 10}},
+	{model.TierSilver, 1000, Benefits{LockTTLBonusSecs: 120, WaitlistPriority: true, DiscountPercent: 5}},
+	{model.TierMember, 0, Benefits{}},
+}
+
+// TierFor returns the tier reached with lifetime points.
+func TierFor(lifetime int64) model.LoyaltyTier {
+	for _, t := range tiers {
+		if lifetime >= t.MinPts {
+			return t.Tier
+		}
+	}
+	return model.TierMember
+}
+
+// BenefitsOf returns the benefits of tier.
+func BenefitsOf(tier model.LoyaltyTier) Benefits {
+	for _, t := range tiers {
+		if t.Tier == tier {
+			return t.Benefits
+		}
+	}
+	return Benefits{}
+}
+
+// NextTier returns the next tier above tier and its lifetime threshold
+// ("" at the top).
+func NextTier(tier model.LoyaltyTier) (model.LoyaltyTier, int64) {
+	for i, t := range tiers {
+		if t.Tier == tier && i > 0 {
+			return tiers[i-1].Tier, tiers[i-1].MinPts
+		}
+	}
+	return "", 0
+}
+
+type Service struct {
+	repo *repo.LoyaltyRepo
+}
+
+func New(r *repo.LoyaltyRepo) *Service {
+	return &Service{repo: r}
+}
+
+// Account returns userID's account (an empty MEMBER account if none yet).
+func (s *Service) Account(ctx context.Context, userID string) (*model.LoyaltyAccount, error) {
+	acc, err := s.repo.FindAccount(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &model.LoyaltyAccount{UserID: userID, Tier: model.TierMember}, nil
+	}
+	return acc, err
+}
+
+// Benefits returns the benefits of userID's current tier.
+func (s *Service) Benefits(ctx context.Context, userID string) (Benefits, error) {
+	acc, err := s.Account(ctx, userID)
+	if err != nil {
+		return Benefits{}, err
+	}
+	return BenefitsOf(acc.Tier), nil
+}
+
+func (s *Service) History(ctx context.Context, userID string, limit int64) ([]model.LoyaltyEntry, error) {
+	return s.repo.FindEntries(ctx, userID, limit)
+}
+
+// PointsFor is the number of points (multiple of PointsPerUnit) needed to
+// take up to want points' worth off total, and the resulting discount.
+func PointsFor(want, total int64) (points, discount int64) {
+	discount = min(want/PointsPerUnit, total)
+	if discount < 0 {
+		discount = 0
+	}
+	return discount * PointsPerUnit, discount
+}
+
+// Redeem spends points on bookingID (atomic balance check).
+func (s *Service) Redeem(ctx context.Context, userID string, bookingID primitive.ObjectID, points int64) error {
+	ok, err := s.repo.Spend(ctx, userID, points)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientPoints
+	}
+
+	if err := s.repo.InsertEntry(ctx, &model.LoyaltyEntry{
+		UserID:    userID,
+		BookingID: bookingID,
+		Kind:      model.LoyaltyRedeem,
+		Points:    -points,
+	}); err != nil {
+		_, _ = s.repo.Apply(ctx, userID, points, 0)
+		return err
+	}
+	return nil
+}
+
+// RefundRedemption gives back the points spent on bookingID, if any.
+// Safe to call more than once.
+func (s *Service) RefundRedemption(ctx context.Context, bookingID primitive.ObjectID) error {
+	return s.reverse(ctx, bookingID, model.LoyaltyRedeem, model.LoyaltyRedeemRefund, false)
+}
+
+// Earn credits the points of a paid booking. Idempotent per booking.
+func (s *Service) Earn(ctx context.Context, userID string, bookingID primitive.ObjectID, amount int64) error {
+	points := amount / SpendPerPoint
+	if points <= 0 {
+		return nil
+	}
+
+	err := s.repo.InsertEntry(ctx, &model.LoyaltyEntry{
+		UserID:    userID,
+		BookingID: bookingID,
+		Kind:      model.LoyaltyEarn,
+		Points:    points,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.apply(ctx, userID, points, points)
+}
+
+// ReverseBooking undoes everything a cancelled booking did: points earned
+// come off (balance may go negative if already spent), points spent come back.
+func (s *Service) ReverseBooking(ctx context.Context, bookingID primitive.ObjectID) error {
+	if err := s.reverse(ctx, bookingID, model.LoyaltyEarn, model.LoyaltyEarnReversal, true); err != nil {
+		return err
+	}
+	return s.RefundRedemption(ctx, bookingID)
+}
+
+// reverse appends the opposite of bookingID's `of` entry as `as`, once.
+func (s *Service) reverse(ctx context.Context, bookingID primitive.ObjectID, of, as model.LoyaltyEntryKind, lifetime bool) error {
+	orig, err := s.repo.FindEntry(ctx, bookingID, of)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.InsertEntry(ctx, &model.LoyaltyEntry{
+		UserID:    orig.UserID,
+		BookingID: bookingID,
+		Kind:      as,
+		Points:    -orig.Points,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var lt int64
+	if lifetime {
+		lt = -orig.Points
+	}
+	return s.apply(ctx, orig.UserID, -orig.Points, lt)
+}
+
+// apply moves the balance and re-evaluates the tier.
+func (s *Service) apply(ctx context.Context, userID string, points, lifetime int64) error {
+	acc, err := s.repo.Apply(ctx, userID, points, lifetime)
+	if err != nil {
+		return err
+	}
+	if tier := TierFor(acc.Lifetime); tier != acc.Tier {
+		return s.repo.SetTier(ctx, userID, tier)
+	}
+	return nil
+}
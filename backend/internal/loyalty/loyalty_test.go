@@ -0,0 +1,92 @@
+package loyalty
+
+import (
+	"cinema/internal/model"
+	"testing"
+)
+
+func TestPointsFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		want         int64
+		total        int64
+		wantPoints   int64
+		wantDiscount int64
+	}{
+		{name: "exact multiple", want: 500, total: 1000, wantPoints: 500, wantDiscount: 50},
+		{name: "rounds down to a whole unit", want: 509, total: 1000, wantPoints: 500, wantDiscount: 50},
+		{name: "less than one unit", want: 9, total: 1000, wantPoints: 0, wantDiscount: 0},
+		{name: "capped at the total", want: 5000, total: 120, wantPoints: 1200, wantDiscount: 120},
+		{name: "nothing to pay", want: 500, total: 0, wantPoints: 0, wantDiscount: 0},
+		{name: "negative request", want: -100, total: 1000, wantPoints: 0, wantDiscount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, discount := PointsFor(tt.want, tt.total)
+			if points != tt.wantPoints || discount != tt.wantDiscount {
+				t.Fatalf("PointsFor(%d, %d) = %d, %d; want %d, %d",
+					tt.want, tt.total, points, discount, tt.wantPoints, tt.wantDiscount)
+			}
+		})
+	}
+}
+
+func TestTierFor(t *testing.T) {
+	tests := []struct {
+		lifetime int64
+		want     model.LoyaltyTier
+	}{
+		{lifetime: -10, want: model.TierMember},
+		{lifetime: 0, want: model.TierMember},
+		{lifetime: 999, want: model.TierMember},
+		{lifetime: 1000, want: model.TierSilver},
+		{lifetime: 4999, want: model.TierSilver},
+		{lifetime: 5000, want: model.TierGold},
+		{lifetime: 1_000_000, want: model.TierGold},
+	}
+
+	for _, tt := range tests {
+		if got := TierFor(tt.lifetime); got != tt.want {
+			t.Errorf("TierFor(%d) = %s, want %s", tt.lifetime, got, tt.want)
+		}
+	}
+}
+
+func TestNextTier(t *testing.T) {
+	tests := []struct {
+		tier     model.LoyaltyTier
+		wantTier model.LoyaltyTier
+		wantMin  int64
+	}{
+		{tier: model.TierMember, wantTier: model.TierSilver, wantMin: 1000},
+		{tier: model.TierSilver, wantTier: model.TierGold, wantMin: 5000},
+		{tier: model.TierGold, wantTier: "", wantMin: 0},
+		{tier: "UNKNOWN", wantTier: "", wantMin: 0},
+	}
+
+	for _, tt := range tests {
+		next, threshold := NextTier(tt.tier)
+		if next != tt.wantTier || threshold != tt.wantMin {
+			t.Errorf("NextTier(%s) = %s, %d; want %s, %d", tt.tier, next, threshold, tt.wantTier, tt.wantMin)
+		}
+	}
+}
+
+func TestBenefitsOf(t *testing.T) {
+	tests := []struct {
+		tier model.LoyaltyTier
+		want Benefits
+	}{
+		{tier: model.TierMember, want: Benefits{}},
+		{tier: model.TierSilver, want: Benefits{LockTTLBonusSecs: 120, WaitlistPriority: true, DiscountPercent: 5}},
+		{tier: model.TierGold, want: Benefits{LockTTLBonusSecs: 300, WaitlistPriority: true, DiscountPercent: 10}},
+		{tier: "UNKNOWN", want: Benefits{}},
+	}
+
+	for _, tt := range tests {
+		if got := BenefitsOf(tt.tier); got != tt.want {
+			t.Errorf("BenefitsOf(%s) = %+v, want %+v", tt.tier, got, tt.want)
+		}
+	}
+}
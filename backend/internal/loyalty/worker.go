@@ -0,0 +1,64 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type bookingEvent struct {
+	Type      string `json:"type"`
+	BookingID string `json:"booking_id"`
+	UserID    string `json:"user_id"`
+	Amount    int64  `json:"amount"`
+}
+
+// Run consumes booking-events until ctx is cancelled: booking.success earns
+// points, booking.cancelled reverses them.
+func (s *Service) Run(ctx context.Context, rdb *redis.Client) {
+	sub := rdb.Subscribe(ctx, "booking-events")
+	defer func() { _ = sub.Close() }()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			s.handle(ctx, msg)
+		}
+	}
+}
+
+func (s *Service) handle(ctx context.Context, msg *redis.Message) {
+	var ev bookingEvent
+	if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+		return
+	}
+	bookingID, err := primitive.ObjectIDFromHex(ev.BookingID)
+	if err != nil || ev.UserID == "" {
+		return
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	switch ev.Type {
+	case "booking.success":
+		err = s.Earn(hctx, ev.UserID, bookingID, ev.Amount)
+	case "booking.cancelled":
+		err = s.ReverseBooking(hctx, bookingID)
+	default:
+		return
+	}
+	if err != nil {
+		log.Println("loyalty:", ev.Type, ev.BookingID, err)
+	}
+}
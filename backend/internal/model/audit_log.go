@@ -8,7 +8,7 @@ import (
 
 type AuditLog struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Type       string             `bson:"type" json:"type"` // seat.locked, seat.released, seat.booked, seat.timeout, booking.success
+	Type       string             `bson:"type" json:"type"` // seat.locked, seat.released, seat.booked, seat.timeout, seat.blocked, seat.unblocked, booking.success, booking.cancelled, booking.exchanged, ticket.transfer_out, ticket.transfer_in, admin.seats_force_released, admin.seats_repaired, seats.reconciled, seats.rebuilt
 	ShowtimeID string             `bson:"showtime_id,omitempty" json:"showtime_id,omitempty"`
 	BookingID  string             `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
 	UserID     string             `bson:"user_id,omitempty" json:"user_id,omitempty"`
@@ -9,23 +9,81 @@ import (
 type BookingStatus string
 
 const (
-	BookingPending BookingStatus = "PENDING"
-	BookingBooked  BookingStatus = "BOOKED"
-	BookingFailed  BookingStatus = "FAILED"
+	BookingPending   BookingStatus = "PENDING"
+	BookingBooked    BookingStatus = "BOOKED"
+	BookingFailed    BookingStatus = "FAILED"
+	BookingCancelled BookingStatus = "CANCELLED"
+	// every seat was transferred to other users
+	BookingTransferred BookingStatus = "TRANSFERRED"
+)
+
+// why a booking was cancelled
+const (
+	CancelByUser     = "user"
+	CancelByShowtime = "showtime_cancelled"
 )
 
 // Booking is created when the user confirms (mock) payment.
 type Booking struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	ShowtimeID string             `bson:"showtime_id" json:"showtime_id"`
-	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
-	SeatIDs    []string           `bson:"seat_ids" json:"seat_ids"`
-	Amount     int64              `bson:"amount" json:"amount"`
-	Currency   string             `bson:"currency" json:"currency"`
-	Status     BookingStatus      `bson:"status" json:"status"`
-	RequestID  string             `bson:"request_id" json:"request_id"`
-	PaymentRef string             `bson:"payment_ref,omitempty" json:"payment_ref,omitempty"`
-	BookedAt   *time.Time         `bson:"booked_at,omitempty" json:"booked_at,omitempty"`
-	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ShowtimeID  string              `bson:"showtime_id" json:"showtime_id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	SeatIDs     []string            `bson:"seat_ids" json:"seat_ids"`
+	Amount      int64               `bson:"amount" json:"amount"` // charged by the payment provider (= Pricing.Total when set)
+	Currency    string              `bson:"currency" json:"currency"`
+	Status      BookingStatus       `bson:"status" json:"status"`
+	RequestID   string              `bson:"request_id" json:"request_id"`
+	PaymentRef  string              `bson:"payment_ref,omitempty" json:"payment_ref,omitempty"`
+	Pricing     *PriceBreakdown     `bson:"pricing,omitempty" json:"pricing,omitempty"`
+	Concessions *BookingConcessions `bson:"concessions,omitempty" json:"concessions,omitempty"`
+	// bumped when seats leave the booking: older tickets stop being valid
+	TicketVersion   int                 `bson:"ticket_version" json:"ticket_version"`
+	TransferredFrom *primitive.ObjectID `bson:"transferred_from,omitempty" json:"transferred_from,omitempty"`
+	// transfers whose seats left this booking (makes RemoveSeats idempotent)
+	TransferIDs  []primitive.ObjectID `bson:"transfer_ids,omitempty" json:"-"`
+	Exchanges    []BookingExchange    `bson:"exchanges,omitempty" json:"exchanges,omitempty"`
+	BookedAt     *time.Time           `bson:"booked_at,omitempty" json:"booked_at,omitempty"`
+	CancelledAt  *time.Time           `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CancelReason string               `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
+	// refund of Amount after a showtime cancellation (mock payment reference)
+	RefundRef string    `bson:"refund_ref,omitempty" json:"refund_ref,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// BookingExchange records seats swapped on a booking and the price
+// difference (positive = charged, negative = refunded).
+type BookingExchange struct {
+	FromSeatIDs []string  `bson:"from_seat_ids" json:"from_seat_ids"`
+	ToSeatIDs   []string  `bson:"to_seat_ids" json:"to_seat_ids"`
+	Difference  int64     `bson:"difference" json:"difference"`
+	PaymentRef  string    `bson:"payment_ref,omitempty" json:"payment_ref,omitempty"`
+	At          time.Time `bson:"at" json:"at"`
+}
+
+// PriceLine is one adjustment to a booking's subtotal (discounts are negative).
+type PriceLine struct {
+	Kind   string `bson:"kind" json:"kind"` // "concession", "promo", ...
+	Code   string `bson:"code,omitempty" json:"code,omitempty"`
+	Label  string `bson:"label,omitempty" json:"label,omitempty"`
+	Amount int64  `bson:"amount" json:"amount"`
+}
+
+// PriceBreakdown explains Booking.Amount (same currency and units).
+type PriceBreakdown struct {
+	Subtotal int64       `bson:"subtotal" json:"subtotal"` // seats
+	Lines    []PriceLine `bson:"lines,omitempty" json:"lines,omitempty"`
+	Total    int64       `bson:"total" json:"total"`
+}
+
+// Add appends a line and recomputes Total (never below zero).
+func (p *PriceBreakdown) Add(l PriceLine) {
+	p.Lines = append(p.Lines, l)
+	p.Total = p.Subtotal
+	for _, x := range p.Lines {
+		p.Total += x.Amount
+	}
+	if p.Total < 0 {
+		p.Total = 0
+	}
 }
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ConcessionKind string
+
+const (
+	ConcessionItemKind  ConcessionKind = "ITEM"
+	ConcessionComboKind ConcessionKind = "COMBO" // stocked as its own unit
+)
+
+// ConcessionItem is one entry of a cinema's food & drinks catalog.
+type ConcessionItem struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CinemaID    string             `bson:"cinema_id" json:"cinema_id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Kind        ConcessionKind     `bson:"kind" json:"kind"`
+	Price       int64              `bson:"price" json:"price"` // booking currency units
+	Stock       int64              `bson:"stock" json:"stock"` // available (not reserved)
+	Active      bool               `bson:"active" json:"active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ConcessionLine is a quantity of one item, priced when it was reserved.
+type ConcessionLine struct {
+	ItemID    primitive.ObjectID `bson:"item_id" json:"item_id"`
+	Name      string             `bson:"name" json:"name"`
+	Qty       int64              `bson:"qty" json:"qty"`
+	UnitPrice int64              `bson:"unit_price" json:"unit_price"`
+}
+
+type ConcessionResStatus string
+
+const (
+	ConcessionHeld     ConcessionResStatus = "HELD"      // stock taken, follows the seat hold
+	ConcessionConsumed ConcessionResStatus = "CONSUMED"  // paid with a booking
+	ConcessionPickedUp ConcessionResStatus = "PICKED_UP" // handed over at the counter
+	ConcessionReleased ConcessionResStatus = "RELEASED"  // stock given back
+)
+
+// ConcessionReservation holds stock for one seat hold (owner + request id)
+// until the booking is confirmed or the hold ends.
+type ConcessionReservation struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CinemaID   string              `bson:"cinema_id" json:"cinema_id"`
+	ShowtimeID string              `bson:"showtime_id" json:"showtime_id"`
+	UserID     string              `bson:"user_id" json:"user_id"`
+	RequestID  string              `bson:"request_id" json:"request_id"`
+	SeatIDs    []string            `bson:"seat_ids" json:"seat_ids"`
+	Items      []ConcessionLine    `bson:"items" json:"items"`
+	Status     ConcessionResStatus `bson:"status" json:"status"`
+	BookingID  *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	PickupCode string              `bson:"pickup_code,omitempty" json:"pickup_code,omitempty"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"` // end of the seat hold (last seen)
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Total is the price of all lines.
+func (r *ConcessionReservation) Total() int64 {
+	var t int64
+	for _, l := range r.Items {
+		t += l.Qty * l.UnitPrice
+	}
+	return t
+}
+
+// BookingConcessions is what a booking shows about its add-ons.
+type BookingConcessions struct {
+	CinemaID   string           `bson:"cinema_id" json:"cinema_id"`
+	Items      []ConcessionLine `bson:"items" json:"items"`
+	PickupCode string           `bson:"pickup_code" json:"pickup_code"`
+}
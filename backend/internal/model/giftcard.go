@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GiftCardSource string
+
+const (
+	GiftCardAdmin    GiftCardSource = "ADMIN"    // issued by an admin
+	GiftCardPurchase GiftCardSource = "PURCHASE" // bought by a user
+)
+
+// GiftCard is a stored-value card. It has no balance field: the balance is
+// the BalanceAfter of its latest gift_card_ledger entry.
+type GiftCard struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code       string             `bson:"code" json:"code"` // unique redemption code
+	Currency   string             `bson:"currency" json:"currency"`
+	Initial    int64              `bson:"initial" json:"initial"` // same units as Booking.Amount
+	Source     GiftCardSource     `bson:"source" json:"source"`
+	IssuedBy   string             `bson:"issued_by" json:"issued_by"` // admin or purchasing user id
+	PaymentRef string             `bson:"payment_ref,omitempty" json:"payment_ref,omitempty"`
+	Active     bool               `bson:"active" json:"active"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type GiftCardEntryKind string
+
+const (
+	GiftCardIssue  GiftCardEntryKind = "ISSUE"
+	GiftCardRedeem GiftCardEntryKind = "REDEEM" // paid part of a booking
+	GiftCardRefund GiftCardEntryKind = "REFUND" // booking failed or cancelled
+)
+
+// GiftCardEntry is one append-only movement of a card. Seq is 1, 2, 3... per
+// card and (card_id, seq) is unique, so concurrent writers cannot both
+// append on top of the same balance.
+type GiftCardEntry struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CardID       primitive.ObjectID  `bson:"card_id" json:"card_id"`
+	Seq          int64               `bson:"seq" json:"seq"`
+	Kind         GiftCardEntryKind   `bson:"kind" json:"kind"`
+	Amount       int64               `bson:"amount" json:"amount"` // signed
+	BalanceAfter int64               `bson:"balance_after" json:"balance_after"`
+	BookingID    *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	At           time.Time           `bson:"at" json:"at"`
+}
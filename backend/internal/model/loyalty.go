@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LoyaltyTier string
+
+const (
+	TierMember LoyaltyTier = "MEMBER"
+	TierSilver LoyaltyTier = "SILVER"
+	TierGold   LoyaltyTier = "GOLD"
+)
+
+// LoyaltyAccount is a user's points balance; _id is the user id (hex).
+// Balances are derived from loyalty_ledger and kept here for atomic checks.
+type LoyaltyAccount struct {
+	UserID    string      `bson:"_id" json:"user_id"`
+	Points    int64       `bson:"points" json:"points"`     // spendable
+	Lifetime  int64       `bson:"lifetime" json:"lifetime"` // earned, net of reversals (tier basis)
+	Tier      LoyaltyTier `bson:"tier" json:"tier"`
+	UpdatedAt time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+type LoyaltyEntryKind string
+
+const (
+	LoyaltyEarn         LoyaltyEntryKind = "EARN"
+	LoyaltyRedeem       LoyaltyEntryKind = "REDEEM"
+	LoyaltyEarnReversal LoyaltyEntryKind = "EARN_REVERSAL"
+	LoyaltyRedeemRefund LoyaltyEntryKind = "REDEEM_REFUND"
+)
+
+// LoyaltyEntry is one append-only points movement. (booking_id, kind) is
+// unique, which makes earning and reversals idempotent.
+type LoyaltyEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	BookingID primitive.ObjectID `bson:"booking_id" json:"booking_id"`
+	Kind      LoyaltyEntryKind   `bson:"kind" json:"kind"`
+	Points    int64              `bson:"points" json:"points"` // signed
+	At        time.Time          `bson:"at" json:"at"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "PERCENT" // Value = percent off (1-100)
+	PromoFixed   PromoKind = "FIXED"   // Value = amount off, booking currency units
+)
+
+// Promo is a discount code redeemed at booking confirm.
+// Empty restriction lists mean "any".
+type Promo struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code        string             `bson:"code" json:"code"` // uppercase, unique
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Kind        PromoKind          `bson:"kind" json:"kind"`
+	Value       int64              `bson:"value" json:"value"`
+
+	// restrictions
+	MinSeats    int        `bson:"min_seats,omitempty" json:"min_seats,omitempty"`
+	MovieIDs    []string   `bson:"movie_ids,omitempty" json:"movie_ids,omitempty"`
+	ShowtimeIDs []string   `bson:"showtime_ids,omitempty" json:"showtime_ids,omitempty"`
+	Weekdays    []int      `bson:"weekdays,omitempty" json:"weekdays,omitempty"` // 0=Sunday
+	ValidFrom   *time.Time `bson:"valid_from,omitempty" json:"valid_from,omitempty"`
+	ValidTo     *time.Time `bson:"valid_to,omitempty" json:"valid_to,omitempty"`
+
+	// limits (0 = unlimited); Redeemed counts active redemptions
+	MaxRedemptions int64 `bson:"max_redemptions" json:"max_redemptions"`
+	PerUserLimit   int64 `bson:"per_user_limit" json:"per_user_limit"`
+	Redeemed       int64 `bson:"redeemed" json:"redeemed"`
+
+	Active    bool      `bson:"active" json:"active"`
+	CreatedBy string    `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type RedemptionStatus string
+
+const (
+	RedemptionRedeemed RedemptionStatus = "REDEEMED"
+	RedemptionReversed RedemptionStatus = "REVERSED"
+)
+
+// PromoRedemption records one use of a promo on a booking.
+type PromoRedemption struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromoID    primitive.ObjectID `bson:"promo_id" json:"promo_id"`
+	Code       string             `bson:"code" json:"code"`
+	UserID     string             `bson:"user_id" json:"user_id"`
+	BookingID  primitive.ObjectID `bson:"booking_id" json:"booking_id"`
+	Discount   int64              `bson:"discount" json:"discount"`
+	Status     RedemptionStatus   `bson:"status" json:"status"`
+	RedeemedAt time.Time          `bson:"redeemed_at" json:"redeemed_at"`
+	ReversedAt *time.Time         `bson:"reversed_at,omitempty" json:"reversed_at,omitempty"`
+
+	// reversal in progress: counters already given back
+	ReversingAt    *time.Time `bson:"reversing_at,omitempty" json:"-"`
+	GlobalReleased bool       `bson:"global_released,omitempty" json:"-"`
+	UserReleased   bool       `bson:"user_released,omitempty" json:"-"`
+}
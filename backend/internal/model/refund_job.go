@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefundJobStatus string
+
+const (
+	RefundJobRunning RefundJobStatus = "RUNNING"
+	RefundJobDone    RefundJobStatus = "DONE"
+	RefundJobStuck   RefundJobStatus = "STUCK" // only bookings it gave up on are left
+)
+
+// RefundJob cancels and refunds every booking of a cancelled showtime.
+// Progress lives here so the job resumes after a restart or leader change.
+type RefundJob struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ShowtimeID string             `bson:"showtime_id" json:"showtime_id"` // unique: one job per showtime
+	Status     RefundJobStatus    `bson:"status" json:"status"`
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	StartedBy  string             `bson:"started_by" json:"started_by"` // admin user id
+
+	Total          int64  `bson:"total" json:"total"`                     // bookings to refund when started
+	Processed      int64  `bson:"processed" json:"processed"`             // cancelled + refunded
+	RefundedAmount int64  `bson:"refunded_amount" json:"refunded_amount"` // paid back through the provider
+	Failed         int64  `bson:"failed" json:"failed"`                   // bookings currently failing
+	LastError      string `bson:"last_error,omitempty" json:"last_error,omitempty"`
+
+	// bookings that failed, by booking id; removed once refunded
+	Failures map[string]RefundFailure `bson:"failures,omitempty" json:"failures,omitempty"`
+
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updated_at"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// RefundFailure is a booking the job could not refund yet. Retries back off;
+// after the last attempt it is left for an admin (GaveUp).
+type RefundFailure struct {
+	Attempts  int       `bson:"attempts" json:"attempts"`
+	LastError string    `bson:"last_error" json:"last_error"`
+	NextAt    time.Time `bson:"next_at" json:"next_at"`
+	GaveUp    bool      `bson:"gave_up,omitempty" json:"gave_up,omitempty"`
+}
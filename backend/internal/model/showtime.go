@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type ShowtimeStatus string
+
+const (
+	ShowtimeDraft       ShowtimeStatus = "DRAFT"        // not published
+	ShowtimeOnSale      ShowtimeStatus = "ON_SALE"      // bookable inside the sales window
+	ShowtimeSalesClosed ShowtimeStatus = "SALES_CLOSED" // past SalesCloseAt
+	ShowtimeStarted     ShowtimeStatus = "STARTED"      // past StartsAt
+	ShowtimeCancelled   ShowtimeStatus = "CANCELLED"
+)
+
+// Showtime is one screening. Its id is the showtime id used everywhere else
+// (seat keys, bookings, channels). Showtimes without a document are not
+// managed: they stay bookable (demo ids).
+type Showtime struct {
+	ID       string         `bson:"_id" json:"id"`
+	MovieID  string         `bson:"movie_id" json:"movie_id"`
+	CinemaID string         `bson:"cinema_id,omitempty" json:"cinema_id,omitempty"`
+	Hall     string         `bson:"hall,omitempty" json:"hall,omitempty"`
+	Status   ShowtimeStatus `bson:"status" json:"status"`
+
+	StartsAt     time.Time `bson:"starts_at" json:"starts_at"`
+	EndsAt       time.Time `bson:"ends_at" json:"ends_at"`
+	SalesOpenAt  time.Time `bson:"sales_open_at" json:"sales_open_at"`
+	SalesCloseAt time.Time `bson:"sales_close_at" json:"sales_close_at"`
+
+	CancelledAt  *time.Time `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CancelledBy  string     `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+	CancelReason string     `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
+
+	// set once the scheduler has removed the showtime's seat keys
+	CleanedAt *time.Time `bson:"cleaned_at,omitempty" json:"cleaned_at,omitempty"`
+
+	CreatedBy string    `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
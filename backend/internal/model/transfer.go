@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "PENDING"
+	TransferAccepting TransferStatus = "ACCEPTING" // claimed by a recipient, in progress
+	TransferAccepted  TransferStatus = "ACCEPTED"
+	TransferCancelled TransferStatus = "CANCELLED"
+)
+
+// TicketTransfer offers seats of a booking to another user, either by email
+// or to whoever opens the claim link (Token).
+type TicketTransfer struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	BookingID    primitive.ObjectID  `bson:"booking_id" json:"booking_id"`
+	ShowtimeID   string              `bson:"showtime_id" json:"showtime_id"`
+	FromUserID   string              `bson:"from_user_id" json:"from_user_id"`
+	SeatIDs      []string            `bson:"seat_ids" json:"seat_ids"`
+	ToEmail      string              `bson:"to_email,omitempty" json:"to_email,omitempty"`
+	Token        string              `bson:"token" json:"-"`
+	Status       TransferStatus      `bson:"status" json:"status"`
+	ToUserID     string              `bson:"to_user_id,omitempty" json:"to_user_id,omitempty"`
+	NewBookingID *primitive.ObjectID `bson:"new_booking_id,omitempty" json:"new_booking_id,omitempty"`
+	ExpiresAt    time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Private, per-user notifications (served on /ws/me/events and /sse/me/events).
+// Public seat channels carry no user identity; anything addressed to one
+// user goes here.
+const (
+	HoldExpiringSoon  = "hold.expiring_soon"
+	HoldExpired       = "hold.expired"
+	PaymentSucceeded  = "payment.succeeded"
+	PaymentFailed     = "payment.failed"
+	BookingCancelled  = "booking.cancelled"
+	WaitlistOffer     = "waitlist.offer"
+	GroupSeatClaimed  = "group.seat_claimed" // to the organizer
+	TransferOffered   = "transfer.offered"   // to the recipient (by email)
+	TransferAccepted  = "transfer.accepted"  // to the sender
+	ShowtimeCancelled = "showtime.cancelled" // booking cancelled + refunded
+)
+
+type UserEvent struct {
+	Type       string   `json:"type"`
+	UserID     string   `json:"-"` // routing only
+	ShowtimeID string   `json:"showtime_id,omitempty"`
+	SeatIDs    []string `json:"seat_ids,omitempty"`
+	RequestID  string   `json:"request_id,omitempty"`
+	BookingID  string   `json:"booking_id,omitempty"`
+	TransferID string   `json:"transfer_id,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"` // unix seconds (holds)
+	At         int64    `json:"at"`                   // unix seconds
+}
+
+// Channel is the Redis channel of one user's private events.
+func Channel(userID string) string {
+	return fmt.Sprintf("user-events:%s", userID)
+}
+
+// Publish sends ev to its user (best-effort, like seat events).
+func Publish(ctx context.Context, rdb *redis.Client, ev UserEvent) {
+	if ev.UserID == "" {
+		return
+	}
+	if ev.At == 0 {
+		ev.At = time.Now().Unix()
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	_ = rdb.Publish(ctx, Channel(ev.UserID), b).Err()
+}
@@ -0,0 +1,171 @@
+package promo
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error is a promo rejection; Code is the API error string.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string { return "promo: " + e.Code }
+
+var (
+	ErrNotFound      = &Error{Code: "promo_not_found"}
+	ErrInactive      = &Error{Code: "promo_inactive"}
+	ErrNotValidNow   = &Error{Code: "promo_not_valid_now"}
+	ErrMinSeats      = &Error{Code: "promo_min_seats"}
+	ErrNotApplicable = &Error{Code: "promo_not_applicable"}
+	ErrExhausted     = &Error{Code: "promo_exhausted"}
+	ErrUserLimit     = &Error{Code: "promo_user_limit"}
+)
+
+// Target is what a promo is checked against.
+type Target struct {
+	ShowtimeID string
+	MovieID    string    // from the showtime; "" for unmanaged showtimes (movie-restricted promos don't apply)
+	Day        time.Time // screening day (weekday restriction)
+	Seats      int
+	Subtotal   int64
+	At         time.Time // validity window
+}
+
+type Service struct {
+	promos *repo.PromoRepo
+}
+
+func New(promos *repo.PromoRepo) *Service {
+	return &Service{promos: promos}
+}
+
+// NormalizeCode trims and uppercases a code.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Quote checks code against t and returns the promo and its discount.
+// Limits are only checked when redeeming.
+func (s *Service) Quote(ctx context.Context, code string, t Target) (*model.Promo, int64, error) {
+	p, err := s.promos.FindByCode(ctx, NormalizeCode(code))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !p.Active {
+		return nil, 0, ErrInactive
+	}
+	if (p.ValidFrom != nil && t.At.Before(*p.ValidFrom)) || (p.ValidTo != nil && t.At.After(*p.ValidTo)) {
+		return nil, 0, ErrNotValidNow
+	}
+	if p.MinSeats > 0 && t.Seats < p.MinSeats {
+		return nil, 0, ErrMinSeats
+	}
+	if len(p.ShowtimeIDs) > 0 && !slices.Contains(p.ShowtimeIDs, t.ShowtimeID) {
+		return nil, 0, ErrNotApplicable
+	}
+	if len(p.MovieIDs) > 0 && !slices.Contains(p.MovieIDs, t.MovieID) {
+		return nil, 0, ErrNotApplicable
+	}
+	if len(p.Weekdays) > 0 && !slices.Contains(p.Weekdays, int(t.Day.Weekday())) {
+		return nil, 0, ErrNotApplicable
+	}
+
+	return p, Discount(p, t.Subtotal), nil
+}
+
+// Discount is the amount p takes off subtotal (never more than subtotal).
+func Discount(p *model.Promo, subtotal int64) int64 {
+	var d int64
+	switch p.Kind {
+	case model.PromoPercent:
+		d = subtotal * p.Value / 100
+	case model.PromoFixed:
+		d = p.Value
+	}
+	return min(max(d, 0), subtotal)
+}
+
+// Redeem atomically consumes one use of p for bookingID: per-user limit,
+// then global limit, then the redemption record. Partial steps are undone.
+func (s *Service) Redeem(ctx context.Context, p *model.Promo, userID string, bookingID primitive.ObjectID, discount int64) error {
+	ok, err := s.promos.ReserveUser(ctx, p.ID, userID, p.PerUserLimit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserLimit
+	}
+
+	ok, err = s.promos.ReserveGlobal(ctx, p.ID)
+	if err != nil || !ok {
+		_ = s.promos.ReleaseUser(ctx, p.ID, userID)
+		if err != nil {
+			return err
+		}
+		return ErrExhausted
+	}
+
+	if err := s.promos.InsertRedemption(ctx, &model.PromoRedemption{
+		PromoID:    p.ID,
+		Code:       p.Code,
+		UserID:     userID,
+		BookingID:  bookingID,
+		Discount:   discount,
+		Status:     model.RedemptionRedeemed,
+		RedeemedAt: time.Now(),
+	}); err != nil {
+		_ = s.promos.ReleaseGlobal(ctx, p.ID)
+		_ = s.promos.ReleaseUser(ctx, p.ID, userID)
+		return err
+	}
+	return nil
+}
+
+// a claimed reversal that hasn't finished after this is taken over
+const reversalLease = time.Minute
+
+// Reverse gives back the redemption of bookingID, if any (cancelled or
+// failed booking). Safe to call more than once. The counters are given
+// back before the redemption is marked REVERSED, each recorded on it, so a
+// failure part way is finished by the next call instead of leaving the
+// promo's usage inflated.
+func (s *Service) Reverse(ctx context.Context, bookingID primitive.ObjectID) error {
+	red, err := s.promos.ClaimReversal(ctx, bookingID, time.Now().Add(-reversalLease))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !red.GlobalReleased {
+		if err := s.promos.ReleaseGlobal(ctx, red.PromoID); err != nil {
+			return err
+		}
+		if err := s.promos.MarkReleased(ctx, red.ID, "global_released"); err != nil {
+			return err
+		}
+	}
+	if !red.UserReleased {
+		if err := s.promos.ReleaseUser(ctx, red.PromoID, red.UserID); err != nil {
+			return err
+		}
+		if err := s.promos.MarkReleased(ctx, red.ID, "user_released"); err != nil {
+			return err
+		}
+	}
+	return s.promos.FinishReversal(ctx, red.ID)
+}
@@ -0,0 +1,48 @@
+package promo
+
+import (
+	"cinema/internal/model"
+	"testing"
+)
+
+func TestDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     model.PromoKind
+		value    int64
+		subtotal int64
+		want     int64
+	}{
+		{name: "percent", kind: model.PromoPercent, value: 10, subtotal: 500, want: 50},
+		{name: "percent rounds down", kind: model.PromoPercent, value: 15, subtotal: 99, want: 14},
+		{name: "hundred percent", kind: model.PromoPercent, value: 100, subtotal: 320, want: 320},
+		{name: "percent over hundred is capped", kind: model.PromoPercent, value: 150, subtotal: 200, want: 200},
+		{name: "fixed", kind: model.PromoFixed, value: 80, subtotal: 500, want: 80},
+		{name: "fixed above subtotal", kind: model.PromoFixed, value: 800, subtotal: 500, want: 500},
+		{name: "negative value", kind: model.PromoFixed, value: -50, subtotal: 500, want: 0},
+		{name: "empty subtotal", kind: model.PromoPercent, value: 20, subtotal: 0, want: 0},
+		{name: "unknown kind", kind: "BOGUS", value: 20, subtotal: 500, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &model.Promo{Kind: tt.kind, Value: tt.value}
+			if got := Discount(p, tt.subtotal); got != tt.want {
+				t.Fatalf("Discount = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := map[string]string{
+		" summer10 ": "SUMMER10",
+		"Gold":       "GOLD",
+		"":           "",
+	}
+	for in, want := range tests {
+		if got := NormalizeCode(in); got != want {
+			t.Errorf("NormalizeCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SlowPolicy decides what happens when a client's send queue is full.
+type SlowPolicy int
+
+const (
+	// DisconnectSlow closes the client (it must reconnect/resync).
+	DisconnectSlow SlowPolicy = iota
+	// DropSlow drops the message for that client only.
+	DropSlow
+)
+
+const defaultSendQueue = 64
+
+// Hub shares one Redis Pub/Sub connection per process and holds one
+// channel subscription per active topic (e.g. seat-events:<showtimeId>),
+// fanning messages out to local clients through bounded queues.
+// The Redis subscription is dropped when the last client leaves.
+type Hub struct {
+	rdb       *redis.Client
+	ps        *redis.PubSub
+	sendQueue int
+	policy    SlowPolicy
+
+	mu     sync.Mutex
+	topics map[string]*topicState
+}
+
+// topicState is the local side of one topic. Clients come and go under
+// Hub.mu; the Redis round trips happen outside it, one at a time per topic
+// (subMu), and bring the subscription in line with the client set, so a
+// Join racing the last Leave's Unsubscribe subscribes again afterwards.
+type topicState struct {
+	clients    map[*Client]struct{}
+	subMu      sync.Mutex
+	subscribed bool // guarded by Hub.mu
+}
+
+// Client is one local subscriber (a WebSocket tab, an SSE stream...).
+type Client struct {
+	topic string
+	send  chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Send yields messages for this client, in publish order.
+func (c *Client) Send() <-chan []byte { return c.send }
+
+// Done is closed when the hub disconnects the client (slow consumer, hub closed).
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+func (c *Client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func NewHub(rdb *redis.Client, sendQueue int, policy SlowPolicy) *Hub {
+	if sendQueue <= 0 {
+		sendQueue = defaultSendQueue
+	}
+	return &Hub{
+		rdb:       rdb,
+		sendQueue: sendQueue,
+		policy:    policy,
+		topics:    make(map[string]*topicState),
+	}
+}
+
+// Run opens the shared Pub/Sub connection and dispatches until ctx is cancelled.
+func (h *Hub) Run(ctx context.Context) {
+	h.mu.Lock()
+	h.ps = h.rdb.Subscribe(ctx)
+	// topics joined before Run
+	pending := make(map[string]*topicState, len(h.topics))
+	for name, t := range h.topics {
+		pending[name] = t
+	}
+	h.mu.Unlock()
+
+	for name, t := range pending {
+		if err := h.sync(ctx, name, t); err != nil {
+			log.Println("hub subscribe failed:", err)
+		}
+	}
+
+	ch := h.ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			h.shutdown()
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				h.shutdown()
+				return
+			}
+			h.dispatch(msg.Channel, []byte(msg.Payload))
+		}
+	}
+}
+
+func (h *Hub) shutdown() {
+	h.mu.Lock()
+	for name, t := range h.topics {
+		for c := range t.clients {
+			c.close()
+		}
+		delete(h.topics, name)
+	}
+	ps := h.ps
+	h.ps = nil
+	h.mu.Unlock()
+
+	if ps != nil {
+		_ = ps.Close()
+	}
+}
+
+// Join registers a client on topic, subscribing in Redis if it is the first
+// one. It returns once the subscription is in place.
+func (h *Hub) Join(ctx context.Context, topic string) (*Client, error) {
+	c := &Client{
+		topic: topic,
+		send:  make(chan []byte, h.sendQueue),
+		done:  make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	t, ok := h.topics[topic]
+	if !ok {
+		t = &topicState{clients: make(map[*Client]struct{})}
+		h.topics[topic] = t
+	}
+	t.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	if err := h.sync(ctx, topic, t); err != nil {
+		h.Leave(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+// Leave removes the client; the Redis subscription goes with the last one.
+func (h *Hub) Leave(c *Client) {
+	h.mu.Lock()
+	t := h.removeLocked(c)
+	h.mu.Unlock()
+
+	if t != nil {
+		_ = h.sync(context.Background(), c.topic, t)
+	}
+}
+
+// removeLocked closes c and drops it from its topic; it returns the topic
+// when c was its last client, for the caller to sync outside h.mu.
+func (h *Hub) removeLocked(c *Client) *topicState {
+	c.close()
+
+	t, ok := h.topics[c.topic]
+	if !ok {
+		return nil
+	}
+	if _, ok := t.clients[c]; !ok {
+		return nil
+	}
+	delete(t.clients, c)
+
+	if len(t.clients) == 0 {
+		return t
+	}
+	return nil
+}
+
+// sync subscribes or unsubscribes topic in Redis so that it matches whether
+// t has clients, and forgets t once it has neither.
+func (h *Hub) sync(ctx context.Context, topic string, t *topicState) error {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+
+	h.mu.Lock()
+	ps := h.ps
+	want := len(t.clients) > 0
+	if ps == nil || want == t.subscribed {
+		if !want && !t.subscribed && h.topics[topic] == t {
+			delete(h.topics, topic)
+		}
+		h.mu.Unlock()
+		return nil
+	}
+	h.mu.Unlock()
+
+	var err error
+	if want {
+		err = ps.Subscribe(ctx, topic)
+	} else {
+		err = ps.Unsubscribe(context.Background(), topic)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	t.subscribed = want
+	if !want && len(t.clients) == 0 && h.topics[topic] == t {
+		delete(h.topics, topic)
+	}
+	return nil
+}
+
+// Topics lists the topics with local clients that start with prefix.
+func (h *Hub) Topics(prefix string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]string, 0)
+	for name, t := range h.topics {
+		if len(t.clients) > 0 && strings.HasPrefix(name, prefix) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Broadcast delivers payload to this process's clients of topic only
+// (no Redis round trip), e.g. values every replica computes itself.
+func (h *Hub) Broadcast(topic string, payload []byte) {
+	h.dispatch(topic, payload)
+}
+
+func (h *Hub) dispatch(topic string, payload []byte) {
+	h.mu.Lock()
+	var emptied *topicState
+	if t, ok := h.topics[topic]; ok {
+		for c := range t.clients {
+			select {
+			case c.send <- payload:
+			default:
+				// queue full: never block the shared reader on one client
+				if h.policy == DisconnectSlow {
+					if e := h.removeLocked(c); e != nil {
+						emptied = e
+					}
+				}
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	// not inline: Unsubscribe would wait on the reader that called dispatch
+	if emptied != nil {
+		go func() { _ = h.sync(context.Background(), topic, emptied) }()
+	}
+}
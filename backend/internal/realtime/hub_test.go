@@ -0,0 +1,178 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestHub(t *testing.T, sendQueue int, policy SlowPolicy) (*Hub, *redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	h := NewHub(rdb, sendQueue, policy)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go h.Run(ctx)
+	return h, rdb, mr
+}
+
+// subscribers waits until Redis sees want subscriptions on topic.
+func subscribers(t *testing.T, mr *miniredis.Miniredis, topic string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := mr.PubSubNumSub(topic)[topic]
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: %d redis subscriptions, want %d", topic, got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, c *Client) string {
+	t.Helper()
+	select {
+	case msg := <-c.Send():
+		return string(msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message")
+		return ""
+	}
+}
+
+func TestHubOneSubscriptionPerTopic(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 0, DisconnectSlow)
+
+	a, err := h.Join(ctx, "seat-events:st1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := h.Join(ctx, "seat-events:st1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := h.Join(ctx, "seat-events:st2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribers(t, mr, "seat-events:st1", 1)
+	subscribers(t, mr, "seat-events:st2", 1)
+
+	rdb.Publish(ctx, "seat-events:st1", "m1")
+	if got := receive(t, a); got != "m1" {
+		t.Fatalf("a got %q", got)
+	}
+	if got := receive(t, b); got != "m1" {
+		t.Fatalf("b got %q", got)
+	}
+	select {
+	case msg := <-other.Send():
+		t.Fatalf("st2 client got %q", msg)
+	default:
+	}
+
+	// the subscription stays while a client is left, and goes with the last
+	h.Leave(a)
+	subscribers(t, mr, "seat-events:st1", 1)
+	h.Leave(b)
+	subscribers(t, mr, "seat-events:st1", 0)
+	subscribers(t, mr, "seat-events:st2", 1)
+}
+
+func TestHubDisconnectsSlowClient(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 1, DisconnectSlow)
+
+	slow, err := h.Join(ctx, "seat-events:st1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribers(t, mr, "seat-events:st1", 1)
+
+	rdb.Publish(ctx, "seat-events:st1", "m1")
+	rdb.Publish(ctx, "seat-events:st1", "m2")
+	select {
+	case <-slow.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow client not disconnected")
+	}
+	subscribers(t, mr, "seat-events:st1", 0)
+}
+
+func TestHubDropsForSlowClient(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 1, DropSlow)
+
+	slow, err := h.Join(ctx, "seat-events:st1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribers(t, mr, "seat-events:st1", 1)
+
+	rdb.Publish(ctx, "seat-events:st1", "m1")
+	rdb.Publish(ctx, "seat-events:st1", "m2")
+	rdb.Publish(ctx, "seat-events:st1", "m3")
+	time.Sleep(100 * time.Millisecond) // all three dispatched
+	if got := receive(t, slow); got != "m1" {
+		t.Fatalf("got %q, want the first message", got)
+	}
+	select {
+	case msg := <-slow.Send():
+		t.Fatalf("got %q, want the rest dropped", msg)
+	default:
+	}
+
+	// still joined: later messages arrive
+	rdb.Publish(ctx, "seat-events:st1", "m4")
+	if got := receive(t, slow); got != "m4" {
+		t.Fatalf("got %q, want m4", got)
+	}
+	select {
+	case <-slow.Done():
+		t.Fatal("client disconnected under DropSlow")
+	default:
+	}
+}
+
+func TestHubJoinRacingLastLeave(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 0, DisconnectSlow)
+
+	// clients come and go while the subscription flips on and off
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := h.Join(ctx, "seat-events:st1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			h.Leave(c)
+		}()
+	}
+	stay, err := h.Join(ctx, "seat-events:st1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	// whatever the order, the client that stayed is subscribed
+	subscribers(t, mr, "seat-events:st1", 1)
+	rdb.Publish(ctx, "seat-events:st1", "m1")
+	if got := receive(t, stay); got != "m1" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,206 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	presenceTick = 5 * time.Second  // refresh + push viewer count
+	presenceTTL  = 15 * time.Second // viewer entry expires if its replica dies
+	// IntentTTL is how long a "considering" marker stays without refresh.
+	IntentTTL = 5 * time.Second
+	// MaxIntentSeats caps seats per intent message.
+	MaxIntentSeats = 10
+)
+
+const presencePrefix = "seat-presence:"
+
+// PresenceChannel carries viewer counts and soft seat intents for a showtime.
+// Kept apart from seat-events so it is not sequenced, logged or audited.
+func PresenceChannel(showtimeID string) string {
+	return presencePrefix + showtimeID
+}
+
+func viewersKey(showtimeID string) string {
+	return fmt.Sprintf("seatviewers:%s", showtimeID)
+}
+
+// Presence tracks announced viewers per showtime across replicas
+// (ZSET member = connection id, score = expiry ms) and relays intents.
+// Counts go to every local client of a showtime's presence channel, whether
+// or not it announced itself.
+type Presence struct {
+	rdb *redis.Client
+	hub *Hub
+
+	mu      sync.Mutex
+	viewers map[string]map[string]struct{} // showtime -> local connection ids
+	intents map[string]map[string]struct{} // showtime -> local connections with an intent
+}
+
+func NewPresence(rdb *redis.Client, hub *Hub) *Presence {
+	return &Presence{
+		rdb:     rdb,
+		hub:     hub,
+		viewers: make(map[string]map[string]struct{}),
+		intents: make(map[string]map[string]struct{}),
+	}
+}
+
+// track adds (on) or removes connID in m[showtimeID]; it reports whether
+// connID was there. Caller holds p.mu.
+func track(m map[string]map[string]struct{}, showtimeID, connID string, on bool) bool {
+	set, ok := m[showtimeID]
+	if !ok {
+		if !on {
+			return false
+		}
+		set = make(map[string]struct{})
+		m[showtimeID] = set
+	}
+	_, had := set[connID]
+	if on {
+		set[connID] = struct{}{}
+	} else {
+		delete(set, connID)
+		if len(set) == 0 {
+			delete(m, showtimeID)
+		}
+	}
+	return had
+}
+
+type ViewersMsg struct {
+	Type       string `json:"type"` // "viewers"
+	ShowtimeID string `json:"showtime_id"`
+	Count      int64  `json:"count"`
+	At         int64  `json:"at"`
+}
+
+type IntentMsg struct {
+	Type       string   `json:"type"` // "intent"
+	ShowtimeID string   `json:"showtime_id"`
+	Viewer     string   `json:"viewer"`   // opaque connection id, not a user id
+	SeatIDs    []string `json:"seat_ids"` // empty = cleared
+	TTLMs      int64    `json:"ttl_ms"`
+	At         int64    `json:"at"`
+}
+
+// Announce counts connID as a viewer of showtimeID.
+func (p *Presence) Announce(ctx context.Context, showtimeID, connID string) error {
+	p.mu.Lock()
+	track(p.viewers, showtimeID, connID, true)
+	p.mu.Unlock()
+
+	exp := time.Now().Add(presenceTTL).UnixMilli()
+	return p.rdb.ZAdd(ctx, viewersKey(showtimeID), redis.Z{Score: float64(exp), Member: connID}).Err()
+}
+
+// Leave removes connID and clears its intent, if it had either.
+func (p *Presence) Leave(ctx context.Context, showtimeID, connID string) {
+	p.mu.Lock()
+	announced := track(p.viewers, showtimeID, connID, false)
+	intent := track(p.intents, showtimeID, connID, false)
+	p.mu.Unlock()
+
+	if announced {
+		_ = p.rdb.ZRem(ctx, viewersKey(showtimeID), connID).Err()
+	}
+	if intent {
+		_ = p.Intent(ctx, showtimeID, connID, nil)
+	}
+}
+
+// Intent publishes a soft "considering" marker (not a lock) to every replica.
+func (p *Presence) Intent(ctx context.Context, showtimeID, connID string, seatIDs []string) error {
+	if seatIDs == nil {
+		seatIDs = []string{}
+	}
+	p.mu.Lock()
+	track(p.intents, showtimeID, connID, len(seatIDs) > 0)
+	p.mu.Unlock()
+
+	b, err := json.Marshal(IntentMsg{
+		Type:       "intent",
+		ShowtimeID: showtimeID,
+		Viewer:     connID,
+		SeatIDs:    seatIDs,
+		TTLMs:      IntentTTL.Milliseconds(),
+		At:         time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return p.rdb.Publish(ctx, PresenceChannel(showtimeID), b).Err()
+}
+
+// Run refreshes local viewers and pushes the global count to local clients
+// every tick. Each replica reads the same ZSET, so counts need no Pub/Sub.
+func (p *Presence) Run(ctx context.Context) {
+	ticker := time.NewTicker(presenceTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.tick(ctx)
+		}
+	}
+}
+
+func (p *Presence) tick(ctx context.Context) {
+	watched := p.hub.Topics(presencePrefix)
+
+	p.mu.Lock()
+	local := make(map[string][]string, len(watched)+len(p.viewers))
+	for _, topic := range watched {
+		local[strings.TrimPrefix(topic, presencePrefix)] = nil
+	}
+	for st, set := range p.viewers {
+		ids := make([]string, 0, len(set))
+		for id := range set {
+			ids = append(ids, id)
+		}
+		local[st] = ids
+	}
+	p.mu.Unlock()
+
+	now := time.Now()
+	exp := float64(now.Add(presenceTTL).UnixMilli())
+
+	for st, ids := range local {
+		zk := viewersKey(st)
+
+		pipe := p.rdb.Pipeline()
+		for _, id := range ids {
+			pipe.ZAdd(ctx, zk, redis.Z{Score: exp, Member: id})
+		}
+		pipe.ZRemRangeByScore(ctx, zk, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		pipe.Expire(ctx, zk, 2*presenceTTL)
+		countCmd := pipe.ZCard(ctx, zk)
+		if _, err := pipe.Exec(ctx); err != nil {
+			continue
+		}
+
+		b, err := json.Marshal(ViewersMsg{
+			Type:       "viewers",
+			ShowtimeID: st,
+			Count:      countCmd.Val(),
+			At:         now.Unix(),
+		})
+		if err != nil {
+			continue
+		}
+		p.hub.Broadcast(PresenceChannel(st), b)
+	}
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nextViewers skips intents up to the next viewer count.
+func nextViewers(t *testing.T, c *Client) ViewersMsg {
+	t.Helper()
+	for {
+		var msg ViewersMsg
+		if err := json.Unmarshal([]byte(receive(t, c)), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == "viewers" {
+			return msg
+		}
+	}
+}
+
+func TestPresenceCountsViewersAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	hub1, rdb, _ := newTestHub(t, 0, DisconnectSlow)
+
+	// two replicas share the viewers ZSET
+	p1 := NewPresence(rdb, hub1)
+	p2 := NewPresence(rdb, NewHub(rdb, 0, DisconnectSlow))
+
+	tab, err := hub1.Join(ctx, PresenceChannel("st1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p1.Announce(ctx, "st1", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p2.Announce(ctx, "st1", "c2"); err != nil {
+		t.Fatal(err)
+	}
+	// a replica that died without Leave: its entry has expired
+	rdb.ZAdd(ctx, viewersKey("st1"), redis.Z{Score: float64(time.Now().Add(-time.Second).UnixMilli()), Member: "gone"})
+
+	p1.tick(ctx)
+
+	if msg := nextViewers(t, tab); msg.ShowtimeID != "st1" || msg.Count != 2 {
+		t.Fatalf("viewers = %+v, want 2", msg)
+	}
+
+	p2.Leave(ctx, "st1", "c2")
+	p1.tick(ctx)
+	if msg := nextViewers(t, tab); msg.Count != 1 {
+		t.Fatalf("viewers after leave = %d, want 1", msg.Count)
+	}
+}
+
+func TestPresenceLeaveClearsIntent(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 0, DisconnectSlow)
+	p := NewPresence(rdb, h)
+
+	tab, err := h.Join(ctx, PresenceChannel("st1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribers(t, mr, PresenceChannel("st1"), 1)
+
+	// never announced: nothing to clear
+	p.Leave(ctx, "st1", "c0")
+
+	if err := p.Announce(ctx, "st1", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Intent(ctx, "st1", "c1", []string{"A1"}); err != nil {
+		t.Fatal(err)
+	}
+	p.Leave(ctx, "st1", "c1")
+
+	for _, want := range []int{1, 0} {
+		var msg IntentMsg
+		if err := json.Unmarshal([]byte(receive(t, tab)), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "intent" || msg.Viewer != "c1" || len(msg.SeatIDs) != want {
+			t.Fatalf("intent = %+v, want %d seats", msg, want)
+		}
+	}
+	if n, _ := rdb.ZCard(ctx, viewersKey("st1")).Result(); n != 0 {
+		t.Fatalf("%d viewers left", n)
+	}
+}
+
+func TestPresenceCountsForSilentViewers(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, _ := newTestHub(t, 0, DisconnectSlow)
+	p := NewPresence(rdb, h)
+
+	// watching without announcing; the only viewer is on another replica
+	tab, err := h.Join(ctx, PresenceChannel("st1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPresence(rdb, NewHub(rdb, 0, DisconnectSlow)).Announce(ctx, "st1", "c2"); err != nil {
+		t.Fatal(err)
+	}
+
+	p.tick(ctx)
+	if msg := nextViewers(t, tab); msg.Count != 1 {
+		t.Fatalf("viewers = %d, want 1", msg.Count)
+	}
+}
+
+func TestPresenceLeaveClearsUnannouncedIntent(t *testing.T) {
+	ctx := context.Background()
+	h, rdb, mr := newTestHub(t, 0, DisconnectSlow)
+	p := NewPresence(rdb, h)
+
+	tab, err := h.Join(ctx, PresenceChannel("st1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribers(t, mr, PresenceChannel("st1"), 1)
+
+	if err := p.Intent(ctx, "st1", "c1", []string{"A1"}); err != nil {
+		t.Fatal(err)
+	}
+	p.Leave(ctx, "st1", "c1")
+
+	for _, want := range []int{1, 0} {
+		var msg IntentMsg
+		if err := json.Unmarshal([]byte(receive(t, tab)), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "intent" || len(msg.SeatIDs) != want {
+			t.Fatalf("intent = %+v, want %d seats", msg, want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(1000, 2)
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("burst not allowed")
+	}
+	if l.Allow() {
+		t.Fatal("allowed past the burst")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("no token after refill")
+	}
+}
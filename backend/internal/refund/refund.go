@@ -0,0 +1,182 @@
+package refund
+
+import (
+	"cinema/internal/concession"
+	"cinema/internal/giftcard"
+	"cinema/internal/loyalty"
+	"cinema/internal/model"
+	"cinema/internal/notify"
+	"cinema/internal/promo"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Bulk refunds of a cancelled showtime. Start records a job; the worker
+// (see Run) cancels every booking of the showtime the way an owner's
+// cancel does (seats, promo, concessions, points, gift card) and pays back
+// what the provider charged. A booking counts as done once refunded_at is
+// set, so the job resumes where it stopped after a restart. A booking whose
+// refund fails is retried with backoff and recorded in the job's failures;
+// a job left with only given-up bookings ends STUCK, and cancelling the
+// showtime again (Start) puts it back to work.
+
+var ErrNotFound = errors.New("refund job not found")
+
+// the booking left BOOKED under us; the next pass picks it up if still owed
+var errChanged = errors.New("booking changed")
+
+type Service struct {
+	rdb         *redis.Client
+	seats       *seatlock.Service
+	bookings    *repo.BookingRepo
+	jobs        *repo.RefundJobRepo
+	promos      *promo.Service
+	concessions *concession.Service
+	loyalty     *loyalty.Service
+	giftCards   *giftcard.Service
+}
+
+func New(
+	rdb *redis.Client,
+	seats *seatlock.Service,
+	bookings *repo.BookingRepo,
+	jobs *repo.RefundJobRepo,
+	promos *promo.Service,
+	concessions *concession.Service,
+	loyaltySvc *loyalty.Service,
+	giftCards *giftcard.Service,
+) *Service {
+	return &Service{
+		rdb:         rdb,
+		seats:       seats,
+		bookings:    bookings,
+		jobs:        jobs,
+		promos:      promos,
+		concessions: concessions,
+		loyalty:     loyaltySvc,
+		giftCards:   giftCards,
+	}
+}
+
+// Start creates the refund job of showtimeID, or returns the existing one
+// (a STUCK one starts over on the bookings it gave up on).
+func (s *Service) Start(ctx context.Context, showtimeID, adminID, reason string) (*model.RefundJob, error) {
+	total, err := s.bookings.CountToRefund(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &model.RefundJob{
+		ShowtimeID: showtimeID,
+		Reason:     reason,
+		StartedBy:  adminID,
+		Total:      total,
+	}
+	err = s.jobs.Create(ctx, j)
+	if mongo.IsDuplicateKeyError(err) {
+		if err := s.jobs.Retry(ctx, showtimeID); err != nil {
+			return nil, err
+		}
+		return s.Job(ctx, showtimeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Job returns the refund job of showtimeID.
+func (s *Service) Job(ctx context.Context, showtimeID string) (*model.RefundJob, error) {
+	j, err := s.jobs.FindByShowtime(ctx, showtimeID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return j, err
+}
+
+// same shape as the booking-events published by the booking handler
+type bookingEvent struct {
+	Type      string   `json:"type"`
+	BookingID string   `json:"booking_id"`
+	Showtime  string   `json:"showtime_id"`
+	UserID    string   `json:"user_id"`
+	SeatIDs   []string `json:"seat_ids"`
+	Amount    int64    `json:"amount"`
+	Currency  string   `json:"currency"`
+	Reason    string   `json:"reason,omitempty"`
+	At        int64    `json:"at"`
+}
+
+// refundOne cancels b (if still BOOKED), gives back everything it used and
+// refunds its amount. Every step is safe to repeat. Returns the amount paid
+// back through the provider.
+func (s *Service) refundOne(ctx context.Context, j *model.RefundJob, b *model.Booking) (int64, error) {
+	if b.Status == model.BookingBooked {
+		cb, err := s.bookings.MarkCancelled(ctx, b.ID, model.CancelByShowtime)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, errChanged
+		}
+		if err != nil {
+			return 0, err
+		}
+		b = cb
+		if _, err := s.seats.ReleaseBooked(ctx, b.ShowtimeID, b.SeatIDs, b.UserID.Hex(), b.ID.Hex()); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.promos.Reverse(ctx, b.ID); err != nil {
+		return 0, err
+	}
+	if err := s.concessions.ReleaseBooking(ctx, b.ID); err != nil {
+		return 0, err
+	}
+	if err := s.loyalty.RefundRedemption(ctx, b.ID); err != nil {
+		return 0, err
+	}
+	if err := s.giftCards.RefundBooking(ctx, b.ID); err != nil {
+		return 0, err
+	}
+
+	// mock provider refund of what was charged
+	refundRef := ""
+	if b.Amount > 0 {
+		refundRef = "mock_refund_" + uuid.NewString()
+	}
+	if err := s.bookings.MarkRefunded(ctx, b.ID, refundRef); err != nil {
+		return 0, err
+	}
+
+	// earned points come off in the loyalty consumer; audit logs it
+	ev := bookingEvent{
+		Type:      "booking.cancelled",
+		BookingID: b.ID.Hex(),
+		Showtime:  b.ShowtimeID,
+		UserID:    b.UserID.Hex(),
+		SeatIDs:   b.SeatIDs,
+		Amount:    b.Amount,
+		Currency:  b.Currency,
+		Reason:    model.CancelByShowtime,
+		At:        time.Now().Unix(),
+	}
+	if raw, err := json.Marshal(ev); err == nil {
+		_ = s.rdb.Publish(ctx, "booking-events", raw).Err()
+	}
+	notify.Publish(ctx, s.rdb, notify.UserEvent{
+		Type:       notify.ShowtimeCancelled,
+		UserID:     b.UserID.Hex(),
+		ShowtimeID: b.ShowtimeID,
+		SeatIDs:    b.SeatIDs,
+		BookingID:  b.ID.Hex(),
+		Reason:     j.Reason,
+	})
+	return b.Amount, nil
+}
@@ -0,0 +1,133 @@
+package refund
+
+import (
+	"cinema/internal/model"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	runEvery  = 5 * time.Second
+	batchSize = 50
+
+	// a failing booking is retried after runEvery, 2x, 4x ... up to
+	// maxBackoff, and given up on (left to an admin) after maxAttempts
+	maxBackoff  = 10 * time.Minute
+	maxAttempts = 8
+)
+
+// Run works through the running refund jobs until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(runEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jobs, err := s.jobs.FindRunning(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Println("refund jobs failed:", err)
+				}
+				continue
+			}
+			for i := range jobs {
+				s.process(ctx, &jobs[i])
+			}
+		}
+	}
+}
+
+// process makes one pass over the job's bookings; failed ones are retried
+// with backoff on later passes. The job is done when nothing is left to
+// refund, or stuck when only bookings it gave up on are left.
+func (s *Service) process(ctx context.Context, j *model.RefundJob) {
+	now := time.Now()
+	after := primitive.NilObjectID
+	retryLater := false
+	gaveUp := 0
+	for ctx.Err() == nil {
+		batch, err := s.bookings.FindToRefund(ctx, j.ShowtimeID, after, batchSize)
+		if err != nil {
+			log.Printf("refund job %s: %v", j.ShowtimeID, err)
+			return
+		}
+
+		for i := range batch {
+			b := &batch[i]
+			after = b.ID
+			bid := b.ID.Hex()
+
+			prev, hadFailure := j.Failures[bid]
+			if hadFailure && prev.GaveUp {
+				gaveUp++
+				continue
+			}
+			if hadFailure && now.Before(prev.NextAt) {
+				retryLater = true
+				continue
+			}
+
+			amount, err := s.refundOne(ctx, j, b)
+			if errors.Is(err, errChanged) {
+				retryLater = true
+				continue
+			}
+			if err != nil {
+				f := nextFailure(prev, err, now)
+				if f.GaveUp {
+					gaveUp++
+					log.Printf("refund job %s: giving up on booking %s: %v", j.ShowtimeID, bid, err)
+				} else {
+					retryLater = true
+				}
+				_ = s.jobs.RecordFailure(ctx, j.ID, bid, f)
+				continue
+			}
+			_ = s.jobs.Progress(ctx, j.ID, bid, amount, hadFailure)
+		}
+		if len(batch) < batchSize {
+			break
+		}
+	}
+	if retryLater || ctx.Err() != nil {
+		return
+	}
+
+	// bookings confirmed while the pass ran show up here
+	left, err := s.bookings.CountToRefund(ctx, j.ShowtimeID)
+	if err != nil || left > int64(gaveUp) {
+		return
+	}
+	status := model.RefundJobDone
+	if gaveUp > 0 {
+		status = model.RefundJobStuck
+	}
+	if err := s.jobs.Finish(ctx, j.ID, status); err != nil {
+		log.Printf("refund job %s: %v", j.ShowtimeID, err)
+		return
+	}
+	log.Printf("refund job %s %s", j.ShowtimeID, strings.ToLower(string(status)))
+}
+
+// nextFailure counts one more failed attempt and schedules the next one.
+func nextFailure(prev model.RefundFailure, err error, now time.Time) model.RefundFailure {
+	f := model.RefundFailure{Attempts: prev.Attempts + 1, LastError: err.Error()}
+	if f.Attempts >= maxAttempts {
+		f.GaveUp = true
+		return f
+	}
+	backoff := runEvery << (f.Attempts - 1)
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	f.NextAt = now.Add(backoff)
+	return f
+}
@@ -0,0 +1,43 @@
+package refund
+
+import (
+	"cinema/internal/model"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextFailure(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	boom := errors.New("payment gateway down")
+
+	tests := []struct {
+		name     string
+		attempts int
+		wait     time.Duration // 0 = given up
+	}{
+		{name: "first failure", attempts: 0, wait: runEvery},
+		{name: "second failure doubles", attempts: 1, wait: 2 * runEvery},
+		{name: "third failure", attempts: 2, wait: 4 * runEvery},
+		{name: "last retry", attempts: maxAttempts - 2, wait: runEvery << (maxAttempts - 2)},
+		{name: "gives up", attempts: maxAttempts - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := nextFailure(model.RefundFailure{Attempts: tt.attempts, LastError: "old"}, boom, now)
+			if f.Attempts != tt.attempts+1 || f.LastError != boom.Error() {
+				t.Fatalf("nextFailure = %+v", f)
+			}
+			if tt.wait == 0 {
+				if !f.GaveUp {
+					t.Fatalf("nextFailure = %+v, want given up", f)
+				}
+				return
+			}
+			if f.GaveUp || !f.NextAt.Equal(now.Add(tt.wait)) {
+				t.Fatalf("next attempt at %v, want %v later", f.NextAt, tt.wait)
+			}
+		})
+	}
+}
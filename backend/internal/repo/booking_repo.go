@@ -19,6 +19,15 @@ func NewBookingRepo(db *mongo.Database) *BookingRepo {
 	return &BookingRepo{col: db.Collection("bookings")}
 }
 
+// EnsureIndexes creates the per-showtime index used by refunds and seat
+// repair (idempotent).
+func (r *BookingRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "showtime_id", Value: 1}, {Key: "status", Value: 1}},
+	})
+	return err
+}
+
 // CreatePending creates a booking document with status=PENDING.
 func (r *BookingRepo) CreatePending(ctx context.Context, b *model.Booking) error {
 	if b == nil {
@@ -47,6 +56,25 @@ func (r *BookingRepo) MarkBooked(ctx context.Context, bookingID primitive.Object
 	return err
 }
 
+// CompletePending marks a booking BOOKED only if it is still PENDING
+// (reconciler: seats were booked in Redis but the confirm never finished).
+func (r *BookingRepo) CompletePending(ctx context.Context, bookingID primitive.ObjectID, paymentRef string) (bool, error) {
+	now := time.Now()
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": bookingID, "status": model.BookingPending},
+		bson.M{"$set": bson.M{
+			"status":      model.BookingBooked,
+			"payment_ref": paymentRef,
+			"booked_at":   now,
+			"updated_at":  now,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
 func (r *BookingRepo) MarkFailed(ctx context.Context, bookingID primitive.ObjectID) error {
 	now := time.Now()
 	_, err := r.col.UpdateByID(ctx, bookingID, bson.M{
@@ -58,6 +86,255 @@ func (r *BookingRepo) MarkFailed(ctx context.Context, bookingID primitive.Object
 	return err
 }
 
+func (r *BookingRepo) FindByID(ctx context.Context, bookingID primitive.ObjectID) (*model.Booking, error) {
+	var out model.Booking
+	if err := r.col.FindOne(ctx, bson.M{"_id": bookingID}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkCancelled flips a BOOKED booking to CANCELLED and returns it.
+// mongo.ErrNoDocuments if it is not (or no longer) BOOKED.
+func (r *BookingRepo) MarkCancelled(ctx context.Context, bookingID primitive.ObjectID, reason string) (*model.Booking, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.Booking
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": bookingID, "status": model.BookingBooked},
+		bson.M{"$set": bson.M{
+			"status":        model.BookingCancelled,
+			"cancelled_at":  now,
+			"cancel_reason": reason,
+			"updated_at":    now,
+		}},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateBooked inserts a booking that is BOOKED from the start (seats
+// received by transfer, nothing to pay).
+func (r *BookingRepo) CreateBooked(ctx context.Context, b *model.Booking) error {
+	if b == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	b.Status = model.BookingBooked
+	b.BookedAt = &now
+	b.CreatedAt = now
+	b.UpdatedAt = now
+
+	_, err := r.col.InsertOne(ctx, b)
+	return err
+}
+
+// RemoveSeats takes seats out of a BOOKED booking that still has all of
+// them for transferID and bumps its ticket version; a booking left without
+// seats becomes TRANSFERRED. mongo.ErrNoDocuments if the booking doesn't
+// qualify (or transferID already took its seats: see TransferIDs).
+func (r *BookingRepo) RemoveSeats(ctx context.Context, bookingID primitive.ObjectID, seatIDs []string, transferID primitive.ObjectID) (*model.Booking, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.Booking
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":          bookingID,
+			"status":       model.BookingBooked,
+			"seat_ids":     bson.M{"$all": seatIDs},
+			"transfer_ids": bson.M{"$ne": transferID},
+		},
+		bson.M{
+			"$pull":     bson.M{"seat_ids": bson.M{"$in": seatIDs}},
+			"$addToSet": bson.M{"transfer_ids": transferID},
+			"$inc":      bson.M{"ticket_version": 1},
+			"$set":      bson.M{"updated_at": now},
+		},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out.SeatIDs) == 0 {
+		_, err = r.col.UpdateOne(ctx,
+			bson.M{"_id": bookingID, "status": model.BookingBooked, "seat_ids": bson.M{"$size": 0}},
+			bson.M{"$set": bson.M{"status": model.BookingTransferred, "updated_at": now}},
+		)
+		out.Status = model.BookingTransferred
+	}
+	return &out, err
+}
+
+// RestoreSeats puts seats back into a booking (undo of RemoveSeats for
+// transferID; a no-op once undone).
+func (r *BookingRepo) RestoreSeats(ctx context.Context, bookingID primitive.ObjectID, seatIDs []string, transferID primitive.ObjectID) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": bookingID, "transfer_ids": transferID},
+		bson.M{
+			"$addToSet": bson.M{"seat_ids": bson.M{"$each": seatIDs}},
+			"$pull":     bson.M{"transfer_ids": transferID},
+			"$set":      bson.M{"status": model.BookingBooked, "updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// ApplyExchange replaces the seats of a BOOKED booking that still has
+// exactly oldSeats, records the exchange and bumps the ticket version.
+// mongo.ErrNoDocuments if the booking changed in between.
+func (r *BookingRepo) ApplyExchange(
+	ctx context.Context,
+	bookingID primitive.ObjectID,
+	oldSeats, newSeats []string,
+	amount int64,
+	pricing *model.PriceBreakdown,
+	ex model.BookingExchange,
+) (*model.Booking, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.Booking
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": bookingID, "status": model.BookingBooked, "seat_ids": oldSeats},
+		bson.M{
+			"$set": bson.M{
+				"seat_ids":   newSeats,
+				"amount":     amount,
+				"pricing":    pricing,
+				"updated_at": time.Now(),
+			},
+			"$push": bson.M{"exchanges": ex},
+			"$inc":  bson.M{"ticket_version": 1},
+		},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindToRefund lists bookings of a cancelled showtime not refunded yet:
+// BOOKED, TRANSFERRED (paid for seats now held by others) and those already
+// cancelled for the showtime. Ordered by id, starting after afterID.
+func (r *BookingRepo) FindToRefund(ctx context.Context, showtimeID string, afterID primitive.ObjectID, limit int64) ([]model.Booking, error) {
+	q := refundQuery(showtimeID)
+	q["_id"] = bson.M{"$gt": afterID}
+
+	cur, err := r.col.Find(ctx, q, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Booking, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CountToRefund counts what FindToRefund would return from the start.
+func (r *BookingRepo) CountToRefund(ctx context.Context, showtimeID string) (int64, error) {
+	return r.col.CountDocuments(ctx, refundQuery(showtimeID))
+}
+
+func refundQuery(showtimeID string) bson.M {
+	return bson.M{
+		"showtime_id": showtimeID,
+		"refunded_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"status": bson.M{"$in": []model.BookingStatus{model.BookingBooked, model.BookingTransferred}}},
+			{"status": model.BookingCancelled, "cancel_reason": model.CancelByShowtime},
+		},
+	}
+}
+
+// MarkRefunded records the refund of a booking ("" = nothing to refund).
+func (r *BookingRepo) MarkRefunded(ctx context.Context, bookingID primitive.ObjectID, refundRef string) error {
+	now := time.Now()
+	set := bson.M{"refunded_at": now, "updated_at": now}
+	if refundRef != "" {
+		set["refund_ref"] = refundRef
+	}
+	_, err := r.col.UpdateByID(ctx, bookingID, bson.M{"$set": set})
+	return err
+}
+
+// FindBookedByShowtime lists the BOOKED bookings of a showtime (seat
+// repair compares their seats with the seatbooked: keys).
+func (r *BookingRepo) FindBookedByShowtime(ctx context.Context, showtimeID string) ([]model.Booking, error) {
+	cur, err := r.col.Find(ctx, bson.M{"showtime_id": showtimeID, "status": model.BookingBooked})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Booking, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FindBookedShowtimeIDs lists the showtimes that have BOOKED bookings (a
+// seat state rebuild finds unmanaged showtimes this way).
+func (r *BookingRepo) FindBookedShowtimeIDs(ctx context.Context) ([]string, error) {
+	vals, err := r.col.Distinct(ctx, "showtime_id", bson.M{"status": model.BookingBooked})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if id, ok := v.(string); ok && id != "" {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// FindPendingBefore lists PENDING bookings of a showtime created before t.
+func (r *BookingRepo) FindPendingBefore(ctx context.Context, showtimeID string, t time.Time) ([]model.Booking, error) {
+	cur, err := r.col.Find(ctx, bson.M{
+		"showtime_id": showtimeID,
+		"status":      model.BookingPending,
+		"created_at":  bson.M{"$lt": t},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Booking, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *BookingRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Booking, error) {
+	out := make([]model.Booking, 0, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	cur, err := r.col.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ===== Admin query =====
 type AdminBookingFilter struct {
 	ShowtimeID string
@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConcessionRepo struct {
+	items        *mongo.Collection
+	reservations *mongo.Collection
+}
+
+func NewConcessionRepo(db *mongo.Database) *ConcessionRepo {
+	return &ConcessionRepo{
+		items:        db.Collection("concession_items"),
+		reservations: db.Collection("concession_reservations"),
+	}
+}
+
+// EnsureIndexes creates the catalog/reservation indexes (idempotent).
+func (r *ConcessionRepo) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.items.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "cinema_id", Value: 1}, {Key: "name", Value: 1}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.reservations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "showtime_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "request_id", Value: 1}}},
+		{Keys: bson.D{{Key: "booking_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "pickup_code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	})
+	return err
+}
+
+// ===== Catalog =====
+
+func (r *ConcessionRepo) CreateItem(ctx context.Context, it *model.ConcessionItem) error {
+	if it == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	it.ID = primitive.NewObjectID()
+	it.CreatedAt = now
+	it.UpdatedAt = now
+
+	_, err := r.items.InsertOne(ctx, it)
+	return err
+}
+
+// UpdateItem applies $set fields to an item and returns it.
+func (r *ConcessionRepo) UpdateItem(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.ConcessionItem, error) {
+	set["updated_at"] = time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.ConcessionItem
+	if err := r.items.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindCatalog lists a cinema's items by name (active only unless all).
+func (r *ConcessionRepo) FindCatalog(ctx context.Context, cinemaID string, all bool) ([]model.ConcessionItem, error) {
+	q := bson.M{"cinema_id": cinemaID}
+	if !all {
+		q["active"] = true
+	}
+
+	cur, err := r.items.Find(ctx, q, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.ConcessionItem, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *ConcessionRepo) FindItems(ctx context.Context, ids []primitive.ObjectID) ([]model.ConcessionItem, error) {
+	cur, err := r.items.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.ConcessionItem, 0, len(ids))
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TakeStock decrements an active item's stock by qty if enough is left
+// (atomic conditional $inc).
+func (r *ConcessionRepo) TakeStock(ctx context.Context, itemID primitive.ObjectID, qty int64) (bool, error) {
+	res, err := r.items.UpdateOne(ctx,
+		bson.M{"_id": itemID, "active": true, "stock": bson.M{"$gte": qty}},
+		bson.M{"$inc": bson.M{"stock": -qty}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *ConcessionRepo) ReturnStock(ctx context.Context, itemID primitive.ObjectID, qty int64) error {
+	_, err := r.items.UpdateOne(ctx, bson.M{"_id": itemID}, bson.M{"$inc": bson.M{"stock": qty}})
+	return err
+}
+
+// ===== Reservations =====
+
+func (r *ConcessionRepo) InsertReservation(ctx context.Context, res *model.ConcessionReservation) error {
+	if res == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	res.ID = primitive.NewObjectID()
+	res.Status = model.ConcessionHeld
+	res.CreatedAt = now
+	res.UpdatedAt = now
+
+	_, err := r.reservations.InsertOne(ctx, res)
+	return err
+}
+
+// FindHeld returns the HELD reservation of a seat hold.
+func (r *ConcessionRepo) FindHeld(ctx context.Context, showtimeID, userID, requestID string) (*model.ConcessionReservation, error) {
+	var out model.ConcessionReservation
+	err := r.reservations.FindOne(ctx, bson.M{
+		"showtime_id": showtimeID,
+		"user_id":     userID,
+		"request_id":  requestID,
+		"status":      model.ConcessionHeld,
+	}).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindByBooking returns the reservation paid with bookingID.
+func (r *ConcessionRepo) FindByBooking(ctx context.Context, bookingID primitive.ObjectID) (*model.ConcessionReservation, error) {
+	var out model.ConcessionReservation
+	if err := r.reservations.FindOne(ctx, bson.M{"booking_id": bookingID}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindHeldBatch lists HELD reservations, oldest hold end first.
+func (r *ConcessionRepo) FindHeldBatch(ctx context.Context, limit int64) ([]model.ConcessionReservation, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "expires_at", Value: 1}}).
+		SetLimit(limit)
+
+	cur, err := r.reservations.Find(ctx, bson.M{"status": model.ConcessionHeld}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.ConcessionReservation, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Transition moves a reservation from one status to another, setting extra
+// fields. False if it was not in from (someone else moved it first).
+func (r *ConcessionRepo) Transition(ctx context.Context, id primitive.ObjectID, from, to model.ConcessionResStatus, set bson.M) (bool, error) {
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = to
+	set["updated_at"] = time.Now()
+
+	res, err := r.reservations.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// TouchHeld records the current end of a still-live hold.
+func (r *ConcessionRepo) TouchHeld(ctx context.Context, id primitive.ObjectID, expiresAt time.Time) error {
+	_, err := r.reservations.UpdateOne(ctx,
+		bson.M{"_id": id, "status": model.ConcessionHeld},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// PickUp marks a paid reservation as collected and returns it.
+func (r *ConcessionRepo) PickUp(ctx context.Context, code string) (*model.ConcessionReservation, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.ConcessionReservation
+	err := r.reservations.FindOneAndUpdate(ctx,
+		bson.M{"pickup_code": code, "status": model.ConcessionConsumed},
+		bson.M{"$set": bson.M{"status": model.ConcessionPickedUp, "updated_at": now}},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
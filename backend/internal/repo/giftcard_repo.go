@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GiftCardRepo struct {
+	cards  *mongo.Collection
+	ledger *mongo.Collection
+}
+
+func NewGiftCardRepo(db *mongo.Database) *GiftCardRepo {
+	return &GiftCardRepo{
+		cards:  db.Collection("gift_cards"),
+		ledger: db.Collection("gift_card_ledger"),
+	}
+}
+
+// EnsureIndexes creates the code and ledger indexes (idempotent).
+func (r *GiftCardRepo) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.cards.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	_, err := r.ledger.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "card_id", Value: 1}, {Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// one redemption + one refund per booking
+			Keys: bson.D{{Key: "booking_id", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"booking_id": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// CreateCard inserts a card; mongo.IsDuplicateKeyError if the code exists.
+func (r *GiftCardRepo) CreateCard(ctx context.Context, g *model.GiftCard) error {
+	if g == nil {
+		return mongo.ErrNilDocument
+	}
+	g.ID = primitive.NewObjectID()
+	g.CreatedAt = time.Now()
+
+	_, err := r.cards.InsertOne(ctx, g)
+	return err
+}
+
+// DeleteCard removes a card that never got its ISSUE entry.
+func (r *GiftCardRepo) DeleteCard(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.cards.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *GiftCardRepo) FindByCode(ctx context.Context, code string) (*model.GiftCard, error) {
+	var out model.GiftCard
+	if err := r.cards.FindOne(ctx, bson.M{"code": code}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GiftCardRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.GiftCard, error) {
+	var out model.GiftCard
+	if err := r.cards.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GiftCardRepo) SetActive(ctx context.Context, id primitive.ObjectID, active bool) (*model.GiftCard, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.GiftCard
+	if err := r.cards.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": active}}, opts).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LastEntry returns the card's latest ledger entry (its current balance).
+func (r *GiftCardRepo) LastEntry(ctx context.Context, cardID primitive.ObjectID) (*model.GiftCardEntry, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+
+	var out model.GiftCardEntry
+	if err := r.ledger.FindOne(ctx, bson.M{"card_id": cardID}, opts).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AppendEntry inserts a ledger entry; mongo.IsDuplicateKeyError if its seq
+// (someone appended first) or its booking/kind already exists.
+func (r *GiftCardRepo) AppendEntry(ctx context.Context, e *model.GiftCardEntry) error {
+	if e == nil {
+		return mongo.ErrNilDocument
+	}
+	e.ID = primitive.NewObjectID()
+	e.At = time.Now()
+
+	_, err := r.ledger.InsertOne(ctx, e)
+	return err
+}
+
+func (r *GiftCardRepo) FindBookingEntry(ctx context.Context, bookingID primitive.ObjectID, kind model.GiftCardEntryKind) (*model.GiftCardEntry, error) {
+	var out model.GiftCardEntry
+	if err := r.ledger.FindOne(ctx, bson.M{"booking_id": bookingID, "kind": kind}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindEntries lists a card's ledger, oldest first.
+func (r *GiftCardRepo) FindEntries(ctx context.Context, cardID primitive.ObjectID) ([]model.GiftCardEntry, error) {
+	cur, err := r.ledger.Find(ctx, bson.M{"card_id": cardID}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.GiftCardEntry, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoyaltyRepo struct {
+	accounts *mongo.Collection
+	ledger   *mongo.Collection
+}
+
+func NewLoyaltyRepo(db *mongo.Database) *LoyaltyRepo {
+	return &LoyaltyRepo{
+		accounts: db.Collection("loyalty_accounts"),
+		ledger:   db.Collection("loyalty_ledger"),
+	}
+}
+
+// EnsureIndexes creates the ledger indexes (idempotent).
+func (r *LoyaltyRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.ledger.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "booking_id", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "at", Value: -1}}},
+	})
+	return err
+}
+
+func (r *LoyaltyRepo) FindAccount(ctx context.Context, userID string) (*model.LoyaltyAccount, error) {
+	var out model.LoyaltyAccount
+	if err := r.accounts.FindOne(ctx, bson.M{"_id": userID}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Apply adds to the balance and lifetime points (creating the account) and
+// returns the account after the change.
+func (r *LoyaltyRepo) Apply(ctx context.Context, userID string, points, lifetime int64) (*model.LoyaltyAccount, error) {
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var out model.LoyaltyAccount
+	err := r.accounts.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$inc":         bson.M{"points": points, "lifetime": lifetime},
+			"$set":         bson.M{"updated_at": time.Now()},
+			"$setOnInsert": bson.M{"tier": model.TierMember},
+		},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Spend takes points from the balance if enough are left (atomic).
+func (r *LoyaltyRepo) Spend(ctx context.Context, userID string, points int64) (bool, error) {
+	res, err := r.accounts.UpdateOne(ctx,
+		bson.M{"_id": userID, "points": bson.M{"$gte": points}},
+		bson.M{
+			"$inc": bson.M{"points": -points},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *LoyaltyRepo) SetTier(ctx context.Context, userID string, tier model.LoyaltyTier) error {
+	_, err := r.accounts.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"tier": tier}})
+	return err
+}
+
+// InsertEntry appends to the ledger; mongo.IsDuplicateKeyError if the
+// booking already has an entry of that kind.
+func (r *LoyaltyRepo) InsertEntry(ctx context.Context, e *model.LoyaltyEntry) error {
+	if e == nil {
+		return mongo.ErrNilDocument
+	}
+	e.ID = primitive.NewObjectID()
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	_, err := r.ledger.InsertOne(ctx, e)
+	return err
+}
+
+func (r *LoyaltyRepo) FindEntry(ctx context.Context, bookingID primitive.ObjectID, kind model.LoyaltyEntryKind) (*model.LoyaltyEntry, error) {
+	var out model.LoyaltyEntry
+	if err := r.ledger.FindOne(ctx, bson.M{"booking_id": bookingID, "kind": kind}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindEntries lists a user's latest ledger entries.
+func (r *LoyaltyRepo) FindEntries(ctx context.Context, userID string, limit int64) ([]model.LoyaltyEntry, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}}).
+		SetLimit(limit)
+
+	cur, err := r.ledger.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.LoyaltyEntry, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,248 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PromoRepo struct {
+	promos      *mongo.Collection
+	redemptions *mongo.Collection
+	usage       *mongo.Collection // per-user redemption counters
+}
+
+func NewPromoRepo(db *mongo.Database) *PromoRepo {
+	return &PromoRepo{
+		promos:      db.Collection("promos"),
+		redemptions: db.Collection("promo_redemptions"),
+		usage:       db.Collection("promo_usage"),
+	}
+}
+
+// EnsureIndexes creates the unique code index (idempotent).
+func (r *PromoRepo) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.promos.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	_, err := r.redemptions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "booking_id", Value: 1}},
+	})
+	return err
+}
+
+// Create inserts a promo; mongo.IsDuplicateKeyError if the code exists.
+func (r *PromoRepo) Create(ctx context.Context, p *model.Promo) error {
+	if p == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	p.ID = primitive.NewObjectID()
+	p.Redeemed = 0
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	_, err := r.promos.InsertOne(ctx, p)
+	return err
+}
+
+func (r *PromoRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Promo, error) {
+	var out model.Promo
+	if err := r.promos.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *PromoRepo) FindByCode(ctx context.Context, code string) (*model.Promo, error) {
+	var out model.Promo
+	if err := r.promos.FindOne(ctx, bson.M{"code": code}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Update applies $set fields to a promo and returns it.
+func (r *PromoRepo) Update(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.Promo, error) {
+	set["updated_at"] = time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.Promo
+	if err := r.promos.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ===== Admin query =====
+type AdminPromoFilter struct {
+	Active    bool
+	HasActive bool
+
+	Limit int64
+	Skip  int64
+}
+
+func (r *PromoRepo) FindAdmin(ctx context.Context, f AdminPromoFilter) ([]model.Promo, int64, error) {
+	q := bson.M{}
+	if f.HasActive {
+		q["active"] = f.Active
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	skip := f.Skip
+	if skip < 0 {
+		skip = 0
+	}
+
+	total, err := r.promos.CountDocuments(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(skip)
+
+	cur, err := r.promos.Find(ctx, q, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Promo, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// ===== Redemption counters =====
+
+// ReserveGlobal takes one redemption of an active promo if its global limit
+// allows it (atomic conditional $inc).
+func (r *PromoRepo) ReserveGlobal(ctx context.Context, promoID primitive.ObjectID) (bool, error) {
+	res, err := r.promos.UpdateOne(ctx,
+		bson.M{
+			"_id":    promoID,
+			"active": true,
+			"$or": bson.A{
+				bson.M{"max_redemptions": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$redeemed", "$max_redemptions"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"redeemed": 1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *PromoRepo) ReleaseGlobal(ctx context.Context, promoID primitive.ObjectID) error {
+	_, err := r.promos.UpdateOne(ctx,
+		bson.M{"_id": promoID, "redeemed": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"redeemed": -1}},
+	)
+	return err
+}
+
+func usageID(promoID primitive.ObjectID, userID string) string {
+	return promoID.Hex() + ":" + userID
+}
+
+// ReserveUser takes one of the user's redemptions (limit 0 = unlimited).
+// Conditional upsert: once the counter reached the limit the filter misses
+// and the insert collides on _id, so the limit cannot be overrun.
+func (r *PromoRepo) ReserveUser(ctx context.Context, promoID primitive.ObjectID, userID string, limit int64) (bool, error) {
+	filter := bson.M{"_id": usageID(promoID, userID)}
+	if limit > 0 {
+		filter["count"] = bson.M{"$lt": limit}
+	}
+
+	inc := bson.M{"$inc": bson.M{"count": 1}}
+	_, err := r.usage.UpdateOne(ctx, filter, inc, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// either at the limit, or a concurrent first redemption won the insert
+		res, err := r.usage.UpdateOne(ctx, filter, inc)
+		if err != nil {
+			return false, err
+		}
+		return res.ModifiedCount == 1, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *PromoRepo) ReleaseUser(ctx context.Context, promoID primitive.ObjectID, userID string) error {
+	_, err := r.usage.UpdateOne(ctx,
+		bson.M{"_id": usageID(promoID, userID), "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
+
+func (r *PromoRepo) InsertRedemption(ctx context.Context, red *model.PromoRedemption) error {
+	if red == nil {
+		return mongo.ErrNilDocument
+	}
+	red.ID = primitive.NewObjectID()
+	_, err := r.redemptions.InsertOne(ctx, red)
+	return err
+}
+
+// ClaimReversal takes the booking's active redemption for reversing, unless
+// another reversal claimed it after staleBefore; mongo.ErrNoDocuments if
+// there is none to take.
+func (r *PromoRepo) ClaimReversal(ctx context.Context, bookingID primitive.ObjectID, staleBefore time.Time) (*model.PromoRedemption, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.PromoRedemption
+	err := r.redemptions.FindOneAndUpdate(ctx,
+		bson.M{
+			"booking_id": bookingID,
+			"status":     model.RedemptionRedeemed,
+			"$or": bson.A{
+				bson.M{"reversing_at": bson.M{"$exists": false}},
+				bson.M{"reversing_at": bson.M{"$lt": staleBefore}},
+			},
+		},
+		bson.M{"$set": bson.M{"reversing_at": time.Now()}},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkReleased records that one of the redemption's counters was given
+// back (field "global_released" or "user_released").
+func (r *PromoRepo) MarkReleased(ctx context.Context, id primitive.ObjectID, field string) error {
+	_, err := r.redemptions.UpdateByID(ctx, id, bson.M{"$set": bson.M{field: true}})
+	return err
+}
+
+// FinishReversal marks a redemption REVERSED.
+func (r *PromoRepo) FinishReversal(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.redemptions.UpdateOne(ctx,
+		bson.M{"_id": id, "status": model.RedemptionRedeemed},
+		bson.M{"$set": bson.M{"status": model.RedemptionReversed, "reversed_at": time.Now()}},
+	)
+	return err
+}
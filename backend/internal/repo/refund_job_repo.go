@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefundJobRepo struct {
+	col *mongo.Collection
+}
+
+func NewRefundJobRepo(db *mongo.Database) *RefundJobRepo {
+	return &RefundJobRepo{col: db.Collection("refund_jobs")}
+}
+
+// EnsureIndexes creates the one-job-per-showtime index (idempotent).
+func (r *RefundJobRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "showtime_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create inserts a RUNNING job; mongo.IsDuplicateKeyError if the showtime
+// already has one.
+func (r *RefundJobRepo) Create(ctx context.Context, j *model.RefundJob) error {
+	if j == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	j.ID = primitive.NewObjectID()
+	j.Status = model.RefundJobRunning
+	j.CreatedAt = now
+	j.UpdatedAt = now
+
+	_, err := r.col.InsertOne(ctx, j)
+	return err
+}
+
+func (r *RefundJobRepo) FindByShowtime(ctx context.Context, showtimeID string) (*model.RefundJob, error) {
+	var out model.RefundJob
+	if err := r.col.FindOne(ctx, bson.M{"showtime_id": showtimeID}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *RefundJobRepo) FindRunning(ctx context.Context) ([]model.RefundJob, error) {
+	cur, err := r.col.Find(ctx, bson.M{"status": model.RefundJobRunning}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.RefundJob, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Progress records one refunded booking; a failure it had is dropped.
+func (r *RefundJobRepo) Progress(ctx context.Context, id primitive.ObjectID, bookingID string, amount int64, hadFailure bool) error {
+	update := bson.M{
+		"$inc": bson.M{"processed": 1, "refunded_amount": amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	if hadFailure {
+		update["$inc"].(bson.M)["failed"] = -1
+		update["$unset"] = bson.M{"failures." + bookingID: ""}
+	}
+	_, err := r.col.UpdateByID(ctx, id, update)
+	return err
+}
+
+// RecordFailure stores the failure of one booking; failed counts bookings,
+// so it only goes up on the first one.
+func (r *RefundJobRepo) RecordFailure(ctx context.Context, id primitive.ObjectID, bookingID string, f model.RefundFailure) error {
+	update := bson.M{"$set": bson.M{
+		"failures." + bookingID: f,
+		"last_error":            bookingID + ": " + f.LastError,
+		"updated_at":            time.Now(),
+	}}
+	if f.Attempts == 1 {
+		update["$inc"] = bson.M{"failed": 1}
+	}
+	_, err := r.col.UpdateByID(ctx, id, update)
+	return err
+}
+
+// Finish ends a RUNNING job as status (DONE or STUCK).
+func (r *RefundJobRepo) Finish(ctx context.Context, id primitive.ObjectID, status model.RefundJobStatus) error {
+	now := time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "status": model.RefundJobRunning},
+		bson.M{"$set": bson.M{"status": status, "finished_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// Retry puts a STUCK job back to RUNNING with its failures cleared.
+func (r *RefundJobRepo) Retry(ctx context.Context, showtimeID string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"showtime_id": showtimeID, "status": model.RefundJobStuck},
+		bson.M{
+			"$set":   bson.M{"status": model.RefundJobRunning, "failed": 0, "updated_at": time.Now()},
+			"$unset": bson.M{"failures": "", "finished_at": ""},
+		},
+	)
+	return err
+}
@@ -0,0 +1,265 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ShowtimeRepo struct {
+	col *mongo.Collection
+}
+
+func NewShowtimeRepo(db *mongo.Database) *ShowtimeRepo {
+	return &ShowtimeRepo{col: db.Collection("showtimes")}
+}
+
+// EnsureIndexes creates the listing and scheduler indexes (idempotent).
+func (r *ShowtimeRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "hall", Value: 1}, {Key: "ends_at", Value: 1}}},
+	})
+	return err
+}
+
+// Create inserts a showtime; mongo.IsDuplicateKeyError if the id exists.
+func (r *ShowtimeRepo) Create(ctx context.Context, s *model.Showtime) error {
+	if s == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+
+	_, err := r.col.InsertOne(ctx, s)
+	return err
+}
+
+func (r *ShowtimeRepo) FindByID(ctx context.Context, id string) (*model.Showtime, error) {
+	var out model.Showtime
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Update sets fields of a showtime whose status is one of statuses.
+// mongo.ErrNoDocuments if there is no such showtime.
+func (r *ShowtimeRepo) Update(ctx context.Context, id string, statuses []model.ShowtimeStatus, set bson.M) (*model.Showtime, error) {
+	set["updated_at"] = time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out model.Showtime
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": statuses}},
+		bson.M{"$set": set},
+		opts,
+	).Decode(&out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Transition moves a showtime from one status to another, setting extra
+// fields. False if it was not in from.
+func (r *ShowtimeRepo) Transition(ctx context.Context, id string, from, to model.ShowtimeStatus, set bson.M) (bool, error) {
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = to
+	set["updated_at"] = time.Now()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// FindDue lists showtimes the scheduler has work for at now: sales to
+// close, screenings that started, and ended ones whose keys are still there.
+func (r *ShowtimeRepo) FindDue(ctx context.Context, now time.Time) ([]model.Showtime, error) {
+	q := bson.M{"$or": []bson.M{
+		{"status": model.ShowtimeOnSale, "sales_close_at": bson.M{"$lte": now}},
+		{"status": bson.M{"$in": []model.ShowtimeStatus{model.ShowtimeOnSale, model.ShowtimeSalesClosed}}, "starts_at": bson.M{"$lte": now}},
+		{"status": bson.M{"$in": []model.ShowtimeStatus{model.ShowtimeStarted, model.ShowtimeCancelled}}, "ends_at": bson.M{"$lte": now}, "cleaned_at": bson.M{"$exists": false}},
+	}}
+	cur, err := r.col.Find(ctx, q, options.Find().SetLimit(200))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Showtime, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *ShowtimeRepo) MarkCleaned(ctx context.Context, id string) error {
+	now := time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"cleaned_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+type ShowtimeFilter struct {
+	MovieID  string
+	Statuses []model.ShowtimeStatus
+	From     *time.Time // starts_at >=
+	To       *time.Time // starts_at <
+
+	Limit int64
+	Skip  int64
+}
+
+// Find lists showtimes by start time.
+func (r *ShowtimeRepo) Find(ctx context.Context, f ShowtimeFilter) ([]model.Showtime, int64, error) {
+	q := bson.M{}
+	if f.MovieID != "" {
+		q["movie_id"] = f.MovieID
+	}
+	if len(f.Statuses) > 0 {
+		q["status"] = bson.M{"$in": f.Statuses}
+	}
+	if f.From != nil || f.To != nil {
+		tr := bson.M{}
+		if f.From != nil {
+			tr["$gte"] = *f.From
+		}
+		if f.To != nil {
+			tr["$lt"] = *f.To
+		}
+		q["starts_at"] = tr
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	skip := f.Skip
+	if skip < 0 {
+		skip = 0
+	}
+
+	total, err := r.col.CountDocuments(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "starts_at", Value: 1}}).
+		SetLimit(limit).
+		SetSkip(skip)
+
+	cur, err := r.col.Find(ctx, q, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.Showtime, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// FindHallIDs lists the ids of the showtimes in hall that haven't ended at
+// now (cancelled ones excluded).
+func (r *ShowtimeRepo) FindHallIDs(ctx context.Context, hall string, now time.Time) ([]string, error) {
+	cur, err := r.col.Find(ctx,
+		bson.M{
+			"hall":    hall,
+			"ends_at": bson.M{"$gt": now},
+			"status":  bson.M{"$ne": model.ShowtimeCancelled},
+		},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.ID)
+	}
+	return out, nil
+}
+
+// FindActiveIDs lists the ids of published showtimes that haven't ended at
+// now (the reconciler checks their seats).
+func (r *ShowtimeRepo) FindActiveIDs(ctx context.Context, now time.Time) ([]string, error) {
+	cur, err := r.col.Find(ctx,
+		bson.M{
+			"status": bson.M{"$in": []model.ShowtimeStatus{
+				model.ShowtimeOnSale,
+				model.ShowtimeSalesClosed,
+				model.ShowtimeStarted,
+			}},
+			"ends_at": bson.M{"$gt": now},
+		},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.ID)
+	}
+	return out, nil
+}
+
+// FindExistingIDs returns which of ids have a showtime document.
+func (r *ShowtimeRepo) FindExistingIDs(ctx context.Context, ids []string) (map[string]bool, error) {
+	out := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	cur, err := r.col.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		out[d.ID] = true
+	}
+	return out, nil
+}
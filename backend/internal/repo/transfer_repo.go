@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"cinema/internal/model"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TransferRepo struct {
+	col *mongo.Collection
+}
+
+func NewTransferRepo(db *mongo.Database) *TransferRepo {
+	return &TransferRepo{col: db.Collection("ticket_transfers")}
+}
+
+// EnsureIndexes creates the recipient inbox and recovery indexes (idempotent).
+func (r *TransferRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "to_email", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+	return err
+}
+
+func (r *TransferRepo) Create(ctx context.Context, t *model.TicketTransfer) error {
+	if t == nil {
+		return mongo.ErrNilDocument
+	}
+
+	now := time.Now()
+	t.ID = primitive.NewObjectID()
+	t.Status = model.TransferPending
+	t.CreatedAt = now
+	t.UpdatedAt = now
+
+	_, err := r.col.InsertOne(ctx, t)
+	return err
+}
+
+func (r *TransferRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.TicketTransfer, error) {
+	var out model.TicketTransfer
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindPendingForEmail lists unexpired PENDING transfers addressed to email.
+func (r *TransferRepo) FindPendingForEmail(ctx context.Context, email string) ([]model.TicketTransfer, error) {
+	q := bson.M{
+		"to_email":   email,
+		"status":     model.TransferPending,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	cur, err := r.col.Find(ctx, q, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(50))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.TicketTransfer, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Transition moves a transfer from one status to another, setting extra
+// fields. False if it was not in from.
+func (r *TransferRepo) Transition(ctx context.Context, id primitive.ObjectID, from, to model.TransferStatus, set bson.M) (bool, error) {
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = to
+	set["updated_at"] = time.Now()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// FindStaleAccepting lists ACCEPTING transfers not touched since before
+// (their acceptance was interrupted).
+func (r *TransferRepo) FindStaleAccepting(ctx context.Context, before time.Time, limit int64) ([]model.TicketTransfer, error) {
+	q := bson.M{
+		"status":     model.TransferAccepting,
+		"updated_at": bson.M{"$lt": before},
+	}
+	cur, err := r.col.Find(ctx, q, options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]model.TicketTransfer, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
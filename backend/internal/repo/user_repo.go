@@ -3,6 +3,7 @@ package repo
 import (
 	"cinema/internal/model"
 	"context"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -83,3 +84,13 @@ func (r *UserRepo) FindByID(ctx context.Context, idHex string) (*model.User, err
 
 	return &out, nil
 }
+
+// find user by email (case-insensitive: stored as given by Google)
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	var out model.User
+	q := bson.M{"email": bson.M{"$regex": "^" + regexp.QuoteMeta(email) + "$", "$options": "i"}}
+	if err := r.col.FindOne(ctx, q).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
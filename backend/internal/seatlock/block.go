@@ -0,0 +1,345 @@
+package seatlock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Admin seat blocks (house holds, broken seats, press)
+// =====================
+
+// Blocks live in two hashes, seat id -> SeatBlock JSON:
+// seatblock:<showtimeId> for one showtime, hallblock:<hall> for every
+// showtime in a hall. A blocked seat can't be locked (see luaLockAll) and is
+// not free; lifting the block puts it back on sale.
+
+func blockKey(showtimeID string) string {
+	return fmt.Sprintf("seatblock:%s", showtimeID)
+}
+
+func hallBlockKey(hall string) string {
+	return fmt.Sprintf("hallblock:%s", hall)
+}
+
+// HallLookup returns the hall of a showtime ("" = none / unknown).
+type HallLookup func(ctx context.Context, showtimeID string) (string, error)
+
+// WithHalls makes hall-wide blocks apply to the showtimes of that hall.
+func (s *Service) WithHalls(fn HallLookup) *Service {
+	s.halls = fn
+	return s
+}
+
+// SeatBlock is one blocked seat.
+type SeatBlock struct {
+	SeatID string `json:"seat_id"`
+	Hall   string `json:"hall,omitempty"` // set for hall-wide blocks
+	Reason string `json:"reason"`
+	By     string `json:"by"` // admin user id
+	At     int64  `json:"at"` // unix seconds
+}
+
+// blockKeys returns the block hashes that apply to showtimeID: its own and,
+// if it is in a hall, the hall's.
+func (s *Service) blockKeys(ctx context.Context, showtimeID string) ([]string, error) {
+	keys := []string{blockKey(showtimeID)}
+	if s.halls == nil {
+		return keys, nil
+	}
+	hall, err := s.halls(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	if hall != "" {
+		keys = append(keys, hallBlockKey(hall))
+	}
+	return keys, nil
+}
+
+// blockedSeats returns which of seatIDs are blocked for showtimeID.
+func (s *Service) blockedSeats(ctx context.Context, showtimeID string, seatIDs []string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	if len(seatIDs) == 0 {
+		return out, nil
+	}
+	keys, err := s.blockKeys(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		vals, err := s.rdb.HMGet(ctx, k, seatIDs...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			if v != nil {
+				out[seatIDs[i]] = true
+			}
+		}
+	}
+	return out, nil
+}
+
+// KEYS: [1..n] lock keys, [n+1..2n] booked keys, [2n+1] block hash
+// ARGV: n, block JSON per seat
+// only free seats can be blocked; a repeated block updates the reason
+var luaBlock = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local hk = KEYS[2*n+1]
+
+for i=1,n do
+  if redis.call("EXISTS", KEYS[n+i]) == 1 then
+    return {0, KEYS[n+i], "booked"}
+  end
+  if redis.call("EXISTS", KEYS[i]) == 1 then
+    return {0, KEYS[i], "locked"}
+  end
+end
+
+for i=1,n do
+  local sid = string.match(KEYS[i], "([^:]+)$")
+  redis.call("HSET", hk, sid, ARGV[1+i])
+end
+return {1, "", ""}
+`)
+
+// BlockSeats blocks free seats of one showtime. All or nothing: if a seat
+// is locked or booked, ok=false and conflicted/reason ("locked", "booked")
+// say which.
+func (s *Service) BlockSeats(
+	ctx context.Context,
+	showtimeID string,
+	seatIDs []string,
+	reason, by string,
+) (ok bool, conflictedSeatID string, state string, err error) {
+	if len(seatIDs) == 0 {
+		return false, "", "invalid_seat_ids", fmt.Errorf("seatIDs required")
+	}
+
+	now := time.Now().Unix()
+	keys := make([]string, 0, len(seatIDs)*2+1)
+	args := make([]any, 0, len(seatIDs)+1)
+	args = append(args, len(seatIDs))
+	for _, sid := range seatIDs {
+		keys = append(keys, key(showtimeID, sid))
+		b, _ := json.Marshal(SeatBlock{SeatID: sid, Reason: reason, By: by, At: now})
+		args = append(args, b)
+	}
+	for _, sid := range seatIDs {
+		keys = append(keys, bookedKey(showtimeID, sid))
+	}
+	keys = append(keys, blockKey(showtimeID))
+
+	res, err := luaBlock.Run(ctx, s.rdb, keys, args...).Result()
+	if err != nil {
+		return false, "", "redis_failed", err
+	}
+	arr, okArr := res.([]any)
+	if !okArr || len(arr) < 3 {
+		return false, "", "unexpected_lua_result", fmt.Errorf("unexpected lua result: %T", res)
+	}
+	if okInt, _ := arr[0].(int64); okInt != 1 {
+		confKey, _ := arr[1].(string)
+		state, _ = arr[2].(string)
+		return false, confKey[strings.LastIndex(confKey, ":")+1:], state, nil
+	}
+
+	s.publish(ctx, SeatEvent{
+		Type:       "blocked",
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Owner:      by,
+		At:         now,
+	})
+	return true, "", "", nil
+}
+
+// UnblockSeats lifts showtime blocks of seatIDs. Seats that are still
+// blocked for the hall stay off sale. Returns the seats back on sale.
+func (s *Service) UnblockSeats(ctx context.Context, showtimeID string, seatIDs []string, by string) ([]string, error) {
+	removed, err := s.hdelSeats(ctx, blockKey(showtimeID), seatIDs)
+	if err != nil {
+		return nil, err
+	}
+	still, err := s.blockedSeats(ctx, showtimeID, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	freed := make([]string, 0, len(removed))
+	for _, sid := range removed {
+		if !still[sid] {
+			freed = append(freed, sid)
+		}
+	}
+	s.publishUnblocked(ctx, showtimeID, freed, by)
+	return freed, nil
+}
+
+// BlockHallSeats blocks seatIDs for every showtime in hall. Seats already
+// locked or booked for a showtime keep that hold; the block applies to the
+// next lock. showtimeIDs (upcoming showtimes of the hall) get a "blocked"
+// event.
+func (s *Service) BlockHallSeats(ctx context.Context, hall string, seatIDs []string, reason, by string, showtimeIDs []string) error {
+	if hall == "" || len(seatIDs) == 0 {
+		return fmt.Errorf("hall/seatIDs required")
+	}
+
+	now := time.Now().Unix()
+	vals := make([]any, 0, len(seatIDs)*2)
+	for _, sid := range seatIDs {
+		b, _ := json.Marshal(SeatBlock{SeatID: sid, Hall: hall, Reason: reason, By: by, At: now})
+		vals = append(vals, sid, b)
+	}
+	if err := s.rdb.HSet(ctx, hallBlockKey(hall), vals...).Err(); err != nil {
+		return err
+	}
+
+	for _, id := range showtimeIDs {
+		s.publish(ctx, SeatEvent{
+			Type:       "blocked",
+			ShowtimeID: id,
+			SeatIDs:    seatIDs,
+			Owner:      by,
+			At:         now,
+		})
+	}
+	return nil
+}
+
+// UnblockHallSeats lifts hall blocks of seatIDs. Returns the seats removed;
+// showtimeIDs get an "unblocked" event for those not blocked per showtime.
+func (s *Service) UnblockHallSeats(ctx context.Context, hall string, seatIDs []string, by string, showtimeIDs []string) ([]string, error) {
+	removed, err := s.hdelSeats(ctx, hallBlockKey(hall), seatIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(removed) == 0 {
+		return removed, nil
+	}
+
+	for _, id := range showtimeIDs {
+		vals, err := s.rdb.HMGet(ctx, blockKey(id), removed...).Result()
+		if err != nil {
+			continue
+		}
+		freed := make([]string, 0, len(removed))
+		for i, v := range vals {
+			if v == nil {
+				freed = append(freed, removed[i])
+			}
+		}
+		s.publishUnblocked(ctx, id, freed, by)
+	}
+	return removed, nil
+}
+
+// hdelSeats removes seatIDs from the hash hk and returns those that were there.
+func (s *Service) hdelSeats(ctx context.Context, hk string, seatIDs []string) ([]string, error) {
+	if len(seatIDs) == 0 {
+		return nil, fmt.Errorf("seatIDs required")
+	}
+
+	pipe := s.rdb.TxPipeline()
+	cmds := make([]*redis.IntCmd, len(seatIDs))
+	for i, sid := range seatIDs {
+		cmds[i] = pipe.HDel(ctx, hk, sid)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0, len(seatIDs))
+	for i, sid := range seatIDs {
+		if cmds[i].Val() == 1 {
+			removed = append(removed, sid)
+		}
+	}
+	return removed, nil
+}
+
+func (s *Service) publishUnblocked(ctx context.Context, showtimeID string, seatIDs []string, by string) {
+	if len(seatIDs) == 0 {
+		return
+	}
+	s.publish(ctx, SeatEvent{
+		Type:       "unblocked",
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Owner:      by,
+		At:         time.Now().Unix(),
+	})
+}
+
+// Blocks lists the blocks in force for showtimeID (its own and its hall's),
+// sorted by seat. A seat blocked both ways is listed once, as a showtime block.
+func (s *Service) Blocks(ctx context.Context, showtimeID string) ([]SeatBlock, error) {
+	keys, err := s.blockKeys(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	out := make([]SeatBlock, 0)
+	for _, k := range keys {
+		blocks, err := s.readBlocks(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blocks {
+			if !seen[b.SeatID] {
+				seen[b.SeatID] = true
+				out = append(out, b)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SeatID < out[j].SeatID })
+	return out, nil
+}
+
+// HallBlocks lists the hall-wide blocks of hall, sorted by seat.
+func (s *Service) HallBlocks(ctx context.Context, hall string) ([]SeatBlock, error) {
+	out, err := s.readBlocks(ctx, hallBlockKey(hall))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SeatID < out[j].SeatID })
+	return out, nil
+}
+
+func (s *Service) readBlocks(ctx context.Context, hk string) ([]SeatBlock, error) {
+	m, err := s.rdb.HGetAll(ctx, hk).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SeatBlock, 0, len(m))
+	for sid, raw := range m {
+		var b SeatBlock
+		if err := json.Unmarshal([]byte(raw), &b); err != nil {
+			b = SeatBlock{Reason: raw}
+		}
+		b.SeatID = sid
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// BlockedSeatIDs returns the ids of the seats blocked for showtimeID, sorted.
+func (s *Service) BlockedSeatIDs(ctx context.Context, showtimeID string) ([]string, error) {
+	blocks, err := s.Blocks(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		out = append(out, b.SeatID)
+	}
+	return out, nil
+}
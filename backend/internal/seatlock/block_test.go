@@ -0,0 +1,86 @@
+package seatlock
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBlockSeats(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	rdb.Set(ctx, bookedKey("st1", "A2"), "b1", 0)
+
+	// only free seats, all or nothing
+	for sid, want := range map[string]string{"A1": "locked", "A2": "booked"} {
+		ok, conflicted, state, err := svc.BlockSeats(ctx, "st1", []string{"A3", sid}, "press", "admin")
+		if err != nil || ok || conflicted != sid || state != want {
+			t.Fatalf("BlockSeats(%s) = %v, %q, %q, %v; want %s", sid, ok, conflicted, state, err, want)
+		}
+	}
+	if ids, _ := svc.BlockedSeatIDs(ctx, "st1"); len(ids) != 0 {
+		t.Fatalf("blocked after refused blocks: %v", ids)
+	}
+
+	if ok, _, _, err := svc.BlockSeats(ctx, "st1", []string{"A3", "A4"}, "press", "admin"); err != nil || !ok {
+		t.Fatalf("BlockSeats = %v, %v", ok, err)
+	}
+
+	// the lock script refuses blocked seats
+	ok, conflicted, err := svc.LockSeats(ctx, "st1", []string{"A5", "A4"}, "u2", "r2")
+	if err != nil || ok || conflicted != "A4" {
+		t.Fatalf("LockSeats on a blocked seat = %v, %q, %v", ok, conflicted, err)
+	}
+	if free, _ := svc.FreeSeats(ctx, "st1", []string{"A3", "A5"}); free["A3"] || !free["A5"] {
+		t.Fatalf("FreeSeats = %v", free)
+	}
+
+	freed, err := svc.UnblockSeats(ctx, "st1", []string{"A4", "A9"}, "admin")
+	if err != nil || !reflect.DeepEqual(freed, []string{"A4"}) {
+		t.Fatalf("UnblockSeats = %v, %v", freed, err)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A4"}, "u2", "r2"); err != nil || !ok {
+		t.Fatalf("lock after unblock: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHallBlocks(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	halls := map[string]string{"st1": "h1", "st2": "h1", "st3": "h2"}
+	svc := New(rdb, time.Minute).WithHalls(func(_ context.Context, id string) (string, error) {
+		return halls[id], nil
+	})
+
+	if err := svc.BlockHallSeats(ctx, "h1", []string{"C1", "C2"}, "broken", "admin", []string{"st1", "st2"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range []string{"st1", "st2"} {
+		if ok, _, err := svc.LockSeats(ctx, st, []string{"C1"}, "u1", "r1"); err != nil || ok {
+			t.Fatalf("lock %s C1: ok=%v err=%v; want refused by the hall block", st, ok, err)
+		}
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st3", []string{"C1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock in another hall: ok=%v err=%v", ok, err)
+	}
+
+	// a showtime block outlives the lifted hall block
+	if ok, _, _, err := svc.BlockSeats(ctx, "st1", []string{"C2"}, "press", "admin"); err != nil || !ok {
+		t.Fatalf("BlockSeats = %v, %v", ok, err)
+	}
+	if removed, err := svc.UnblockHallSeats(ctx, "h1", []string{"C1", "C2"}, "admin", []string{"st1", "st2"}); err != nil || len(removed) != 2 {
+		t.Fatalf("UnblockHallSeats = %v, %v", removed, err)
+	}
+	if ids, _ := svc.BlockedSeatIDs(ctx, "st1"); !reflect.DeepEqual(ids, []string{"C2"}) {
+		t.Fatalf("st1 blocked = %v, want its own block", ids)
+	}
+	if ids, _ := svc.BlockedSeatIDs(ctx, "st2"); len(ids) != 0 {
+		t.Fatalf("st2 blocked = %v", ids)
+	}
+}
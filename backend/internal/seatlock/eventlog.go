@@ -0,0 +1,144 @@
+package seatlock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Sequenced seat events + replay log
+// =====================
+
+// keep the last N events per showtime for ?since= resync
+const eventLogSize = 500
+
+func seqKey(showtimeID string) string {
+	return fmt.Sprintf("seatseq:%s", showtimeID)
+}
+
+func eventLogKey(showtimeID string) string {
+	return fmt.Sprintf("seatlog:%s", showtimeID)
+}
+
+// assign seq, append to log, publish - in one step so subscribers always
+// receive events in seq order.
+// ARGV[2] is the event JSON without "seq"; seq is spliced in as first field.
+var luaPublishSeq = redis.NewScript(`
+local ch = ARGV[1]
+local body = ARGV[2]
+local maxLen = tonumber(ARGV[3])
+
+local seq = redis.call("INCR", KEYS[1])
+local payload = '{"seq":' .. seq .. ',' .. string.sub(body, 2)
+
+redis.call("ZADD", KEYS[2], seq, payload)
+redis.call("ZREMRANGEBYRANK", KEYS[2], 0, -(maxLen + 1))
+redis.call("PUBLISH", ch, payload)
+
+return seq
+`)
+
+func publishSeatEvent(ctx context.Context, rdb *redis.Client, ev SeatEvent) {
+	ev.Seq = 0
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	keys := []string{seqKey(ev.ShowtimeID), eventLogKey(ev.ShowtimeID)}
+	_ = luaPublishSeq.Run(ctx, rdb, keys, channel(ev.ShowtimeID), b, eventLogSize).Err()
+}
+
+// EventSeq extracts "seq" from a published seat event payload (0 if absent).
+func EventSeq(payload []byte) int64 {
+	var v struct {
+		Seq int64 `json:"seq"`
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return 0
+	}
+	return v.Seq
+}
+
+// CurrentSeq returns the seq of the last event published for showtimeID.
+func (s *Service) CurrentSeq(ctx context.Context, showtimeID string) (int64, error) {
+	n, err := s.rdb.Get(ctx, seqKey(showtimeID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Snapshot is the full seat state of a showtime as of Seq: every event with
+// seq > Seq happened after (or concurrently with) the read.
+type Snapshot struct {
+	Seq     int64      `json:"seq"`
+	Locks   []LockInfo `json:"locks"`
+	Booked  []string   `json:"booked"`
+	Blocked []string   `json:"blocked"`
+}
+
+func (s *Service) Snapshot(ctx context.Context, showtimeID string) (*Snapshot, error) {
+	// read seq first: events racing with the scan are re-applied by the client (idempotent)
+	seq, err := s.CurrentSeq(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := s.ListLocks(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	booked, err := s.ListBookedSeats(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.BlockedSeatIDs(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Seq: seq, Locks: locks, Booked: booked, Blocked: blocked}, nil
+}
+
+// EventsSince returns payloads with seq > since, oldest first.
+// ok=false means the log no longer covers since (too old, or the counter was
+// reset) and the caller must send a fresh snapshot instead.
+func (s *Service) EventsSince(ctx context.Context, showtimeID string, since int64) (events [][]byte, ok bool, err error) {
+	cur, err := s.CurrentSeq(ctx, showtimeID)
+	if err != nil {
+		return nil, false, err
+	}
+	if since < 0 || since > cur {
+		return nil, false, nil
+	}
+	if since == cur {
+		return nil, true, nil
+	}
+
+	zs, err := s.rdb.ZRangeByScoreWithScores(ctx, eventLogKey(showtimeID), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(since, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// gap between since and the oldest kept event
+	if len(zs) == 0 || int64(zs[0].Score) != since+1 {
+		return nil, false, nil
+	}
+
+	out := make([][]byte, 0, len(zs))
+	for _, z := range zs {
+		m, _ := z.Member.(string)
+		out = append(out, []byte(m))
+	}
+	return out, true, nil
+}
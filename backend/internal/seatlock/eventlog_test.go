@@ -0,0 +1,95 @@
+package seatlock
+
+import (
+	"context"
+	"testing"
+)
+
+// publishN publishes n "locked" events for showtimeID.
+func publishN(t *testing.T, s *Service, showtimeID string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		publishSeatEvent(context.Background(), s.rdb, SeatEvent{Type: "locked", ShowtimeID: showtimeID, SeatIDs: []string{"A1"}})
+	}
+}
+
+func TestPublishSeatEventSequence(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	s := New(rdb, 0)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	publishN(t, s, "st1", 3)
+	publishN(t, s, "st2", 1)
+
+	got := events()
+	if len(got) != 3 {
+		t.Fatalf("events = %+v", got)
+	}
+	for i, ev := range got {
+		if ev.Seq != int64(i+1) {
+			t.Fatalf("event %d seq = %d", i, ev.Seq)
+		}
+	}
+	if cur, err := s.CurrentSeq(ctx, "st1"); err != nil || cur != 3 {
+		t.Fatalf("CurrentSeq = %d, %v", cur, err)
+	}
+	if cur, _ := s.CurrentSeq(ctx, "st2"); cur != 1 {
+		t.Fatalf("st2 CurrentSeq = %d, want its own counter", cur)
+	}
+}
+
+func TestEventsSince(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	s := New(rdb, 0)
+	publishN(t, s, "st1", 5)
+
+	tests := []struct {
+		name   string
+		since  int64
+		wantOK bool
+		want   []int64
+	}{
+		{name: "up to date", since: 5, wantOK: true},
+		{name: "replay the tail", since: 2, wantOK: true, want: []int64{3, 4, 5}},
+		{name: "replay everything", since: 0, wantOK: true, want: []int64{1, 2, 3, 4, 5}},
+		{name: "ahead of the counter (reset)", since: 9},
+		{name: "negative", since: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok, err := s.EventsSince(ctx, "st1", tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || len(events) != len(tt.want) {
+				t.Fatalf("EventsSince(%d) = %d events, ok=%v", tt.since, len(events), ok)
+			}
+			for i, p := range events {
+				if seq := EventSeq(p); seq != tt.want[i] {
+					t.Fatalf("event %d seq = %d, want %d", i, seq, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEventsSinceTrimmedLog(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	s := New(rdb, 0)
+	publishN(t, s, "st1", eventLogSize+3)
+
+	if n, _ := rdb.ZCard(ctx, eventLogKey("st1")).Result(); n != eventLogSize {
+		t.Fatalf("log size = %d, want %d", n, eventLogSize)
+	}
+	// seq 1..3 were dropped: replay from 1 can't be complete
+	if _, ok, err := s.EventsSince(ctx, "st1", 1); err != nil || ok {
+		t.Fatalf("EventsSince(1) ok=%v err=%v, want a snapshot", ok, err)
+	}
+	events, ok, err := s.EventsSince(ctx, "st1", 3)
+	if err != nil || !ok || len(events) != eventLogSize {
+		t.Fatalf("EventsSince(3) = %d events ok=%v err=%v", len(events), ok, err)
+	}
+}
@@ -0,0 +1,158 @@
+package seatlock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Seat exchange: move a booking to other seats
+// =====================
+
+// KEYS layout: [1..a] lock keys of the new seats, [a+1..2a] their booked
+// keys, [2a+1..] booked keys of the seats given up
+// ARGV: expected lock value ("" = don't require locks), booking id, a
+var luaExchangeBooked = redis.NewScript(`
+local expected = ARGV[1]
+local bookingId = ARGV[2]
+local a = tonumber(ARGV[3])
+
+for i=1,a do
+  if redis.call("EXISTS", KEYS[a+i]) == 1 then
+    return {0, KEYS[a+i], "already_booked"}
+  end
+  if expected ~= "" then
+    local v = redis.call("GET", KEYS[i])
+    if (not v) then
+      return {0, KEYS[i], "missing_lock"}
+    end
+    if v ~= expected then
+      return {0, KEYS[i], "not_owner"}
+    end
+  end
+end
+
+for i=2*a+1,#KEYS do
+  if redis.call("GET", KEYS[i]) ~= bookingId then
+    return {0, KEYS[i], "not_booked"}
+  end
+end
+
+for i=1,a do
+  redis.call("SET", KEYS[a+i], bookingId)
+  if expected ~= "" then
+    redis.call("DEL", KEYS[i])
+  end
+end
+for i=2*a+1,#KEYS do
+  redis.call("DEL", KEYS[i])
+end
+
+return {1, "", ""}
+`)
+
+// ExchangeBooked atomically books newSeats (locked by owner:requestID) for
+// bookingID and frees oldSeats, which must be booked by it. Seats in both
+// lists are not touched. All or nothing; on failure conflicted/reason say
+// why (already_booked, missing_lock, not_owner, not_booked).
+func (s *Service) ExchangeBooked(
+	ctx context.Context,
+	showtimeID string,
+	newSeats, oldSeats []string,
+	owner, requestID, bookingID string,
+) (ok bool, conflictedSeatID string, reason string, err error) {
+	if len(newSeats) == 0 || len(oldSeats) == 0 {
+		return false, "", "invalid_seat_ids", fmt.Errorf("seatIDs required")
+	}
+	if owner == "" || requestID == "" || bookingID == "" {
+		return false, "", "invalid_args", fmt.Errorf("owner/requestID/bookingID required")
+	}
+
+	ok, conflictedSeatID, reason, err = s.swapBooked(ctx, showtimeID, newSeats, oldSeats, owner+":"+requestID, bookingID)
+	if err != nil || !ok {
+		return ok, conflictedSeatID, reason, err
+	}
+
+	zk := expZKey(showtimeID)
+	pipe := s.rdb.Pipeline()
+	for _, sid := range newSeats {
+		pipe.ZRem(ctx, zk, expMember(sid, owner, requestID), warnMember(sid, owner, requestID))
+	}
+	_, _ = pipe.Exec(ctx)
+
+	s.publishExchange(ctx, showtimeID, newSeats, oldSeats, owner, requestID, bookingID)
+	return true, "", "", nil
+}
+
+// RevertExchange undoes ExchangeBooked: oldSeats are booked for bookingID
+// again and newSeats freed. Fails (ok=false) if an old seat was booked by
+// someone else in between.
+func (s *Service) RevertExchange(
+	ctx context.Context,
+	showtimeID string,
+	newSeats, oldSeats []string,
+	owner, bookingID string,
+) (ok bool, err error) {
+	ok, _, _, err = s.swapBooked(ctx, showtimeID, oldSeats, newSeats, "", bookingID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.publishExchange(ctx, showtimeID, oldSeats, newSeats, owner, "", bookingID)
+	return true, nil
+}
+
+func (s *Service) swapBooked(ctx context.Context, showtimeID string, add, remove []string, expected, bookingID string) (bool, string, string, error) {
+	keys := make([]string, 0, len(add)*2+len(remove))
+	for _, sid := range add {
+		keys = append(keys, key(showtimeID, sid))
+	}
+	for _, sid := range add {
+		keys = append(keys, bookedKey(showtimeID, sid))
+	}
+	for _, sid := range remove {
+		keys = append(keys, bookedKey(showtimeID, sid))
+	}
+
+	res, err := luaExchangeBooked.Run(ctx, s.rdb, keys, expected, bookingID, len(add)).Result()
+	if err != nil {
+		return false, "", "redis_failed", err
+	}
+	arr, okArr := res.([]any)
+	if !okArr || len(arr) < 3 {
+		return false, "", "unexpected_lua_result", fmt.Errorf("unexpected lua result: %T", res)
+	}
+	if okInt, _ := arr[0].(int64); okInt == 1 {
+		return true, "", "", nil
+	}
+
+	confKey, _ := arr[1].(string)
+	reason, _ := arr[2].(string)
+	parts := strings.Split(confKey, ":")
+	return false, parts[len(parts)-1], reason, nil
+}
+
+// new seats are "booked", the old ones "released", both for the booking
+func (s *Service) publishExchange(ctx context.Context, showtimeID string, booked, released []string, owner, requestID, bookingID string) {
+	now := time.Now().Unix()
+	s.publish(ctx, SeatEvent{
+		Type:       "booked",
+		ShowtimeID: showtimeID,
+		SeatIDs:    booked,
+		Owner:      owner,
+		RequestID:  requestID,
+		BookingID:  bookingID,
+		At:         now,
+	})
+	s.publish(ctx, SeatEvent{
+		Type:       "released",
+		ShowtimeID: showtimeID,
+		SeatIDs:    released,
+		Owner:      owner,
+		BookingID:  bookingID,
+		At:         now,
+	})
+}
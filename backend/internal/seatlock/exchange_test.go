@@ -0,0 +1,71 @@
+package seatlock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExchangeBooked(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+	rdb.Set(ctx, bookedKey("st1", "A1"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "A2"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "C1"), "b2", 0)
+
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"B1", "B2"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+
+	tests := []struct {
+		name       string
+		newSeats   []string
+		oldSeats   []string
+		requestID  string
+		conflicted string
+		reason     string
+	}{
+		{name: "new seat booked", newSeats: []string{"B1", "C1"}, oldSeats: []string{"A1"}, requestID: "r1", conflicted: "C1", reason: "already_booked"},
+		{name: "new seat not locked", newSeats: []string{"B3"}, oldSeats: []string{"A1"}, requestID: "r1", conflicted: "B3", reason: "missing_lock"},
+		{name: "other hold", newSeats: []string{"B1"}, oldSeats: []string{"A1"}, requestID: "r2", conflicted: "B1", reason: "not_owner"},
+		{name: "old seat not ours", newSeats: []string{"B1"}, oldSeats: []string{"C1"}, requestID: "r1", conflicted: "C1", reason: "not_booked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, conflicted, reason, err := svc.ExchangeBooked(ctx, "st1", tt.newSeats, tt.oldSeats, "u1", tt.requestID, "b1")
+			if err != nil || ok || conflicted != tt.conflicted || reason != tt.reason {
+				t.Fatalf("ExchangeBooked = %v, %q, %q, %v; want %q on %s", ok, conflicted, reason, err, tt.reason, tt.conflicted)
+			}
+		})
+	}
+	if v, _ := rdb.Get(ctx, key("st1", "B1")).Result(); v != "u1:r1" {
+		t.Fatalf("B1 lock = %q after refused exchanges", v)
+	}
+
+	ok, _, _, err := svc.ExchangeBooked(ctx, "st1", []string{"B1", "B2"}, []string{"A1", "A2"}, "u1", "r1", "b1")
+	if err != nil || !ok {
+		t.Fatalf("ExchangeBooked = %v, %v", ok, err)
+	}
+	booked := func(sid string) string {
+		v, _ := rdb.Get(ctx, bookedKey("st1", sid)).Result()
+		return v
+	}
+	if booked("B1") != "b1" || booked("B2") != "b1" || booked("A1") != "" || booked("A2") != "" {
+		t.Fatal("seats not swapped")
+	}
+	if n, _ := rdb.Exists(ctx, key("st1", "B1"), key("st1", "B2")).Result(); n != 0 {
+		t.Fatalf("%d locks left on the new seats", n)
+	}
+	if n, _ := rdb.ZCard(ctx, expZKey("st1")).Result(); n != 0 {
+		t.Fatalf("%d expiry entries left", n)
+	}
+
+	// payment for the difference failed: back to the old seats
+	if ok, err := svc.RevertExchange(ctx, "st1", []string{"B1", "B2"}, []string{"A1", "A2"}, "u1", "b1"); err != nil || !ok {
+		t.Fatalf("RevertExchange = %v, %v", ok, err)
+	}
+	if booked("A1") != "b1" || booked("B1") != "" {
+		t.Fatal("exchange not reverted")
+	}
+}
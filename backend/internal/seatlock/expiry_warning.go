@@ -0,0 +1,134 @@
+package seatlock
+
+import (
+	"cinema/internal/notify"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Hold expiry warnings ("expiring_soon")
+// =====================
+
+// A second schedule type in seatlockexp:<showtimeId>:
+//   timeout member: "A1|<owner>|<rid>"       due at lock expiry
+//   warning member: "A1|<owner>|<rid>|warn"  due warnBefore earlier
+// Both share the "A1|<owner>|" prefix, so release/confirm cleanup covers them.
+
+const warnSuffix = "|warn"
+
+func warnMember(seatID, owner, rid string) string {
+	return expMember(seatID, owner, rid) + warnSuffix
+}
+
+// wake-up key for SEAT_EXPIRY_MODE=notify (its expiry event triggers the
+// warning pass); the ZSET stays the source of truth.
+func warnWakeKey(showtimeID, rid string) string {
+	return fmt.Sprintf("seatlockwarn:%s:%s", showtimeID, rid)
+}
+
+// parseScheduled parses either member type.
+func parseScheduled(m string) (seatID, owner, rid string, warn bool, ok bool) {
+	if base, found := strings.CutSuffix(m, warnSuffix); found {
+		seatID, owner, rid, ok = parseMember(base)
+		return seatID, owner, rid, true, ok
+	}
+	seatID, owner, rid, ok = parseMember(m)
+	return seatID, owner, rid, false, ok
+}
+
+// scheduleWarnings adds warning members for a fresh (or renewed) hold.
+func (s *Service) scheduleWarnings(ctx context.Context, pipe redis.Pipeliner, showtimeID string, seatIDs []string, owner, rid string, ttl time.Duration, expireMs int64) {
+	if s.warnBefore <= 0 || s.warnBefore >= ttl {
+		return
+	}
+
+	warnAt := expireMs - s.warnBefore.Milliseconds()
+	zk := expZKey(showtimeID)
+	for _, sid := range seatIDs {
+		pipe.ZAdd(ctx, zk, redis.Z{Score: float64(warnAt), Member: warnMember(sid, owner, rid)})
+	}
+	pipe.Set(ctx, warnWakeKey(showtimeID, rid), owner, ttl-s.warnBefore)
+}
+
+// warnBatch groups due warning members per hold (owner+rid) so a hold of
+// several seats produces one notification.
+type warnBatch struct {
+	showtimeID string
+	holds      map[string]*notify.UserEvent
+}
+
+func newWarnBatch(showtimeID string) *warnBatch {
+	return &warnBatch{showtimeID: showtimeID, holds: make(map[string]*notify.UserEvent)}
+}
+
+// add checks one due warning member; the hold must still be live.
+func (b *warnBatch) add(ctx context.Context, rdb *redis.Client, seatID, owner, rid string) {
+	lockK := key(b.showtimeID, seatID)
+
+	v, err := rdb.Get(ctx, lockK).Result()
+	if err != nil || v != owner+":"+rid {
+		// released, booked, expired or re-owned: nothing to warn about
+		return
+	}
+	ttl, err := rdb.PTTL(ctx, lockK).Result()
+	if err != nil || ttl <= 0 {
+		return
+	}
+
+	k := owner + "|" + rid
+	ev, ok := b.holds[k]
+	if !ok {
+		ev = &notify.UserEvent{
+			Type:       notify.HoldExpiringSoon,
+			UserID:     owner,
+			ShowtimeID: b.showtimeID,
+			RequestID:  rid,
+		}
+		b.holds[k] = ev
+	}
+	ev.SeatIDs = append(ev.SeatIDs, seatID)
+	if exp := time.Now().Add(ttl).Unix(); exp > ev.ExpiresAt {
+		ev.ExpiresAt = exp
+	}
+}
+
+func (b *warnBatch) flush(ctx context.Context, rdb *redis.Client) {
+	for _, ev := range b.holds {
+		sort.Strings(ev.SeatIDs)
+		notify.Publish(ctx, rdb, *ev)
+	}
+	b.holds = make(map[string]*notify.UserEvent)
+}
+
+// handleDueWarnings claims (ZREM) every due warning member of a showtime.
+// Used by the notify-mode listener; the sweep handles them inline.
+func handleDueWarnings(ctx context.Context, rdb *redis.Client, showtimeID string) {
+	zk := expZKey(showtimeID)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	members, err := rdb.ZRangeByScore(ctx, zk, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return
+	}
+
+	batch := newWarnBatch(showtimeID)
+	for _, m := range members {
+		seatID, owner, rid, warn, ok := parseScheduled(m)
+		if !ok || !warn {
+			continue
+		}
+		// ZREM decides the winner across replicas
+		if n, err := rdb.ZRem(ctx, zk, m).Result(); err != nil || n == 0 {
+			continue
+		}
+		batch.add(ctx, rdb, seatID, owner, rid)
+	}
+	batch.flush(ctx, rdb)
+}
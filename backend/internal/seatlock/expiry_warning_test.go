@@ -0,0 +1,132 @@
+package seatlock
+
+import (
+	"cinema/internal/notify"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseScheduled(t *testing.T) {
+	tests := []struct {
+		member string
+		seat   string
+		rid    string
+		warn   bool
+		ok     bool
+	}{
+		{member: "A1|u1|r1", seat: "A1", rid: "r1", ok: true},
+		{member: "A1|u1|r1|warn", seat: "A1", rid: "r1", warn: true, ok: true},
+		{member: "A1|u1", ok: false},
+		{member: "A1|u1|warn", warn: true, ok: false},
+	}
+	for _, tt := range tests {
+		seat, _, rid, warn, ok := parseScheduled(tt.member)
+		if ok != tt.ok || warn != tt.warn || (ok && (seat != tt.seat || rid != tt.rid)) {
+			t.Errorf("parseScheduled(%q) = %q %q warn=%v ok=%v", tt.member, seat, rid, warn, ok)
+		}
+	}
+}
+
+// watchUserEvents collects the private events of userID.
+func watchUserEvents(t *testing.T, rdb *redis.Client, userID string) func() []notify.UserEvent {
+	t.Helper()
+	ps := rdb.Subscribe(context.Background(), notify.Channel(userID))
+	if _, err := ps.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ps.Close() })
+
+	ch := ps.Channel()
+	return func() []notify.UserEvent {
+		var out []notify.UserEvent
+		for {
+			select {
+			case msg := <-ch:
+				var ev notify.UserEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, ev)
+			case <-time.After(100 * time.Millisecond):
+				return out
+			}
+		}
+	}
+}
+
+func TestExpiryWarningOncePerHold(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	s := New(rdb, time.Minute).WithExpiryWarning(20 * time.Second)
+	events := watchUserEvents(t, rdb, "u1")
+
+	if ok, _, err := s.LockSeats(ctx, "st1", []string{"A2", "A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+
+	zk := expZKey("st1")
+	warnAt, err := rdb.ZScore(ctx, zk, warnMember("A1", "u1", "r1")).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expireAt, _ := rdb.ZScore(ctx, zk, expMember("A1", "u1", "r1")).Result()
+	if expireAt-warnAt != float64((20 * time.Second).Milliseconds()) {
+		t.Fatalf("warning scheduled %vms before expiry", expireAt-warnAt)
+	}
+
+	// make the warnings due
+	for _, sid := range []string{"A1", "A2"} {
+		rdb.ZAdd(ctx, zk, redis.Z{Score: 1, Member: warnMember(sid, "u1", "r1")})
+	}
+	handleDueWarnings(ctx, rdb, "st1")
+	handleDueWarnings(ctx, rdb, "st1") // another replica: nothing left to claim
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("events = %+v, want one warning", got)
+	}
+	if ev := got[0]; ev.Type != notify.HoldExpiringSoon || ev.RequestID != "r1" || !reflect.DeepEqual(ev.SeatIDs, []string{"A1", "A2"}) || ev.ExpiresAt == 0 {
+		t.Fatalf("warning = %+v", ev)
+	}
+	// the timeouts stay scheduled
+	if n, _ := rdb.ZCard(ctx, zk).Result(); n != 2 {
+		t.Fatalf("%d members left, want the 2 timeouts", n)
+	}
+}
+
+func TestExpiryWarningSkipsReleasedHold(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	s := New(rdb, time.Minute).WithExpiryWarning(20 * time.Second)
+	events := watchUserEvents(t, rdb, "u1")
+
+	if ok, _, err := s.LockSeats(ctx, "st1", []string{"A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	rdb.Del(ctx, key("st1", "A1")) // gone without cleanup (expired early, flushed...)
+	rdb.ZAdd(ctx, expZKey("st1"), redis.Z{Score: 1, Member: warnMember("A1", "u1", "r1")})
+
+	handleDueWarnings(ctx, rdb, "st1")
+	if got := events(); len(got) != 0 {
+		t.Fatalf("events = %+v, want no warning for a dead hold", got)
+	}
+}
+
+func TestExpiryWarningOff(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	// an offset at least as long as the hold disables warnings
+	s := New(rdb, time.Minute).WithExpiryWarning(time.Minute)
+
+	if ok, _, err := s.LockSeats(ctx, "st1", []string{"A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	if _, err := rdb.ZScore(ctx, expZKey("st1"), warnMember("A1", "u1", "r1")).Result(); err != redis.Nil {
+		t.Fatalf("warning scheduled: err=%v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package seatlock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// =====================
+// Showtime cleanup (cancelled / ended)
+// =====================
+
+// ReleaseAllLocks deletes every seat lock of showtimeID (showtime
+// cancelled) and announces the seats as released, one event per hold.
+// Booked seats are not touched. Returns the number of seats released.
+func (s *Service) ReleaseAllLocks(ctx context.Context, showtimeID string) (int, error) {
+	pattern := fmt.Sprintf("seatlock:%s:*", showtimeID)
+
+	var released []LockInfo
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, 200).Result()
+		if err != nil {
+			return len(released), err
+		}
+		seatIDs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			seatIDs = append(seatIDs, k[strings.LastIndex(k, ":")+1:])
+		}
+		released = append(released, s.dropLocks(ctx, showtimeID, seatIDs)...)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	s.publishDropped(ctx, showtimeID, released)
+	return len(released), nil
+}
+
+// dropLocks deletes the locks on seatIDs whoever holds them, with their
+// expiry tracking. Seats not locked are skipped. Returns the locks removed.
+func (s *Service) dropLocks(ctx context.Context, showtimeID string, seatIDs []string) []LockInfo {
+	zk := expZKey(showtimeID)
+	out := make([]LockInfo, 0, len(seatIDs))
+	for _, sid := range seatIDs {
+		v, err := s.rdb.GetDel(ctx, key(showtimeID, sid)).Result()
+		if err != nil {
+			continue // not locked / expired meanwhile
+		}
+		owner, rid, _ := strings.Cut(v, ":")
+		_ = s.rdb.ZRem(ctx, zk, expMember(sid, owner, rid), warnMember(sid, owner, rid)).Err()
+		out = append(out, LockInfo{SeatID: sid, Owner: owner, RequestID: rid})
+	}
+	return out
+}
+
+// publishDropped announces dropped locks as released, one event per hold.
+func (s *Service) publishDropped(ctx context.Context, showtimeID string, locks []LockInfo) {
+	type hold struct{ owner, rid string }
+	byHold := make(map[hold][]string)
+	order := make([]hold, 0)
+	for _, l := range locks {
+		h := hold{l.Owner, l.RequestID}
+		if _, ok := byHold[h]; !ok {
+			order = append(order, h)
+		}
+		byHold[h] = append(byHold[h], l.SeatID)
+	}
+
+	now := time.Now().Unix()
+	for _, h := range order {
+		s.publish(ctx, SeatEvent{
+			Type:       "released",
+			ShowtimeID: showtimeID,
+			SeatIDs:    byHold[h],
+			Owner:      h.owner,
+			RequestID:  h.rid,
+			At:         now,
+		})
+	}
+}
+
+// PurgeShowtime deletes every seatlock:, seatbooked:, seatlockexp: and
+// seatblock: key of showtimeID. No seat events are published: nobody books an ended
+// showtime. Returns the number of keys deleted.
+func (s *Service) PurgeShowtime(ctx context.Context, showtimeID string) (int64, error) {
+	var deleted int64
+	for _, pattern := range []string{
+		fmt.Sprintf("seatlock:%s:*", showtimeID),
+		fmt.Sprintf("seatbooked:%s:*", showtimeID),
+	} {
+		var cursor uint64
+		for {
+			keys, next, err := s.rdb.Scan(ctx, cursor, pattern, 200).Result()
+			if err != nil {
+				return deleted, err
+			}
+			if len(keys) > 0 {
+				n, err := s.rdb.Del(ctx, keys...).Result()
+				if err != nil {
+					return deleted, err
+				}
+				deleted += n
+			}
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	n, err := s.rdb.Del(ctx, expZKey(showtimeID), blockKey(showtimeID)).Result()
+	if err != nil {
+		return deleted, err
+	}
+	return deleted + n, nil
+}
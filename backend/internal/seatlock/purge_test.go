@@ -0,0 +1,73 @@
+package seatlock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurgeShowtime(t *testing.T) {
+	ctx := context.Background()
+	rdb, mr := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+
+	for _, st := range []string{"st1", "st2"} {
+		if ok, _, err := svc.LockSeats(ctx, st, []string{"A1", "A2"}, "u1", "r1"); err != nil || !ok {
+			t.Fatalf("lock %s: ok=%v err=%v", st, ok, err)
+		}
+		rdb.Set(ctx, bookedKey(st, "B1"), "b1", 0)
+	}
+
+	n, err := svc.PurgeShowtime(ctx, "st1")
+	if err != nil || n != 4 {
+		t.Fatalf("PurgeShowtime = %d, %v; want 2 locks, 1 booked, the expiry set", n, err)
+	}
+	for _, k := range mr.Keys() {
+		if k == expZKey("st1") || k == bookedKey("st1", "B1") || k == key("st1", "A1") {
+			t.Fatalf("%s left after purge", k)
+		}
+	}
+	if !mr.Exists(key("st2", "A1")) || !mr.Exists(bookedKey("st2", "B1")) || !mr.Exists(expZKey("st2")) {
+		t.Fatalf("other showtime touched: keys = %v", mr.Keys())
+	}
+}
+
+func TestReleaseAllLocks(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A1", "A2"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"B1"}, "u2", "r2"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	rdb.Set(ctx, bookedKey("st1", "C1"), "b1", 0)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	n, err := svc.ReleaseAllLocks(ctx, "st1")
+	if err != nil || n != 3 {
+		t.Fatalf("ReleaseAllLocks = %d, %v", n, err)
+	}
+	if left, _ := rdb.Keys(ctx, "seatlock:st1:*").Result(); len(left) != 0 {
+		t.Fatalf("locks left = %v", left)
+	}
+	if zn, _ := rdb.ZCard(ctx, expZKey("st1")).Result(); zn != 0 {
+		t.Fatalf("%d expiry entries left", zn)
+	}
+	if v, _ := rdb.Get(ctx, bookedKey("st1", "C1")).Result(); v != "b1" {
+		t.Fatalf("booked seat touched: %q", v)
+	}
+
+	// one released event per hold
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("events = %+v, want one per hold", got)
+	}
+	for _, ev := range got {
+		if ev.Type != "released" || (ev.Owner == "u1") != (len(ev.SeatIDs) == 2) {
+			t.Fatalf("event = %+v", ev)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package seatlock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// =====================
+// Rebuild gate: no locks or confirms until seatbooked: is trusted
+// =====================
+
+// Redis keeps no data across restarts, so seatbooked: keys can vanish while
+// Mongo still has the BOOKED bookings. readyKey is written by a rebuild; as
+// long as it is missing (Redis lost its data, or never rebuilt) every
+// showtime is gated. A showtime in rebuildSetKey is gated until its markers
+// have been rebuilt. The lock and confirm scripts check both atomically.
+const (
+	readyKey      = "seatstate:ready"
+	rebuildSetKey = "seatrebuild:pending"
+)
+
+// ErrRebuilding is returned by lock and confirm while the showtime's booked
+// markers are being rebuilt from Mongo.
+var ErrRebuilding = errors.New("seat state is being rebuilt")
+
+// gate keys passed to the lock/confirm scripts (showtime id goes in ARGV)
+func gateKeys() []string {
+	return []string{readyKey, rebuildSetKey}
+}
+
+// Rebuilding reports whether lock/confirm are gated for showtimeID.
+func (s *Service) Rebuilding(ctx context.Context, showtimeID string) (bool, error) {
+	pipe := s.rdb.Pipeline()
+	ready := pipe.Exists(ctx, readyKey)
+	pending := pipe.SIsMember(ctx, rebuildSetKey, showtimeID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return ready.Val() == 0 || pending.Val(), nil
+}
+
+// StateReady reports whether a rebuild has run since Redis last lost its
+// data.
+func (s *Service) StateReady(ctx context.Context) (bool, error) {
+	n, err := s.rdb.Exists(ctx, readyKey).Result()
+	return n == 1, err
+}
+
+// BeginRebuild gates showtimeIDs and then, with markReady, lifts the global
+// gate: from here on only the listed showtimes wait for their rebuild.
+func (s *Service) BeginRebuild(ctx context.Context, showtimeIDs []string, markReady bool) error {
+	if len(showtimeIDs) > 0 {
+		members := make([]any, 0, len(showtimeIDs))
+		for _, id := range showtimeIDs {
+			members = append(members, id)
+		}
+		if err := s.rdb.SAdd(ctx, rebuildSetKey, members...).Err(); err != nil {
+			return err
+		}
+	}
+	if !markReady {
+		return nil
+	}
+	return s.rdb.Set(ctx, readyKey, time.Now().Unix(), 0).Err()
+}
+
+// EndRebuild lifts the gate of showtimeID.
+func (s *Service) EndRebuild(ctx context.Context, showtimeID string) error {
+	return s.rdb.SRem(ctx, rebuildSetKey, showtimeID).Err()
+}
+
+// PendingRebuilds lists the showtimes still gated by a rebuild.
+func (s *Service) PendingRebuilds(ctx context.Context) ([]string, error) {
+	return s.rdb.SMembers(ctx, rebuildSetKey).Result()
+}
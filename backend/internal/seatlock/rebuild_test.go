@@ -0,0 +1,53 @@
+package seatlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRebuildGate(t *testing.T) {
+	ctx := context.Background()
+	rdb, mr := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A1"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+
+	// Redis lost its data: every showtime is gated
+	mr.Del(readyKey)
+	if gated, err := svc.Rebuilding(ctx, "st2"); err != nil || !gated {
+		t.Fatalf("Rebuilding(st2) = %v, %v; want gated without the ready key", gated, err)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st2", []string{"A1"}, "u1", "r2"); ok || !errors.Is(err, ErrRebuilding) {
+		t.Fatalf("lock while not ready: ok=%v err=%v", ok, err)
+	}
+
+	// the rebuild gates st1 and opens the rest
+	if err := svc.BeginRebuild(ctx, []string{"st1"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st2", []string{"A1"}, "u1", "r2"); err != nil || !ok {
+		t.Fatalf("lock st2: ok=%v err=%v", ok, err)
+	}
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A2"}, "u1", "r1"); ok || !errors.Is(err, ErrRebuilding) {
+		t.Fatalf("lock on a gated showtime: ok=%v err=%v", ok, err)
+	}
+	// a missing marker proves nothing yet: no confirm either
+	ok, _, reason, err := svc.ConfirmSeatsBooked(ctx, "st1", []string{"A1"}, "u1", "r1", "b1")
+	if ok || reason != "rebuilding" || !errors.Is(err, ErrRebuilding) {
+		t.Fatalf("confirm on a gated showtime = %v, %q, %v", ok, reason, err)
+	}
+	if pending, _ := svc.PendingRebuilds(ctx); len(pending) != 1 || pending[0] != "st1" {
+		t.Fatalf("PendingRebuilds = %v", pending)
+	}
+
+	if err := svc.EndRebuild(ctx, "st1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _, err := svc.ConfirmSeatsBooked(ctx, "st1", []string{"A1"}, "u1", "r1", "b1"); err != nil || !ok {
+		t.Fatalf("confirm after the rebuild: ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,161 @@
+package seatlock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Support tools: force release, booked marker repair
+// =====================
+
+// ForceRelease deletes the locks on seatIDs whoever holds them and
+// announces the seats as released, one event per hold. Seats not locked are
+// skipped. Returns the locks removed.
+func (s *Service) ForceRelease(ctx context.Context, showtimeID string, seatIDs []string) ([]LockInfo, error) {
+	if len(seatIDs) == 0 {
+		return nil, fmt.Errorf("seatIDs required")
+	}
+	released := s.dropLocks(ctx, showtimeID, seatIDs)
+	s.publishDropped(ctx, showtimeID, released)
+	return released, nil
+}
+
+// BookedMarkers returns seat -> booking id for every seatbooked: key of
+// showtimeID.
+func (s *Service) BookedMarkers(ctx context.Context, showtimeID string) (map[string]string, error) {
+	pattern := fmt.Sprintf("seatbooked:%s:*", showtimeID)
+	out := make(map[string]string)
+
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, 200).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			vals, err := s.rdb.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, err
+			}
+			for i, k := range keys {
+				if v, ok := vals[i].(string); ok {
+					out[k[strings.LastIndex(k, ":")+1:]] = v
+				}
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// BookedShowtimeIDs returns the showtimes that have seatbooked: keys.
+func (s *Service) BookedShowtimeIDs(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, "seatbooked:*", 500).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			parts := strings.Split(k, ":")
+			if len(parts) == 3 && !seen[parts[1]] {
+				seen[parts[1]] = true
+				out = append(out, parts[1])
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// MarkerChange rewrites one seatbooked: marker. From is the booking id
+// expected there now ("" = no marker), To the one to write ("" = delete).
+type MarkerChange struct {
+	SeatID string `json:"seat_id"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// compare-and-set per key, so a confirm or cancel racing with the repair wins
+// KEYS: booked keys; ARGV: from, to per key
+var luaSwapMarkers = redis.NewScript(`
+local applied = {}
+for i=1,#KEYS do
+  local from = ARGV[2*i-1]
+  local to = ARGV[2*i]
+  local cur = redis.call("GET", KEYS[i]) or ""
+  if cur == from then
+    if to == "" then
+      redis.call("DEL", KEYS[i])
+    else
+      redis.call("SET", KEYS[i], to)
+    end
+    table.insert(applied, i)
+  end
+end
+return applied
+`)
+
+// ApplyMarkers applies the changes whose From still matches and announces
+// the seats: "released" for removed markers, "booked" for written ones (per
+// booking). Returns the changes applied.
+func (s *Service) ApplyMarkers(ctx context.Context, showtimeID string, changes []MarkerChange) ([]MarkerChange, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(changes))
+	args := make([]any, 0, len(changes)*2)
+	for _, ch := range changes {
+		keys = append(keys, bookedKey(showtimeID, ch.SeatID))
+		args = append(args, ch.From, ch.To)
+	}
+
+	idx, err := luaSwapMarkers.Run(ctx, s.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]MarkerChange, 0, len(idx))
+	released := make(map[string][]string) // old booking -> seats
+	booked := make(map[string][]string)   // new booking -> seats
+	var releasedOrder, bookedOrder []string
+	for _, i := range idx {
+		ch := changes[i-1]
+		applied = append(applied, ch)
+		if ch.To != "" {
+			if _, ok := booked[ch.To]; !ok {
+				bookedOrder = append(bookedOrder, ch.To)
+			}
+			booked[ch.To] = append(booked[ch.To], ch.SeatID)
+		} else {
+			if _, ok := released[ch.From]; !ok {
+				releasedOrder = append(releasedOrder, ch.From)
+			}
+			released[ch.From] = append(released[ch.From], ch.SeatID)
+		}
+	}
+
+	now := time.Now().Unix()
+	for _, b := range releasedOrder {
+		s.publish(ctx, SeatEvent{Type: "released", ShowtimeID: showtimeID, SeatIDs: released[b], BookingID: b, At: now})
+	}
+	for _, b := range bookedOrder {
+		s.publish(ctx, SeatEvent{Type: "booked", ShowtimeID: showtimeID, SeatIDs: booked[b], BookingID: b, At: now})
+	}
+	return applied, nil
+}
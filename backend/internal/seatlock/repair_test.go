@@ -0,0 +1,93 @@
+package seatlock
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestForceRelease(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+
+	if ok, _, err := svc.LockSeats(ctx, "st1", []string{"A1", "A2"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	events := watchSeatEvents(t, rdb, "st1")
+
+	released, err := svc.ForceRelease(ctx, "st1", []string{"A1", "A3"})
+	want := []LockInfo{{SeatID: "A1", Owner: "u1", RequestID: "r1"}}
+	if err != nil || !reflect.DeepEqual(released, want) {
+		t.Fatalf("ForceRelease = %+v, %v; want %+v", released, err, want)
+	}
+	if v, _ := rdb.Get(ctx, key("st1", "A2")).Result(); v != "u1:r1" {
+		t.Fatalf("A2 lock = %q, want untouched", v)
+	}
+	if members, _ := rdb.ZRange(ctx, expZKey("st1"), 0, -1).Result(); len(members) != 1 || members[0] != expMember("A2", "u1", "r1") {
+		t.Fatalf("expiry members = %v", members)
+	}
+	if got := events(); len(got) != 1 || got[0].Type != "released" || !reflect.DeepEqual(got[0].SeatIDs, []string{"A1"}) {
+		t.Fatalf("events = %+v", got)
+	}
+}
+
+func TestApplyMarkers(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+	rdb.Set(ctx, bookedKey("st1", "A1"), "stale", 0)
+	rdb.Set(ctx, bookedKey("st1", "A2"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "A3"), "b3", 0) // changed since the check
+
+	markers, err := svc.BookedMarkers(ctx, "st1")
+	if err != nil || len(markers) != 3 || markers["A1"] != "stale" {
+		t.Fatalf("BookedMarkers = %v, %v", markers, err)
+	}
+	events := watchSeatEvents(t, rdb, "st1")
+
+	changes := []MarkerChange{
+		{SeatID: "A1", From: "stale"},
+		{SeatID: "A2", From: "b1", To: "b2"},
+		{SeatID: "A3", From: "", To: "b2"},
+		{SeatID: "A4", To: "b2"},
+	}
+	applied, err := svc.ApplyMarkers(ctx, "st1", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []MarkerChange{changes[0], changes[1], changes[3]}; !reflect.DeepEqual(applied, want) {
+		t.Fatalf("applied = %+v, want %+v", applied, want)
+	}
+
+	markers, _ = svc.BookedMarkers(ctx, "st1")
+	if want := map[string]string{"A2": "b2", "A3": "b3", "A4": "b2"}; !reflect.DeepEqual(markers, want) {
+		t.Fatalf("markers = %v, want %v", markers, want)
+	}
+
+	got := events()
+	if len(got) != 2 || got[0].Type != "released" || got[1].Type != "booked" || !reflect.DeepEqual(got[1].SeatIDs, []string{"A2", "A4"}) {
+		t.Fatalf("events = %+v", got)
+	}
+}
+
+func TestBookedShowtimeIDs(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+	rdb.Set(ctx, bookedKey("st1", "A1"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "A2"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st2", "A1"), "b2", 0)
+	rdb.Set(ctx, key("st3", "A1"), "u1:r1", time.Minute)
+
+	ids, err := svc.BookedShowtimeIDs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"st1", "st2"}) {
+		t.Fatalf("BookedShowtimeIDs = %v", ids)
+	}
+}
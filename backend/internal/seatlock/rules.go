@@ -0,0 +1,237 @@
+package seatlock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Seat selection rules
+// =====================
+
+// Selection is what a rule sees: the seats being locked plus the current
+// occupancy of every row they touch.
+type Selection struct {
+	ShowtimeID string
+	Owner      string
+	SeatIDs    []string
+
+	// Taken = booked, blocked or locked by someone else.
+	Taken map[string]struct{}
+	// Mine = already locked by Owner (kept after this lock).
+	Mine map[string]struct{}
+}
+
+func (s *Selection) occupiedBefore(seatID string) bool {
+	if _, ok := s.Taken[seatID]; ok {
+		return true
+	}
+	_, ok := s.Mine[seatID]
+	return ok
+}
+
+func (s *Selection) occupiedAfter(seatID string, picked map[string]struct{}) bool {
+	if s.occupiedBefore(seatID) {
+		return true
+	}
+	_, ok := picked[seatID]
+	return ok
+}
+
+// RuleViolation is returned by LockSeats when a selection breaks a rule.
+type RuleViolation struct {
+	Code   string `json:"error"`   // e.g. "seat_gap_violation"
+	Rule   string `json:"rule"`    // rule name
+	SeatID string `json:"seat_id"` // offending seat
+}
+
+func (v *RuleViolation) Error() string {
+	return fmt.Sprintf("%s: seat %s (%s)", v.Code, v.SeatID, v.Rule)
+}
+
+// Rule validates a selection against the seat map.
+type Rule interface {
+	Name() string
+	Check(m *SeatMap, sel *Selection) *RuleViolation
+}
+
+// Rules is an ordered set of rules bound to one seat map.
+type Rules struct {
+	seatMap *SeatMap
+	rules   []Rule
+}
+
+func NewRules(seatMap *SeatMap, rules ...Rule) *Rules {
+	return &Rules{seatMap: seatMap, rules: rules}
+}
+
+func (r *Rules) SeatMap() *SeatMap { return r.seatMap }
+
+func (r *Rules) empty() bool {
+	return r == nil || r.seatMap == nil || len(r.rules) == 0
+}
+
+// Check runs every rule and returns the first violation.
+func (r *Rules) Check(sel *Selection) *RuleViolation {
+	if r.empty() {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if v := rule.Check(r.seatMap, sel); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// rowsOf returns the distinct rows (known to the map) touched by seatIDs.
+func (r *Rules) rowsOf(seatIDs []string) []string {
+	seen := make(map[string]struct{}, len(seatIDs))
+	out := make([]string, 0, len(seatIDs))
+	for _, sid := range seatIDs {
+		row := r.seatMap.RowOf(sid)
+		if row == "" {
+			continue
+		}
+		if _, ok := seen[row]; ok {
+			continue
+		}
+		seen[row] = struct{}{}
+		out = append(out, row)
+	}
+	return out
+}
+
+// checkSeatMap rejects seats the map doesn't have (invalid_seat) or marks
+// as never sold (seat_unavailable). Applies to every lock, admins included.
+func (s *Service) checkSeatMap(seatIDs []string) *RuleViolation {
+	if s.seatMap == nil {
+		return nil
+	}
+	for _, sid := range seatIDs {
+		if !s.seatMap.Has(sid) {
+			return &RuleViolation{Code: "invalid_seat", Rule: "seat_map", SeatID: sid}
+		}
+		if s.seatMap.Blocked(sid) {
+			return &RuleViolation{Code: "seat_unavailable", Rule: "seat_map", SeatID: sid}
+		}
+	}
+	return nil
+}
+
+// =====================
+// Single-seat gap rule
+// =====================
+
+// SingleSeatGapRule rejects selections that leave one empty seat isolated
+// between occupied seats, aisles, blocked seats or the row edge.
+// Seats that were already isolated before the selection are not counted.
+type SingleSeatGapRule struct{}
+
+func (SingleSeatGapRule) Name() string { return "single_seat_gap" }
+
+func (g SingleSeatGapRule) Check(m *SeatMap, sel *Selection) *RuleViolation {
+	picked := make(map[string]struct{}, len(sel.SeatIDs))
+	rows := make(map[string]struct{}, len(sel.SeatIDs))
+	for _, sid := range sel.SeatIDs {
+		picked[sid] = struct{}{}
+		if row := m.RowOf(sid); row != "" {
+			rows[row] = struct{}{}
+		}
+	}
+
+	// map order, so the reported seat is the same on every call
+	for _, row := range m.Rows() {
+		if _, ok := rows[row]; !ok {
+			continue
+		}
+		for _, seg := range m.Segments(row) {
+			for i, sid := range seg {
+				if sel.occupiedAfter(sid, picked) {
+					continue
+				}
+
+				leftAfter := i == 0 || sel.occupiedAfter(seg[i-1], picked)
+				rightAfter := i == len(seg)-1 || sel.occupiedAfter(seg[i+1], picked)
+				if !leftAfter || !rightAfter {
+					continue
+				}
+
+				leftBefore := i == 0 || sel.occupiedBefore(seg[i-1])
+				rightBefore := i == len(seg)-1 || sel.occupiedBefore(seg[i+1])
+				if leftBefore && rightBefore {
+					// already orphaned, not caused by this selection
+					continue
+				}
+
+				return &RuleViolation{
+					Code:   "seat_gap_violation",
+					Rule:   g.Name(),
+					SeatID: sid,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// =====================
+// Occupancy snapshot for rules
+// =====================
+
+// loadSelection reads lock/booked state for every seat in the rows touched
+// by seatIDs. It is a point-in-time read; the lock itself stays atomic.
+func (s *Service) loadSelection(ctx context.Context, showtimeID string, seatIDs []string, owner string) (*Selection, error) {
+	sel := &Selection{
+		ShowtimeID: showtimeID,
+		Owner:      owner,
+		SeatIDs:    seatIDs,
+		Taken:      make(map[string]struct{}),
+		Mine:       make(map[string]struct{}),
+	}
+
+	var rowSeats []string
+	for _, row := range s.rules.rowsOf(seatIDs) {
+		rowSeats = append(rowSeats, s.rules.seatMap.RowSeats(row)...)
+	}
+	if len(rowSeats) == 0 {
+		return sel, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	lockCmds := make([]*redis.StringCmd, len(rowSeats))
+	bookedCmds := make([]*redis.IntCmd, len(rowSeats))
+	for i, sid := range rowSeats {
+		lockCmds[i] = pipe.Get(ctx, key(showtimeID, sid))
+		bookedCmds[i] = pipe.Exists(ctx, bookedKey(showtimeID, sid))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	blocked, err := s.blockedSeats(ctx, showtimeID, rowSeats)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sid := range rowSeats {
+		if n, _ := bookedCmds[i].Result(); n == 1 || blocked[sid] {
+			sel.Taken[sid] = struct{}{}
+			continue
+		}
+		v, err := lockCmds[i].Result()
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(v, owner+":") {
+			sel.Mine[sid] = struct{}{}
+		} else {
+			sel.Taken[sid] = struct{}{}
+		}
+	}
+
+	return sel, nil
+}
@@ -0,0 +1,98 @@
+package seatlock
+
+import "testing"
+
+func seatSet(ids ...string) map[string]struct{} {
+	out := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+func TestSingleSeatGapRule(t *testing.T) {
+	m, err := ParseSeatMap("A=SSSSSS;B=SSS_SSS;C=SXSSS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		seats  []string
+		taken  []string
+		mine   []string
+		wantAt string // offending seat, "" = allowed
+	}{
+		{name: "whole block from the edge", seats: []string{"A1", "A2"}},
+		{name: "leaves the edge seat alone", seats: []string{"A2", "A3"}, wantAt: "A1"},
+		{name: "leaves one seat next to a taken one", seats: []string{"A1", "A2"}, taken: []string{"A4"}, wantAt: "A3"},
+		{name: "fills the gap exactly", seats: []string{"A3"}, taken: []string{"A1", "A2", "A4"}},
+		{name: "gap that existed before", seats: []string{"A4", "A5", "A6"}, taken: []string{"A1", "A3"}},
+		{name: "own earlier lock counts as occupied", seats: []string{"A2"}, mine: []string{"A3", "A4"}, wantAt: "A1"},
+		{name: "aisle is an edge", seats: []string{"B1", "B2"}, wantAt: "B3"},
+		{name: "up to the aisle", seats: []string{"B2", "B3"}, wantAt: "B1"},
+		{name: "blocked seat is an edge", seats: []string{"C3", "C4"}, wantAt: "C5"},
+		{name: "next to a blocked seat", seats: []string{"C4"}, wantAt: "C3"},
+		// rows are checked in map order, whatever order the seats come in
+		{name: "first row in map order", seats: []string{"B2", "B3", "A2", "A3"}, wantAt: "A1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := &Selection{
+				SeatIDs: tt.seats,
+				Taken:   seatSet(tt.taken...),
+				Mine:    seatSet(tt.mine...),
+			}
+
+			v := SingleSeatGapRule{}.Check(m, sel)
+			switch {
+			case tt.wantAt == "" && v != nil:
+				t.Fatalf("Check = %v, want nil", v)
+			case tt.wantAt != "" && v == nil:
+				t.Fatalf("Check = nil, want a violation at %s", tt.wantAt)
+			case v != nil && (v.SeatID != tt.wantAt || v.Code != "seat_gap_violation"):
+				t.Fatalf("Check = %s %s, want seat_gap_violation %s", v.Code, v.SeatID, tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestCheckSeatMap(t *testing.T) {
+	m, err := ParseSeatMap("A=SXS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{seatMap: m}
+
+	tests := []struct {
+		name     string
+		seats    []string
+		wantCode string
+		wantSeat string
+	}{
+		{name: "sellable", seats: []string{"A1", "A3"}},
+		{name: "unknown seat", seats: []string{"A1", "A9"}, wantCode: "invalid_seat", wantSeat: "A9"},
+		{name: "blocked seat", seats: []string{"A2"}, wantCode: "seat_unavailable", wantSeat: "A2"},
+		{name: "first problem wins", seats: []string{"A2", "Z1"}, wantCode: "seat_unavailable", wantSeat: "A2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := s.checkSeatMap(tt.seats)
+			if tt.wantCode == "" {
+				if v != nil {
+					t.Fatalf("checkSeatMap = %v, want nil", v)
+				}
+				return
+			}
+			if v == nil || v.Code != tt.wantCode || v.SeatID != tt.wantSeat {
+				t.Fatalf("checkSeatMap = %v, want %s %s", v, tt.wantCode, tt.wantSeat)
+			}
+		})
+	}
+
+	if v := (&Service{}).checkSeatMap([]string{"anything"}); v != nil {
+		t.Fatalf("no seat map: checkSeatMap = %v, want nil", v)
+	}
+}
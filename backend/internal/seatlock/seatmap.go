@@ -0,0 +1,211 @@
+package seatlock
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Seat map layout symbols (one rune per position in a row)
+const (
+	cellSeat    = 'S' // sellable seat
+	cellBlocked = 'X' // physical seat that is never sold (broken, camera, ...)
+	cellAisle   = '_' // aisle / gap, does not consume a seat number
+)
+
+// DefaultSeatType applies to rows without an explicit type ("E:premium=...").
+const DefaultSeatType = "standard"
+
+// DefaultSeatMapSpec matches the demo hall rendered by the frontend (A-E x 10).
+const DefaultSeatMapSpec = "A=SSSSSSSSSS;B=SSSSSSSSSS;C=SSSSSSSSSS;D=SSSSSSSSSS;E=SSSSSSSSSS"
+
+type cellKind int
+
+const (
+	kindSeat cellKind = iota
+	kindBlocked
+	kindAisle
+)
+
+type cell struct {
+	kind   cellKind
+	seatID string // empty for aisles
+}
+
+// SeatMap is the physical layout of a hall.
+// Seats are numbered left to right starting at 1; aisles are skipped.
+type SeatMap struct {
+	rows  map[string][]cell
+	order []string          // rows in spec order
+	types map[string]string // row -> seat type
+	index map[string]seatPos
+}
+
+type seatPos struct {
+	row string
+	col int // index into rows[row]
+}
+
+var seatIDPartsRe = regexp.MustCompile(`^([A-Z]{1,3})([0-9]{1,3})$`)
+
+// ParseSeatMap parses "A=SSSS_SSSS;B=SSXS_SSSS" into a SeatMap.
+// A row may carry a seat type: "E:premium=SSSS_SSSS".
+func ParseSeatMap(spec string) (*SeatMap, error) {
+	m := &SeatMap{
+		rows:  make(map[string][]cell),
+		types: make(map[string]string),
+		index: make(map[string]seatPos),
+	}
+
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid seat map row: %q", part)
+		}
+
+		row := strings.ToUpper(strings.TrimSpace(kv[0]))
+		seatType := DefaultSeatType
+		if r, t, found := strings.Cut(row, ":"); found {
+			row = strings.TrimSpace(r)
+			seatType = strings.ToLower(strings.TrimSpace(t))
+		}
+		layout := strings.ToUpper(strings.TrimSpace(kv[1]))
+		if row == "" || layout == "" || seatType == "" {
+			return nil, fmt.Errorf("invalid seat map row: %q", part)
+		}
+		if _, dup := m.rows[row]; dup {
+			return nil, fmt.Errorf("duplicate seat map row: %s", row)
+		}
+
+		cells := make([]cell, 0, len(layout))
+		num := 0
+		for _, r := range layout {
+			switch r {
+			case cellSeat, cellBlocked:
+				num++
+				sid := row + strconv.Itoa(num)
+				kind := kindSeat
+				if r == cellBlocked {
+					kind = kindBlocked
+				}
+				m.index[sid] = seatPos{row: row, col: len(cells)}
+				cells = append(cells, cell{kind: kind, seatID: sid})
+			case cellAisle:
+				cells = append(cells, cell{kind: kindAisle})
+			default:
+				return nil, fmt.Errorf("invalid seat map symbol %q in row %s", r, row)
+			}
+		}
+		m.rows[row] = cells
+		m.order = append(m.order, row)
+		m.types[row] = seatType
+	}
+
+	if len(m.rows) == 0 {
+		return nil, fmt.Errorf("seat map is empty")
+	}
+	return m, nil
+}
+
+// Has reports whether seatID exists in the map (blocked seats included).
+func (m *SeatMap) Has(seatID string) bool {
+	_, ok := m.index[seatID]
+	return ok
+}
+
+// Blocked reports whether seatID is a permanently blocked seat.
+func (m *SeatMap) Blocked(seatID string) bool {
+	p, ok := m.index[seatID]
+	if !ok {
+		return false
+	}
+	return m.rows[p.row][p.col].kind == kindBlocked
+}
+
+// RowOf returns the row label of seatID ("" if unknown).
+func (m *SeatMap) RowOf(seatID string) string {
+	if p, ok := m.index[seatID]; ok {
+		return p.row
+	}
+	if mm := seatIDPartsRe.FindStringSubmatch(seatID); mm != nil {
+		if _, ok := m.rows[mm[1]]; ok {
+			return mm[1]
+		}
+	}
+	return ""
+}
+
+// RowSeats returns every seat ID in row (blocked seats included, aisles skipped).
+func (m *SeatMap) RowSeats(row string) []string {
+	cells := m.rows[row]
+	out := make([]string, 0, len(cells))
+	for _, c := range cells {
+		if c.kind != kindAisle {
+			out = append(out, c.seatID)
+		}
+	}
+	return out
+}
+
+// SeatMapFromSpec parses spec, falling back to DefaultSeatMapSpec when empty.
+func SeatMapFromSpec(spec string) (*SeatMap, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultSeatMapSpec
+	}
+	return ParseSeatMap(spec)
+}
+
+// Rows returns the row labels in spec order.
+func (m *SeatMap) Rows() []string {
+	return append([]string(nil), m.order...)
+}
+
+// RowType returns the seat type of row.
+func (m *SeatMap) RowType(row string) string {
+	if t, ok := m.types[row]; ok {
+		return t
+	}
+	return DefaultSeatType
+}
+
+// SeatType returns the seat type of seatID.
+func (m *SeatMap) SeatType(seatID string) string {
+	return m.RowType(m.RowOf(seatID))
+}
+
+// HasSeatType reports whether any row is of seatType.
+func (m *SeatMap) HasSeatType(seatType string) bool {
+	for _, t := range m.types {
+		if t == seatType {
+			return true
+		}
+	}
+	return false
+}
+
+// Segments splits a row into runs of sellable seats separated by aisles,
+// blocked seats and the row edges.
+func (m *SeatMap) Segments(row string) [][]string {
+	var out [][]string
+	var cur []string
+	for _, c := range m.rows[row] {
+		if c.kind == kindSeat {
+			cur = append(cur, c.seatID)
+			continue
+		}
+		if len(cur) > 0 {
+			out = append(out, cur)
+			cur = nil
+		}
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
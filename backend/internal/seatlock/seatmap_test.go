@@ -0,0 +1,144 @@
+package seatlock
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSeatMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantErr  bool
+		rows     []string
+		rowSeats map[string][]string
+		types    map[string]string
+		blocked  []string
+	}{
+		{
+			name:     "seats and aisles",
+			spec:     "A=SS_SS",
+			rows:     []string{"A"},
+			rowSeats: map[string][]string{"A": {"A1", "A2", "A3", "A4"}},
+			types:    map[string]string{"A": DefaultSeatType},
+		},
+		{
+			name:     "blocked seat keeps its number",
+			spec:     "B=SXS",
+			rows:     []string{"B"},
+			rowSeats: map[string][]string{"B": {"B1", "B2", "B3"}},
+			blocked:  []string{"B2"},
+		},
+		{
+			name:     "spec order, seat types, lower case",
+			spec:     " b=ss ; A:Premium=S_S ;",
+			rows:     []string{"B", "A"},
+			rowSeats: map[string][]string{"B": {"B1", "B2"}, "A": {"A1", "A2"}},
+			types:    map[string]string{"A": "premium", "B": DefaultSeatType},
+		},
+		{name: "empty", spec: " ; ", wantErr: true},
+		{name: "missing layout", spec: "A", wantErr: true},
+		{name: "empty row label", spec: "=SS", wantErr: true},
+		{name: "empty seat type", spec: "A:=SS", wantErr: true},
+		{name: "duplicate row", spec: "A=SS;A=SS", wantErr: true},
+		{name: "unknown symbol", spec: "A=S?S", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseSeatMap(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSeatMap(%q) = nil error, want one", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSeatMap(%q): %v", tt.spec, err)
+			}
+			if got := m.Rows(); !reflect.DeepEqual(got, tt.rows) {
+				t.Errorf("Rows() = %v, want %v", got, tt.rows)
+			}
+			for row, want := range tt.rowSeats {
+				if got := m.RowSeats(row); !reflect.DeepEqual(got, want) {
+					t.Errorf("RowSeats(%s) = %v, want %v", row, got, want)
+				}
+			}
+			for row, want := range tt.types {
+				if got := m.RowType(row); got != want {
+					t.Errorf("RowType(%s) = %q, want %q", row, got, want)
+				}
+			}
+			for _, sid := range tt.blocked {
+				if !m.Blocked(sid) {
+					t.Errorf("Blocked(%s) = false, want true", sid)
+				}
+			}
+		})
+	}
+}
+
+func TestSeatMapLookups(t *testing.T) {
+	m, err := ParseSeatMap("A=SS_SXS;B:vip=SS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		seatID   string
+		has      bool
+		blocked  bool
+		row      string
+		seatType string
+	}{
+		{seatID: "A1", has: true, row: "A", seatType: DefaultSeatType},
+		{seatID: "A4", has: true, blocked: true, row: "A", seatType: DefaultSeatType},
+		{seatID: "B2", has: true, row: "B", seatType: "vip"},
+		// unknown number in a known row still maps to the row
+		{seatID: "A9", row: "A", seatType: DefaultSeatType},
+		{seatID: "Z1", seatType: DefaultSeatType},
+		{seatID: "bogus", seatType: DefaultSeatType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.seatID, func(t *testing.T) {
+			if got := m.Has(tt.seatID); got != tt.has {
+				t.Errorf("Has = %v, want %v", got, tt.has)
+			}
+			if got := m.Blocked(tt.seatID); got != tt.blocked {
+				t.Errorf("Blocked = %v, want %v", got, tt.blocked)
+			}
+			if got := m.RowOf(tt.seatID); got != tt.row {
+				t.Errorf("RowOf = %q, want %q", got, tt.row)
+			}
+			if got := m.SeatType(tt.seatID); got != tt.seatType {
+				t.Errorf("SeatType = %q, want %q", got, tt.seatType)
+			}
+		})
+	}
+}
+
+func TestSeatMapSegments(t *testing.T) {
+	tests := []struct {
+		spec string
+		want [][]string
+	}{
+		{spec: "A=SSSS", want: [][]string{{"A1", "A2", "A3", "A4"}}},
+		{spec: "A=SS_SS", want: [][]string{{"A1", "A2"}, {"A3", "A4"}}},
+		{spec: "A=SXS", want: [][]string{{"A1"}, {"A3"}}},
+		{spec: "A=_S__S_", want: [][]string{{"A1"}, {"A2"}}},
+		{spec: "A=XX", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			m, err := ParseSeatMap(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Segments("A"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Segments(A) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
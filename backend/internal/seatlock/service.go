@@ -2,7 +2,6 @@ package seatlock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,14 +11,46 @@ import (
 )
 
 type Service struct {
-	rdb *redis.Client
-	ttl time.Duration
+	rdb        *redis.Client
+	ttl        time.Duration
+	rules      *Rules
+	warnBefore time.Duration // expiring_soon offset (0 = off)
+	halls      HallLookup    // showtime -> hall, for hall-wide blocks (nil = none)
+	seatMap    *SeatMap      // seats that exist and can be sold (nil = any id)
 }
 
 func New(rdb *redis.Client, ttl time.Duration) *Service {
 	return &Service{rdb: rdb, ttl: ttl}
 }
 
+// WithRules enables selection rules (nil disables them).
+func (s *Service) WithRules(r *Rules) *Service {
+	s.rules = r
+	return s
+}
+
+// WithSeatMap makes LockSeats refuse seats not in m and seats m marks as
+// blocked ('X').
+func (s *Service) WithSeatMap(m *SeatMap) *Service {
+	s.seatMap = m
+	return s
+}
+
+// WithExpiryWarning notifies holders d before their lock expires
+// (0, or d >= ttl, disables it).
+func (s *Service) WithExpiryWarning(d time.Duration) *Service {
+	s.warnBefore = d
+	return s
+}
+
+// LockOptions tweaks a single LockSeats call.
+type LockOptions struct {
+	// BypassRules skips selection rules (admin only).
+	BypassRules bool
+	// TTL overrides the service TTL for this hold (e.g. short waitlist offers).
+	TTL time.Duration
+}
+
 func key(showtimeID, seatID string) string {
 	return fmt.Sprintf("seatlock:%s:%s", showtimeID, seatID)
 }
@@ -40,7 +71,8 @@ type LockInfo struct {
 // =====================
 
 type SeatEvent struct {
-	Type       string   `json:"type"` // "locked" | "released" | "booked" | "timeout"
+	Seq        int64    `json:"seq,omitempty"` // per-showtime, assigned on publish
+	Type       string   `json:"type"`          // "locked" | "released" | "booked" | "timeout" | "blocked" | "unblocked"
 	ShowtimeID string   `json:"showtime_id"`
 	SeatIDs    []string `json:"seat_ids"`
 	Owner      string   `json:"owner"`
@@ -54,11 +86,7 @@ func channel(showtimeID string) string {
 }
 
 func (s *Service) publish(ctx context.Context, ev SeatEvent) {
-	b, err := json.Marshal(ev)
-	if err != nil {
-		return
-	}
-	_ = s.rdb.Publish(ctx, channel(ev.ShowtimeID), b).Err()
+	publishSeatEvent(ctx, s.rdb, ev)
 }
 
 // =====================
@@ -67,17 +95,31 @@ func (s *Service) publish(ctx context.Context, ev SeatEvent) {
 
 // value stored as: owner:requestId
 // - allow lock if key empty OR already owned by same owner (prefix match)
+// - never lock a seat blocked by an admin (any of the block hashes)
+// - nothing while the showtime's booked markers are rebuilt (returns 2)
+// KEYS: [1..n] lock keys, [n+1] ready key, [n+2] rebuild set, [n+3..] block hashes
+// ARGV: owner, value, ttlMs, n, showtime id, seat ids
 var luaLockAll = redis.NewScript(`
 local owner = ARGV[1]
 local value = ARGV[2]
 local ttlMs = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
 
 local function starts_with(str, prefix)
   return string.sub(str, 1, string.len(prefix)) == prefix
 end
 
+if redis.call("EXISTS", KEYS[n+1]) == 0 or redis.call("SISMEMBER", KEYS[n+2], ARGV[5]) == 1 then
+  return {2, ""}
+end
+
 -- check conflicts first
-for i=1,#KEYS do
+for i=1,n do
+  for j=n+3,#KEYS do
+    if redis.call("HEXISTS", KEYS[j], ARGV[5+i]) == 1 then
+      return {0, KEYS[i]}
+    end
+  end
   local v = redis.call("GET", KEYS[i])
   if v and (not starts_with(v, owner .. ":")) then
     return {0, KEYS[i]}
@@ -85,7 +127,7 @@ for i=1,#KEYS do
 end
 
 -- lock all
-for i=1,#KEYS do
+for i=1,n do
   redis.call("SET", KEYS[i], value, "PX", ttlMs)
 end
 
@@ -93,6 +135,19 @@ return {1, ""}
 `)
 
 func (s *Service) LockSeats(ctx context.Context, showtimeID string, seatIDs []string, owner string, requestID string) (locked bool, conflictedSeatID string, err error) {
+	return s.LockSeatsWithOptions(ctx, showtimeID, seatIDs, owner, requestID, LockOptions{})
+}
+
+// LockSeatsWithOptions is LockSeats with per-call options.
+// A rule violation is returned as *RuleViolation.
+func (s *Service) LockSeatsWithOptions(
+	ctx context.Context,
+	showtimeID string,
+	seatIDs []string,
+	owner string,
+	requestID string,
+	opts LockOptions,
+) (locked bool, conflictedSeatID string, err error) {
 	if len(seatIDs) == 0 {
 		return false, "", fmt.Errorf("seatIDs required")
 	}
@@ -103,14 +158,46 @@ func (s *Service) LockSeats(ctx context.Context, showtimeID string, seatIDs []st
 		return false, "", fmt.Errorf("requestID required")
 	}
 
-	keys := make([]string, 0, len(seatIDs))
+	if v := s.checkSeatMap(seatIDs); v != nil {
+		return false, "", v
+	}
+
+	if !opts.BypassRules && !s.rules.empty() {
+		sel, err := s.loadSelection(ctx, showtimeID, seatIDs, owner)
+		if err != nil {
+			return false, "", err
+		}
+		if v := s.rules.Check(sel); v != nil {
+			return false, "", v
+		}
+	}
+
+	blockKeys, err := s.blockKeys(ctx, showtimeID)
+	if err != nil {
+		return false, "", err
+	}
+
+	keys := make([]string, 0, len(seatIDs)+len(blockKeys)+2)
+	args := make([]any, 0, len(seatIDs)+5)
 	for _, sid := range seatIDs {
 		keys = append(keys, key(showtimeID, sid))
 	}
+	keys = append(keys, gateKeys()...)
+	keys = append(keys, blockKeys...)
 
 	value := owner + ":" + requestID
 
-	res, err := luaLockAll.Run(ctx, s.rdb, keys, owner, value, s.ttl.Milliseconds()).Result()
+	ttl := s.ttl
+	if opts.TTL > 0 {
+		ttl = opts.TTL
+	}
+
+	args = append(args, owner, value, ttl.Milliseconds(), len(seatIDs), showtimeID)
+	for _, sid := range seatIDs {
+		args = append(args, sid)
+	}
+
+	res, err := luaLockAll.Run(ctx, s.rdb, keys, args...).Result()
 	if err != nil {
 		return false, "", err
 	}
@@ -121,9 +208,12 @@ func (s *Service) LockSeats(ctx context.Context, showtimeID string, seatIDs []st
 	}
 
 	okInt, _ := arr[0].(int64)
+	if okInt == 2 {
+		return false, "", ErrRebuilding
+	}
 	if okInt == 1 {
 		// track expiry for timeout sweeper
-		expireMs := time.Now().Add(s.ttl).UnixMilli()
+		expireMs := time.Now().Add(ttl).UnixMilli()
 		zk := expZKey(showtimeID)
 
 		pipe := s.rdb.Pipeline()
@@ -133,6 +223,7 @@ func (s *Service) LockSeats(ctx context.Context, showtimeID string, seatIDs []st
 				Member: expMember(sid, owner, requestID),
 			})
 		}
+		s.scheduleWarnings(ctx, pipe, showtimeID, seatIDs, owner, requestID, ttl, expireMs)
 		_, _ = pipe.Exec(ctx)
 
 		s.publish(ctx, SeatEvent{
@@ -236,7 +327,9 @@ func (s *Service) ReleaseSeats(ctx context.Context, showtimeID string, seatIDs [
 // Confirm booking atomically
 // =====================
 
-// KEYS layout: [1..n] lock keys, [n+1..2n] booked keys
+// KEYS layout: [1..n] lock keys, [n+1..2n] booked keys, [2n+1] ready key,
+// [2n+2] rebuild set
+// ARGV: owner, rid, booking id, n, showtime id
 var luaConfirmBooked = redis.NewScript(`
 local owner = ARGV[1]
 local rid = ARGV[2]
@@ -245,6 +338,11 @@ local n = tonumber(ARGV[4])
 
 local expected = owner .. ":" .. rid
 
+-- booked markers are being rebuilt: a missing one proves nothing
+if redis.call("EXISTS", KEYS[2*n+1]) == 0 or redis.call("SISMEMBER", KEYS[2*n+2], ARGV[5]) == 1 then
+  return {0, "", "rebuilding"}
+end
+
 -- First: if any seat already booked
 for i=1,n do
   local bookedK = KEYS[n+i]
@@ -291,15 +389,16 @@ func (s *Service) ConfirmSeatsBooked(
 		return false, "", "invalid_args", fmt.Errorf("owner/requestID/bookingID required")
 	}
 
-	keys := make([]string, 0, len(seatIDs)*2)
+	keys := make([]string, 0, len(seatIDs)*2+2)
 	for _, sid := range seatIDs {
 		keys = append(keys, key(showtimeID, sid))
 	}
 	for _, sid := range seatIDs {
 		keys = append(keys, bookedKey(showtimeID, sid))
 	}
+	keys = append(keys, gateKeys()...)
 
-	res, err := luaConfirmBooked.Run(ctx, s.rdb, keys, owner, requestID, bookingID, len(seatIDs)).Result()
+	res, err := luaConfirmBooked.Run(ctx, s.rdb, keys, owner, requestID, bookingID, len(seatIDs), showtimeID).Result()
 	if err != nil {
 		return false, "", "redis_failed", err
 	}
@@ -317,7 +416,7 @@ func (s *Service) ConfirmSeatsBooked(
 	if okInt == 1 {
 		pipe := s.rdb.Pipeline()
 		for _, sid := range seatIDs {
-			pipe.ZRem(ctx, zk, expMember(sid, owner, requestID))
+			pipe.ZRem(ctx, zk, expMember(sid, owner, requestID), warnMember(sid, owner, requestID))
 		}
 		_, _ = pipe.Exec(ctx)
 
@@ -337,6 +436,9 @@ func (s *Service) ConfirmSeatsBooked(
 	// failure
 	confKey, _ := arr[1].(string)
 	reason, _ = arr[2].(string)
+	if reason == "rebuilding" {
+		return false, "", reason, ErrRebuilding
+	}
 
 	// ✅ IMPORTANT:
 	// - If already_booked -> remove zset member to avoid sweeper firing timeout later (stale)
@@ -345,7 +447,7 @@ func (s *Service) ConfirmSeatsBooked(
 	if reason == "already_booked" {
 		pipe := s.rdb.Pipeline()
 		for _, sid := range seatIDs {
-			pipe.ZRem(ctx, zk, expMember(sid, owner, requestID))
+			pipe.ZRem(ctx, zk, expMember(sid, owner, requestID), warnMember(sid, owner, requestID))
 		}
 		_, _ = pipe.Exec(ctx)
 	}
@@ -463,3 +565,87 @@ func (s *Service) ListBookedSeats(ctx context.Context, showtimeID string) ([]str
 	sort.Strings(out)
 	return out, nil
 }
+
+// =====================
+// Free seats (neither locked, booked nor blocked)
+// =====================
+func (s *Service) FreeSeats(ctx context.Context, showtimeID string, seatIDs []string) (map[string]bool, error) {
+	free := make(map[string]bool, len(seatIDs))
+	if len(seatIDs) == 0 {
+		return free, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	lockCmds := make([]*redis.IntCmd, len(seatIDs))
+	bookedCmds := make([]*redis.IntCmd, len(seatIDs))
+	for i, sid := range seatIDs {
+		lockCmds[i] = pipe.Exists(ctx, key(showtimeID, sid))
+		bookedCmds[i] = pipe.Exists(ctx, bookedKey(showtimeID, sid))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.blockedSeats(ctx, showtimeID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sid := range seatIDs {
+		if lockCmds[i].Val() == 0 && bookedCmds[i].Val() == 0 && !blocked[sid] {
+			free[sid] = true
+		}
+	}
+	return free, nil
+}
+
+// =====================
+// Cancel a booking: free its booked seats
+// =====================
+
+// only markers that still belong to this booking are removed
+var luaReleaseBooked = redis.NewScript(`
+local bookingId = ARGV[1]
+local freed = {}
+for i=1,#KEYS do
+  if redis.call("GET", KEYS[i]) == bookingId then
+    redis.call("DEL", KEYS[i])
+    table.insert(freed, i)
+  end
+end
+return freed
+`)
+
+// ReleaseBooked removes the seatbooked: markers of bookingID and announces
+// the seats as released. Returns the seats actually freed.
+func (s *Service) ReleaseBooked(ctx context.Context, showtimeID string, seatIDs []string, owner, bookingID string) ([]string, error) {
+	if len(seatIDs) == 0 || bookingID == "" {
+		return nil, fmt.Errorf("seatIDs/bookingID required")
+	}
+
+	keys := make([]string, 0, len(seatIDs))
+	for _, sid := range seatIDs {
+		keys = append(keys, bookedKey(showtimeID, sid))
+	}
+
+	idx, err := luaReleaseBooked.Run(ctx, s.rdb, keys, bookingID).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	freed := make([]string, 0, len(idx))
+	for _, i := range idx {
+		freed = append(freed, seatIDs[i-1])
+	}
+	if len(freed) > 0 {
+		s.publish(ctx, SeatEvent{
+			Type:       "released",
+			ShowtimeID: showtimeID,
+			SeatIDs:    freed,
+			Owner:      owner,
+			BookingID:  bookingID,
+			At:         time.Now().Unix(),
+		})
+	}
+	return freed, nil
+}
@@ -0,0 +1,27 @@
+package seatlock
+
+import (
+	"cinema/internal/config"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewFromConfig builds the seat lock service the same way for the API and
+// the worker binary: TTL, expiry warning, seat map and selection rules.
+func NewFromConfig(rdb *redis.Client, cfg config.Config) (*Service, *SeatMap, error) {
+	seatMap, err := SeatMapFromSpec(cfg.SeatMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	svc := New(rdb, time.Duration(cfg.SeatLockTTLSeconds)*time.Second).
+		WithExpiryWarning(time.Duration(cfg.SeatLockWarnBeforeSecs) * time.Second).
+		WithSeatMap(seatMap)
+
+	// seat selection rules (single-seat gap)
+	if cfg.SeatGapRuleEnabled {
+		svc.WithRules(NewRules(seatMap, SingleSeatGapRule{}))
+	}
+	return svc, seatMap, nil
+}
@@ -0,0 +1,179 @@
+package seatlock
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Expiry modes (config SEAT_EXPIRY_MODE)
+const (
+	ExpiryModeSweep  = "sweep"  // poll seatlockexp:* every second
+	ExpiryModeNotify = "notify" // Redis keyspace notifications + slow reconcile sweep
+)
+
+const expiredPattern = "__keyevent@*__:expired"
+
+// StartExpiryListener emits "timeout" as soon as Redis expires a seatlock: key.
+// The ZSET sweep still runs every reconcileEvery to catch notifications lost
+// while disconnected (Pub/Sub is fire-and-forget).
+// Runs until ctx is cancelled.
+func StartExpiryListener(ctx context.Context, rdb *redis.Client, reconcileEvery time.Duration) {
+	if err := ensureExpiredNotifications(ctx, rdb); err != nil {
+		// managed Redis may forbid CONFIG SET; it must then be enabled server-side
+		log.Println("seatlock: enable keyspace notifications failed:", err)
+	}
+
+	ps := rdb.PSubscribe(ctx, expiredPattern)
+	defer func() { _ = ps.Close() }()
+
+	ch := ps.Channel()
+
+	ticker := time.NewTicker(reconcileEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepOnce(ctx, rdb)
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if showtimeID, ok := parseWarnWakeKey(msg.Payload); ok {
+				handleDueWarnings(ctx, rdb, showtimeID)
+				continue
+			}
+			showtimeID, seatID, ok := parseLockKey(msg.Payload)
+			if !ok {
+				continue
+			}
+			handleExpiredLock(ctx, rdb, showtimeID, seatID)
+		}
+	}
+}
+
+// ensureExpiredNotifications adds "Ex" to notify-keyspace-events, keeping existing flags.
+func ensureExpiredNotifications(ctx context.Context, rdb *redis.Client) error {
+	cur, err := rdb.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return err
+	}
+
+	flags := cur["notify-keyspace-events"]
+	want := flags
+	if !strings.Contains(want, "E") {
+		want += "E"
+	}
+	if !strings.Contains(want, "x") && !strings.Contains(want, "A") {
+		want += "x"
+	}
+	if want == flags {
+		return nil
+	}
+	return rdb.ConfigSet(ctx, "notify-keyspace-events", want).Err()
+}
+
+// key format: seatlock:<showtimeId>:<seatId>
+func parseLockKey(k string) (showtimeID, seatID string, ok bool) {
+	rest, found := strings.CutPrefix(k, "seatlock:")
+	if !found {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// key format: seatlockwarn:<showtimeId>:<rid>
+func parseWarnWakeKey(k string) (showtimeID string, ok bool) {
+	rest, found := strings.CutPrefix(k, "seatlockwarn:")
+	if !found {
+		return "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 {
+		return "", false
+	}
+	return rest[:i], true
+}
+
+// handleExpiredLock removes the seat's expiry members and publishes one timeout
+// for the most recent hold. ZREM decides the winner across replicas.
+func handleExpiredLock(ctx context.Context, rdb *redis.Client, showtimeID, seatID string) {
+	zk := expZKey(showtimeID)
+
+	// ZSCAN returns member, score, member, score...; a skiplist-encoded set
+	// takes several pages
+	var members []string
+	var cursor uint64
+	for {
+		page, next, err := rdb.ZScan(ctx, zk, cursor, seatID+"|*", 100).Result()
+		if err != nil {
+			return
+		}
+		members = append(members, page...)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+
+	// re-locked right after expiry? keep the live member
+	live, _ := rdb.Get(ctx, key(showtimeID, seatID)).Result()
+
+	var (
+		latestMember string
+		latestScore  float64
+	)
+	for i := 0; i+1 < len(members); i += 2 {
+		m := members[i]
+		sid, owner, rid, warn, ok := parseScheduled(m)
+		if !ok || sid != seatID {
+			continue
+		}
+		if live != "" && live == owner+":"+rid {
+			// this hold is still live (re-locked): keep its members
+			continue
+		}
+		if warn {
+			// its lock is gone: a pending warning is moot
+			_ = rdb.ZRem(ctx, zk, m).Err()
+			continue
+		}
+
+		n, err := rdb.ZRem(ctx, zk, m).Result()
+		if err != nil || n == 0 {
+			// another replica (or the reconcile sweep) got it
+			continue
+		}
+
+		score, _ := strconv.ParseFloat(members[i+1], 64)
+		if latestMember == "" || score >= latestScore {
+			latestMember = m
+			latestScore = score
+		}
+	}
+
+	if latestMember == "" {
+		return
+	}
+
+	if ex, _ := rdb.Exists(ctx, bookedKey(showtimeID, seatID)).Result(); ex == 1 {
+		return
+	}
+
+	_, owner, rid, _ := parseMember(latestMember)
+	publishTimeout(ctx, rdb, showtimeID, seatID, owner, rid)
+}
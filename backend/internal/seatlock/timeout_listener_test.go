@@ -0,0 +1,141 @@
+package seatlock
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	// a fresh Redis counts as rebuilt so locks go through
+	mr.Set(readyKey, "1")
+	return rdb, mr
+}
+
+// watchSeatEvents subscribes to showtimeID; the returned func collects what
+// arrived so far.
+func watchSeatEvents(t *testing.T, rdb *redis.Client, showtimeID string) func() []SeatEvent {
+	t.Helper()
+	ps := rdb.Subscribe(context.Background(), channel(showtimeID))
+	if _, err := ps.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ps.Close() })
+
+	ch := ps.Channel()
+	return func() []SeatEvent {
+		var out []SeatEvent
+		for {
+			select {
+			case msg := <-ch:
+				var ev SeatEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, ev)
+			case <-time.After(100 * time.Millisecond):
+				return out
+			}
+		}
+	}
+}
+
+func TestParseLockKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		showtime string
+		seat     string
+		ok       bool
+	}{
+		{key: "seatlock:SHOW1:A1", showtime: "SHOW1", seat: "A1", ok: true},
+		{key: "seatlock:a:b:C7", showtime: "a:b", seat: "C7", ok: true},
+		{key: "seatbooked:SHOW1:A1"},
+		{key: "seatlock:SHOW1:"},
+		{key: "seatlock:A1"},
+	}
+	for _, tt := range tests {
+		st, seat, ok := parseLockKey(tt.key)
+		if st != tt.showtime || seat != tt.seat || ok != tt.ok {
+			t.Errorf("parseLockKey(%q) = %q, %q, %v", tt.key, st, seat, ok)
+		}
+	}
+}
+
+func TestHandleExpiredLockOneTimeout(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	zk := expZKey("st1")
+	rdb.ZAdd(ctx, zk,
+		redis.Z{Score: 1000, Member: expMember("A1", "u1", "old")},
+		redis.Z{Score: 2000, Member: expMember("A1", "u1", "new")},
+		redis.Z{Score: 2000, Member: expMember("A2", "u1", "new")},
+	)
+
+	// the notification reaches every replica: only one wins the ZREM
+	handleExpiredLock(ctx, rdb, "st1", "A1")
+	handleExpiredLock(ctx, rdb, "st1", "A1")
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("events = %+v, want one timeout", got)
+	}
+	if ev := got[0]; ev.Type != "timeout" || ev.SeatIDs[0] != "A1" || ev.RequestID != "new" {
+		t.Fatalf("event = %+v, want timeout of the latest hold", ev)
+	}
+	if left, _ := rdb.ZRange(ctx, zk, 0, -1).Result(); len(left) != 1 || left[0] != expMember("A2", "u1", "new") {
+		t.Fatalf("members left = %v", left)
+	}
+}
+
+func TestHandleExpiredLockKeepsLiveHold(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	zk := expZKey("st1")
+	rdb.ZAdd(ctx, zk,
+		redis.Z{Score: 1000, Member: expMember("A1", "u1", "old")},
+		redis.Z{Score: 900, Member: warnMember("A1", "u1", "old")},
+		redis.Z{Score: 9000, Member: expMember("A1", "u2", "live")},
+		redis.Z{Score: 8000, Member: warnMember("A1", "u2", "live")},
+	)
+	// re-locked by someone else right after the expiry
+	rdb.Set(ctx, key("st1", "A1"), "u2:live", time.Minute)
+
+	handleExpiredLock(ctx, rdb, "st1", "A1")
+
+	if got := events(); len(got) != 1 || got[0].RequestID != "old" {
+		t.Fatalf("events = %+v, want the old hold's timeout", got)
+	}
+	// the live hold keeps its timeout and its warning
+	left, _ := rdb.ZRange(ctx, zk, 0, -1).Result()
+	if want := []string{warnMember("A1", "u2", "live"), expMember("A1", "u2", "live")}; !reflect.DeepEqual(left, want) {
+		t.Fatalf("members left = %v, want %v", left, want)
+	}
+}
+
+func TestHandleExpiredLockBooked(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	rdb.ZAdd(ctx, expZKey("st1"), redis.Z{Score: 1000, Member: expMember("A1", "u1", "r1")})
+	rdb.Set(ctx, bookedKey("st1", "A1"), "b1", 0)
+
+	handleExpiredLock(ctx, rdb, "st1", "A1")
+
+	if got := events(); len(got) != 0 {
+		t.Fatalf("events = %+v, want none for a booked seat", got)
+	}
+}
@@ -1,8 +1,8 @@
 package seatlock
 
 import (
+	"cinema/internal/notify"
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -27,15 +27,28 @@ func parseMember(m string) (seatID, owner, rid string, ok bool) {
 	return parts[0], parts[1], parts[2], true
 }
 
-func publishSeatEvent(ctx context.Context, rdb *redis.Client, ev SeatEvent) {
-	b, err := json.Marshal(ev)
-	if err != nil {
-		return
-	}
-	_ = rdb.Publish(ctx, channel(ev.ShowtimeID), b).Err()
+// publishTimeout announces an expired hold: public seat event + private
+// notification to the holder.
+func publishTimeout(ctx context.Context, rdb *redis.Client, showtimeID, seatID, owner, rid string) {
+	publishSeatEvent(ctx, rdb, SeatEvent{
+		Type:       "timeout",
+		ShowtimeID: showtimeID,
+		SeatIDs:    []string{seatID},
+		Owner:      owner,
+		RequestID:  rid,
+		At:         time.Now().Unix(),
+	})
+	notify.Publish(ctx, rdb, notify.UserEvent{
+		Type:       notify.HoldExpired,
+		UserID:     owner,
+		ShowtimeID: showtimeID,
+		SeatIDs:    []string{seatID},
+		RequestID:  rid,
+	})
 }
 
-// StartTimeoutSweeper runs forever until ctx is cancelled.
+// StartTimeoutSweeper polls the expiry ZSETs every second until ctx is
+// cancelled.
 func StartTimeoutSweeper(ctx context.Context, rdb *redis.Client) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -76,6 +89,9 @@ func sweepOnce(ctx context.Context, rdb *redis.Client) {
 func handleZSet(ctx context.Context, rdb *redis.Client, showtimeID, zk string) {
 	nowMs := time.Now().UnixMilli()
 
+	warnings := newWarnBatch(showtimeID)
+	defer warnings.flush(ctx, rdb)
+
 	// process up to 200 items per tick per showtime
 	for i := 0; i < 200; i++ {
 		// ZPOPMIN is atomic: prevents duplicate processing across instances
@@ -93,12 +109,18 @@ func handleZSet(ctx context.Context, rdb *redis.Client, showtimeID, zk string) {
 			return
 		}
 
-		seatID, owner, rid, ok := parseMember(m)
+		seatID, owner, rid, warn, ok := parseScheduled(m)
 		if !ok {
 			// drop invalid member
 			continue
 		}
 
+		// expiring_soon: notify holder if the hold is still live
+		if warn {
+			warnings.add(ctx, rdb, seatID, owner, rid)
+			continue
+		}
+
 		lockK := key(showtimeID, seatID)
 		bookedK := bookedKey(showtimeID, seatID)
 
@@ -125,13 +147,6 @@ func handleZSet(ctx context.Context, rdb *redis.Client, showtimeID, zk string) {
 		}
 
 		// 3) lock missing + not booked => timeout event
-		publishSeatEvent(ctx, rdb, SeatEvent{
-			Type:       "timeout",
-			ShowtimeID: showtimeID,
-			SeatIDs:    []string{seatID},
-			Owner:      owner,
-			RequestID:  rid,
-			At:         time.Now().Unix(),
-		})
+		publishTimeout(ctx, rdb, showtimeID, seatID, owner, rid)
 	}
 }
@@ -0,0 +1,207 @@
+package seatlock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Hold inspection + transfer (group bookings, ticket transfers)
+// =====================
+
+// SeatHold is the Redis view of one seat.
+type SeatHold struct {
+	SeatID    string
+	Owner     string        // lock owner ("" = not locked)
+	RequestID string        // lock request id
+	TTL       time.Duration // remaining lock time
+	BookingID string        // set once booked
+}
+
+// Inspect returns the lock/booked state of each seat, in order.
+func (s *Service) Inspect(ctx context.Context, showtimeID string, seatIDs []string) ([]SeatHold, error) {
+	pipe := s.rdb.Pipeline()
+	valCmds := make([]*redis.StringCmd, len(seatIDs))
+	ttlCmds := make([]*redis.DurationCmd, len(seatIDs))
+	bookedCmds := make([]*redis.StringCmd, len(seatIDs))
+	for i, sid := range seatIDs {
+		valCmds[i] = pipe.Get(ctx, key(showtimeID, sid))
+		ttlCmds[i] = pipe.PTTL(ctx, key(showtimeID, sid))
+		bookedCmds[i] = pipe.Get(ctx, bookedKey(showtimeID, sid))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	out := make([]SeatHold, len(seatIDs))
+	for i, sid := range seatIDs {
+		h := SeatHold{SeatID: sid}
+		if v, err := valCmds[i].Result(); err == nil {
+			h.Owner, h.RequestID, _ = strings.Cut(v, ":")
+			if ttl, err := ttlCmds[i].Result(); err == nil && ttl > 0 {
+				h.TTL = ttl
+			}
+		}
+		if b, err := bookedCmds[i].Result(); err == nil {
+			h.BookingID = b
+		}
+		out[i] = h
+	}
+	return out, nil
+}
+
+// KEYS: [1..n] lock keys, [n+1] expiry zset
+// ARGV: from value, to value, n, then per seat:
+//
+//	old timeout member, old warning member, new timeout member, new warning member
+var luaTransfer = redis.NewScript(`
+local from = ARGV[1]
+local to = ARGV[2]
+local n = tonumber(ARGV[3])
+local zk = KEYS[n+1]
+
+for i=1,n do
+  if redis.call("GET", KEYS[i]) ~= from then
+    return {0, KEYS[i]}
+  end
+end
+
+for i=1,n do
+  redis.call("SET", KEYS[i], to, "KEEPTTL")
+
+  -- move expiry schedules to the new holder, same due time
+  local base = 3 + (i-1)*4
+  for j=1,2 do
+    local old = ARGV[base+j]
+    local score = redis.call("ZSCORE", zk, old)
+    if score then
+      redis.call("ZREM", zk, old)
+      redis.call("ZADD", zk, score, ARGV[base+j+2])
+    end
+  end
+end
+
+return {1, ""}
+`)
+
+// TransferLocks hands seats held by fromOwner:fromRID to toOwner:toRID,
+// keeping the remaining TTL. All or nothing; conflicted is the first seat
+// not held by the sender.
+func (s *Service) TransferLocks(
+	ctx context.Context,
+	showtimeID string,
+	seatIDs []string,
+	fromOwner, fromRID string,
+	toOwner, toRID string,
+) (ok bool, conflictedSeatID string, err error) {
+	if len(seatIDs) == 0 {
+		return false, "", fmt.Errorf("seatIDs required")
+	}
+	if fromOwner == "" || fromRID == "" || toOwner == "" || toRID == "" {
+		return false, "", fmt.Errorf("owner/requestID required")
+	}
+
+	keys := make([]string, 0, len(seatIDs)+1)
+	for _, sid := range seatIDs {
+		keys = append(keys, key(showtimeID, sid))
+	}
+	keys = append(keys, expZKey(showtimeID))
+
+	args := []any{fromOwner + ":" + fromRID, toOwner + ":" + toRID, len(seatIDs)}
+	for _, sid := range seatIDs {
+		args = append(args,
+			expMember(sid, fromOwner, fromRID),
+			warnMember(sid, fromOwner, fromRID),
+			expMember(sid, toOwner, toRID),
+			warnMember(sid, toOwner, toRID),
+		)
+	}
+
+	res, err := luaTransfer.Run(ctx, s.rdb, keys, args...).Result()
+	if err != nil {
+		return false, "", err
+	}
+	arr, okArr := res.([]any)
+	if !okArr || len(arr) < 2 {
+		return false, "", fmt.Errorf("unexpected lua result: %T", res)
+	}
+
+	if okInt, _ := arr[0].(int64); okInt != 1 {
+		confKey, _ := arr[1].(string)
+		parts := strings.Split(confKey, ":")
+		return false, parts[len(parts)-1], nil
+	}
+
+	// new holder: "locked" for them (public frames only show "mine")
+	s.publish(ctx, SeatEvent{
+		Type:       "locked",
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Owner:      toOwner,
+		RequestID:  toRID,
+		At:         time.Now().Unix(),
+	})
+	return true, "", nil
+}
+
+// KEYS: booked keys
+// ARGV: from booking id, to booking id
+var luaReassignBooked = redis.NewScript(`
+for i=1,#KEYS do
+  if redis.call("GET", KEYS[i]) ~= ARGV[1] then
+    return {0, KEYS[i]}
+  end
+end
+for i=1,#KEYS do
+  redis.call("SET", KEYS[i], ARGV[2])
+end
+return {1, ""}
+`)
+
+// ReassignBooked moves booked seats from one booking to another (ticket
+// transfer). All or nothing; conflicted is the first seat not booked by
+// fromBookingID.
+func (s *Service) ReassignBooked(
+	ctx context.Context,
+	showtimeID string,
+	seatIDs []string,
+	fromBookingID, toBookingID, toOwner string,
+) (ok bool, conflictedSeatID string, err error) {
+	if len(seatIDs) == 0 || fromBookingID == "" || toBookingID == "" {
+		return false, "", fmt.Errorf("seatIDs/bookingIDs required")
+	}
+
+	keys := make([]string, 0, len(seatIDs))
+	for _, sid := range seatIDs {
+		keys = append(keys, bookedKey(showtimeID, sid))
+	}
+
+	res, err := luaReassignBooked.Run(ctx, s.rdb, keys, fromBookingID, toBookingID).Result()
+	if err != nil {
+		return false, "", err
+	}
+	arr, okArr := res.([]any)
+	if !okArr || len(arr) < 2 {
+		return false, "", fmt.Errorf("unexpected lua result: %T", res)
+	}
+	if okInt, _ := arr[0].(int64); okInt != 1 {
+		confKey, _ := arr[1].(string)
+		parts := strings.Split(confKey, ":")
+		return false, parts[len(parts)-1], nil
+	}
+
+	// still booked for everyone else; the new holder sees them as theirs
+	s.publish(ctx, SeatEvent{
+		Type:       "booked",
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Owner:      toOwner,
+		BookingID:  toBookingID,
+		At:         time.Now().Unix(),
+	})
+	return true, "", nil
+}
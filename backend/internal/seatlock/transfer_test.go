@@ -0,0 +1,40 @@
+package seatlock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReassignBooked(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTestRedis(t)
+	svc := New(rdb, time.Minute)
+	rdb.Set(ctx, bookedKey("st1", "A1"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "A2"), "b1", 0)
+	rdb.Set(ctx, bookedKey("st1", "A3"), "b2", 0)
+	events := watchSeatEvents(t, rdb, "st1")
+
+	// all or nothing: A3 belongs to another booking
+	ok, conflicted, err := svc.ReassignBooked(ctx, "st1", []string{"A1", "A3"}, "b1", "b9", "u9")
+	if err != nil || ok || conflicted != "A3" {
+		t.Fatalf("ReassignBooked = %v, %q, %v; want conflict on A3", ok, conflicted, err)
+	}
+	if v, _ := rdb.Get(ctx, bookedKey("st1", "A1")).Result(); v != "b1" {
+		t.Fatalf("A1 booked by %q after a refused reassign", v)
+	}
+
+	ok, _, err = svc.ReassignBooked(ctx, "st1", []string{"A1", "A2"}, "b1", "b9", "u9")
+	if err != nil || !ok {
+		t.Fatalf("ReassignBooked = %v, %v", ok, err)
+	}
+	for _, sid := range []string{"A1", "A2"} {
+		if v, _ := rdb.Get(ctx, bookedKey("st1", sid)).Result(); v != "b9" {
+			t.Fatalf("%s booked by %q, want b9", sid, v)
+		}
+	}
+	got := events()
+	if len(got) != 1 || got[0].Type != "booked" || got[0].BookingID != "b9" || got[0].Owner != "u9" {
+		t.Fatalf("events = %+v, want one booked event for the new holder", got)
+	}
+}
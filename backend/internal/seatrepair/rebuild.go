@@ -0,0 +1,367 @@
+package seatrepair
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// =====================
+// Seat state rebuild after Redis data loss
+// =====================
+
+// Redis runs without persistence: after a restart every seatbooked: key is
+// gone and booked seats look free. Lock and confirm are refused until the
+// seatlock ready marker exists (see seatlock/rebuild.go), so nothing is sold
+// twice in the meantime. Guard notices the missing marker and rebuilds the
+// markers of every upcoming showtime from its BOOKED bookings; each showtime
+// opens again as soon as its own markers are back. Only missing markers are
+// written: stale ones are left to the reconciler and seats/repair. Locks and
+// admin seat blocks lived only in Redis and are not restored.
+
+const (
+	rebuildCheckEvery = 10 * time.Second
+	rebuildLockKey    = "seatrebuild:lock" // one rebuild at a time across instances
+	rebuildLockTTL    = 2 * time.Minute    // refreshed per showtime
+	lastRebuildKey    = "seatrebuild:last"
+)
+
+// ErrRebuildRunning is returned when another rebuild holds the lock.
+var ErrRebuildRunning = errors.New("seat rebuild already running")
+
+// ShowtimeRebuild is the outcome for one showtime.
+type ShowtimeRebuild struct {
+	ShowtimeID string `json:"showtime_id"`
+	Bookings   int    `json:"bookings"` // BOOKED bookings
+	Seats      int    `json:"seats"`    // their seats
+	Restored   int    `json:"restored"` // markers written
+	Present    int    `json:"present"`  // markers already right
+	// seats booked twice, or marked for another booking (for seats/repair)
+	Conflicts []Fix  `json:"conflicts"`
+	Error     string `json:"error,omitempty"` // the showtime stays gated
+}
+
+// RebuildRun is one rebuild with its totals; Results lists every showtime.
+type RebuildRun struct {
+	Trigger    string            `json:"trigger"` // "data_loss" | "resume" | "admin"
+	By         string            `json:"by,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Showtimes  int               `json:"showtimes"`
+	Bookings   int               `json:"bookings"`
+	Seats      int               `json:"seats"`
+	Restored   int               `json:"restored"`
+	Present    int               `json:"present"`
+	Conflicts  int               `json:"conflicts"`
+	Failed     int               `json:"failed"` // showtimes still gated
+	Results    []ShowtimeRebuild `json:"results"`
+}
+
+// Guard checks every rebuildCheckEvery (and right away) whether Redis lost
+// the seat state, or a rebuild was interrupted, and rebuilds until ctx is
+// cancelled.
+func (s *Service) Guard(ctx context.Context) {
+	ticker := time.NewTicker(rebuildCheckEvery)
+	defer ticker.Stop()
+
+	for {
+		s.GuardOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Standby is Guard for API instances: it checks right away, then every
+// rebuildCheckEvery while no instance holds the worker lease (leader returns
+// ""). Redis flushed while no worker runs would otherwise keep lock and
+// confirm gated until a worker comes back.
+func (s *Service) Standby(ctx context.Context, leader func(context.Context) (string, error)) {
+	ticker := time.NewTicker(rebuildCheckEvery)
+	defer ticker.Stop()
+
+	for {
+		if id, err := leader(ctx); err == nil && id == "" {
+			s.GuardOnce(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GuardOnce is one Guard check.
+func (s *Service) GuardOnce(ctx context.Context) {
+	ready, err := s.seats.StateReady(ctx)
+	if err != nil {
+		return
+	}
+
+	var run *RebuildRun
+	if !ready {
+		log.Println("seat state missing in redis: rebuilding booked markers from mongo")
+		run, err = s.Rebuild(ctx, nil, "data_loss", "")
+	} else {
+		pending, perr := s.seats.PendingRebuilds(ctx)
+		if perr != nil || len(pending) == 0 {
+			return
+		}
+		run, err = s.Rebuild(ctx, pending, "resume", "")
+	}
+	if errors.Is(err, ErrRebuildRunning) {
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("seat rebuild failed:", err)
+		}
+		return
+	}
+	log.Printf("seat rebuild (%s): %d showtimes, %d bookings, %d markers restored, %d conflicts, %d failed",
+		run.Trigger, run.Showtimes, run.Bookings, run.Restored, run.Conflicts, run.Failed)
+}
+
+// Rebuild restores the missing booked markers of showtimeIDs (nil = every
+// upcoming showtime, and lifts the global gate once they are all gated).
+// While the global gate is up, a rebuild of some showtimes rebuilds them all,
+// since that is the only way to lift it. Each showtime is gated until its
+// markers are back; one that fails stays gated and is retried by Guard.
+func (s *Service) Rebuild(ctx context.Context, showtimeIDs []string, trigger, by string) (*RebuildRun, error) {
+	token := uuid.NewString()
+	got, err := s.rdb.SetNX(ctx, rebuildLockKey, token, rebuildLockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !got {
+		return nil, ErrRebuildRunning
+	}
+	defer func() { _ = luaUnlock.Run(ctx, s.rdb, []string{rebuildLockKey}, token).Err() }()
+
+	run := &RebuildRun{
+		Trigger:   trigger,
+		By:        by,
+		StartedAt: time.Now(),
+		Results:   make([]ShowtimeRebuild, 0),
+	}
+
+	if showtimeIDs != nil {
+		ready, err := s.seats.StateReady(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			showtimeIDs = nil
+		}
+	}
+
+	full := showtimeIDs == nil
+	if full {
+		ids, err := s.upcomingShowtimes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		showtimeIDs = ids
+	}
+	if err := s.seats.BeginRebuild(ctx, showtimeIDs, full); err != nil {
+		return nil, err
+	}
+
+	for _, id := range showtimeIDs {
+		_ = s.rdb.Expire(ctx, rebuildLockKey, rebuildLockTTL).Err()
+
+		res := s.rebuildOne(ctx, id)
+		if res.Error == "" {
+			if err := s.seats.EndRebuild(ctx, id); err != nil {
+				res.Error = err.Error()
+			}
+		}
+
+		run.Showtimes++
+		run.Bookings += res.Bookings
+		run.Seats += res.Seats
+		run.Restored += res.Restored
+		run.Present += res.Present
+		run.Conflicts += len(res.Conflicts)
+		if res.Error != "" {
+			run.Failed++
+		}
+		run.Results = append(run.Results, *res)
+	}
+	run.FinishedAt = time.Now()
+
+	if raw, err := json.Marshal(run); err == nil {
+		_ = s.rdb.Set(ctx, lastRebuildKey, raw, 0).Err()
+	}
+	s.audit(ctx, "seats.rebuilt", "", by, nil, map[string]any{
+		"trigger":   run.Trigger,
+		"showtimes": run.Showtimes,
+		"bookings":  run.Bookings,
+		"seats":     run.Seats,
+		"restored":  run.Restored,
+		"present":   run.Present,
+		"conflicts": run.Conflicts,
+		"failed":    run.Failed,
+	})
+	return run, nil
+}
+
+// LastRebuild returns the last rebuild (nil if none yet).
+func (s *Service) LastRebuild(ctx context.Context) (*RebuildRun, error) {
+	raw, err := s.rdb.Get(ctx, lastRebuildKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run RebuildRun
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RebuildState reports whether seat state is ready and which showtimes are
+// still gated.
+func (s *Service) RebuildState(ctx context.Context) (bool, []string, error) {
+	ready, err := s.seats.StateReady(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	pending, err := s.seats.PendingRebuilds(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	sort.Strings(pending)
+	return ready, pending, nil
+}
+
+// delete the lock only if it is still ours
+var luaUnlock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// upcomingShowtimes = published showtimes that haven't ended, plus the
+// unmanaged ids (no document) that have BOOKED bookings, plus any left
+// gated by an interrupted rebuild. Markers can't be listed: they are gone.
+func (s *Service) upcomingShowtimes(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+
+	active, err := s.showtimes.FindActiveIDs(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range active {
+		add(id)
+	}
+
+	booked, err := s.bookings.FindBookedShowtimeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := s.showtimes.FindExistingIDs(ctx, booked)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range booked {
+		if !managed[id] {
+			add(id)
+		}
+	}
+
+	pending, err := s.seats.PendingRebuilds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range pending {
+		add(id)
+	}
+
+	sort.Strings(out)
+	return out, nil
+}
+
+// rebuildOne writes the missing markers of one showtime. A seat booked
+// twice gets the first booking's marker (so it can't be sold a third time)
+// and is reported.
+func (s *Service) rebuildOne(ctx context.Context, showtimeID string) *ShowtimeRebuild {
+	res := &ShowtimeRebuild{ShowtimeID: showtimeID, Conflicts: make([]Fix, 0)}
+
+	booked, err := s.bookings.FindBookedByShowtime(ctx, showtimeID)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	markers, err := s.seats.BookedMarkers(ctx, showtimeID)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	want := make(map[string]string)
+	doubled := make(map[string]bool)
+	for _, b := range booked {
+		res.Bookings++
+		for _, sid := range b.SeatIDs {
+			res.Seats++
+			if _, taken := want[sid]; taken {
+				doubled[sid] = true
+				continue
+			}
+			want[sid] = b.ID.Hex()
+		}
+	}
+
+	seats := make([]string, 0, len(want))
+	for sid := range want {
+		seats = append(seats, sid)
+	}
+	sort.Strings(seats)
+
+	changes := make([]seatlock.MarkerChange, 0)
+	for _, sid := range seats {
+		have, w := markers[sid], want[sid]
+		switch {
+		case doubled[sid]:
+			res.Conflicts = append(res.Conflicts, Fix{SeatID: sid, Action: ActionSkip, Have: have, Want: w, Note: "double_booked"})
+			if have == "" {
+				changes = append(changes, seatlock.MarkerChange{SeatID: sid, To: w})
+			}
+		case have == w:
+			res.Present++
+		case have == "":
+			changes = append(changes, seatlock.MarkerChange{SeatID: sid, To: w})
+		default:
+			res.Conflicts = append(res.Conflicts, Fix{SeatID: sid, Action: ActionSkip, Have: have, Want: w, Note: "other_booking"})
+		}
+	}
+
+	applied, err := s.seats.ApplyMarkers(ctx, showtimeID, changes)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Restored = len(applied)
+	return res
+}
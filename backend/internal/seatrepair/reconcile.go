@@ -0,0 +1,400 @@
+package seatrepair
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/notify"
+	"cinema/internal/seatlock"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// =====================
+// Redis–Mongo reconciler
+// =====================
+
+// Confirm writes Redis (luaConfirmBooked) and then Mongo (MarkBooked); a
+// crash in between leaves the two apart. The reconciler runs Check on every
+// active showtime and repairs what is safe without a human:
+//   - markers of bookings that don't hold the seat (failed, cancelled,
+//     unknown, seat moved away) are removed
+//   - missing markers of BOOKED seats are added when nobody holds the seat
+//   - a PENDING booking past pendingGrace whose seats are all marked for it
+//     is marked BOOKED (the confirm got through Redis, then died)
+//
+// Anything else (seat booked twice, marker of another booking, locked seat,
+// stale PENDING without seats) is reported for an admin. A fix is only
+// applied if two checks reconcileSettle apart agree on it, so requests that
+// are between their Redis and Mongo writes are left alone.
+
+const (
+	reconcileEvery = 5 * time.Minute
+	// longer than any request that writes both sides (5s timeouts)
+	reconcileSettle = 6 * time.Second
+	// a PENDING booking this old is no longer being confirmed
+	pendingGrace = 2 * time.Minute
+)
+
+const lastRunKey = "seatreconcile:last"
+
+// ShowtimeReconcile is what the reconciler found for one showtime.
+type ShowtimeReconcile struct {
+	ShowtimeID  string   `json:"showtime_id"`
+	Markers     int      `json:"markers"`
+	BookedSeats int      `json:"booked_seats"`
+	Repaired    []Fix    `json:"repaired"`  // safe fixes (applied unless dry run)
+	Confirmed   []string `json:"confirmed"` // stale PENDING bookings marked BOOKED (unless dry run)
+	Unsafe      []Fix    `json:"unsafe"`    // left for an admin (seats/repair)
+	// PENDING past the grace period with no seats booked in Redis
+	StalePending []string `json:"stale_pending"`
+	Error        string   `json:"error,omitempty"`
+}
+
+func (r *ShowtimeReconcile) clean() bool {
+	return len(r.Repaired) == 0 && len(r.Confirmed) == 0 && len(r.Unsafe) == 0 &&
+		len(r.StalePending) == 0 && r.Error == ""
+}
+
+// ReconcileRun is one reconciler pass; Showtimes only lists those with
+// findings.
+type ReconcileRun struct {
+	Trigger    string              `json:"trigger"` // "schedule" | "admin"
+	By         string              `json:"by,omitempty"`
+	DryRun     bool                `json:"dry_run"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Checked    int                 `json:"checked"`
+	Showtimes  []ShowtimeReconcile `json:"showtimes"`
+}
+
+// Run reconciles every active showtime each reconcileEvery until ctx is
+// cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(reconcileEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := s.Reconcile(ctx, nil, "schedule", "", false)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Println("seat reconcile failed:", err)
+				}
+				continue
+			}
+			if len(run.Showtimes) > 0 {
+				log.Printf("seat reconcile: %d showtimes checked, %d with findings", run.Checked, len(run.Showtimes))
+			}
+		}
+	}
+}
+
+// Reconcile checks showtimeIDs (nil = every active showtime) and, unless
+// dryRun, applies the safe repairs. The run is kept as the last run.
+func (s *Service) Reconcile(ctx context.Context, showtimeIDs []string, trigger, by string, dryRun bool) (*ReconcileRun, error) {
+	run := &ReconcileRun{
+		Trigger:   trigger,
+		By:        by,
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Showtimes: make([]ShowtimeReconcile, 0),
+	}
+
+	if showtimeIDs == nil {
+		ids, err := s.activeShowtimes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		showtimeIDs = ids
+	}
+	run.Checked = len(showtimeIDs)
+
+	first := make(map[string]*Report, len(showtimeIDs))
+	for _, id := range showtimeIDs {
+		if rep, err := s.Check(ctx, id); err == nil {
+			first[id] = rep
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(reconcileSettle):
+	}
+
+	for _, id := range showtimeIDs {
+		out := s.reconcileOne(ctx, id, first[id], by, dryRun)
+		if !out.clean() {
+			run.Showtimes = append(run.Showtimes, *out)
+		}
+	}
+	run.FinishedAt = time.Now()
+
+	if raw, err := json.Marshal(run); err == nil {
+		_ = s.rdb.Set(ctx, lastRunKey, raw, 0).Err()
+	}
+	return run, nil
+}
+
+// LastRun returns the last reconciler pass (nil if none yet).
+func (s *Service) LastRun(ctx context.Context) (*ReconcileRun, error) {
+	raw, err := s.rdb.Get(ctx, lastRunKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run ReconcileRun
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// activeShowtimes = published showtimes that haven't ended, plus any
+// showtime with booked markers (unmanaged ids).
+func (s *Service) activeShowtimes(ctx context.Context) ([]string, error) {
+	ids, err := s.showtimes.FindActiveIDs(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	marked, err := s.seats.BookedShowtimeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range marked {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func fixKey(f Fix) string {
+	return f.SeatID + "|" + f.Action + "|" + f.Have + "|" + f.Want
+}
+
+func (s *Service) reconcileOne(ctx context.Context, showtimeID string, first *Report, by string, dryRun bool) *ShowtimeReconcile {
+	out := &ShowtimeReconcile{
+		ShowtimeID:   showtimeID,
+		Repaired:     make([]Fix, 0),
+		Confirmed:    make([]string, 0),
+		Unsafe:       make([]Fix, 0),
+		StalePending: make([]string, 0),
+	}
+
+	rep, err := s.Check(ctx, showtimeID)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	out.Markers, out.BookedSeats = rep.Markers, rep.BookedSeats
+	if first == nil {
+		return out // first check failed; next pass
+	}
+
+	seen := make(map[string]bool, len(first.Fixes))
+	for _, f := range first.Fixes {
+		seen[fixKey(f)] = true
+	}
+
+	// split the fixes both checks agree on
+	var changes []Fix
+	pending := make(map[string][]string) // PENDING booking -> seats marked for it
+	for _, f := range rep.Fixes {
+		if !seen[fixKey(f)] {
+			continue
+		}
+		switch {
+		case f.Action == ActionRemove || f.Action == ActionAdd:
+			changes = append(changes, f)
+		case f.Action == ActionSkip && f.Note == "booking_pending":
+			pending[f.Have] = append(pending[f.Have], f.SeatID)
+		default:
+			out.Unsafe = append(out.Unsafe, f)
+		}
+	}
+
+	// adding a marker under someone's hold would break their confirm
+	changes, err = s.dropLocked(ctx, showtimeID, changes, out)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+
+	cutoff := time.Now().Add(-pendingGrace)
+	confirm := s.stalePending(ctx, pending, cutoff, out)
+
+	stale, err := s.bookings.FindPendingBefore(ctx, showtimeID, cutoff)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	for _, b := range stale {
+		if _, marked := pending[b.ID.Hex()]; !marked {
+			out.StalePending = append(out.StalePending, b.ID.Hex())
+		}
+	}
+
+	if dryRun {
+		out.Repaired = append(out.Repaired, changes...)
+		for _, b := range confirm {
+			out.Confirmed = append(out.Confirmed, b.ID.Hex())
+		}
+		return out
+	}
+
+	s.apply(ctx, showtimeID, changes, confirm, out)
+	if len(out.Confirmed) > 0 || slices.ContainsFunc(out.Repaired, func(f Fix) bool { return f.Applied }) {
+		s.audit(ctx, "seats.reconciled", showtimeID, by, nil, map[string]any{
+			"repaired":  out.Repaired,
+			"confirmed": out.Confirmed,
+			"unsafe":    out.Unsafe,
+		})
+	}
+	return out
+}
+
+// dropLocked moves add fixes for locked seats to out.Unsafe.
+func (s *Service) dropLocked(ctx context.Context, showtimeID string, changes []Fix, out *ShowtimeReconcile) ([]Fix, error) {
+	var adds []string
+	for _, f := range changes {
+		if f.Action == ActionAdd {
+			adds = append(adds, f.SeatID)
+		}
+	}
+	if len(adds) == 0 {
+		return changes, nil
+	}
+	holds, err := s.seats.Inspect(ctx, showtimeID, adds)
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[string]bool)
+	for _, h := range holds {
+		if h.Owner != "" {
+			locked[h.SeatID] = true
+		}
+	}
+
+	kept := changes[:0]
+	for _, f := range changes {
+		if f.Action == ActionAdd && locked[f.SeatID] {
+			f.Action, f.Note = ActionSkip, "seat_locked"
+			out.Unsafe = append(out.Unsafe, f)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// stalePending returns the PENDING bookings to mark BOOKED: created before
+// cutoff, still PENDING, and every seat marked for them. Partly marked ones
+// go to out.Unsafe; younger ones are left to their request.
+func (s *Service) stalePending(ctx context.Context, pending map[string][]string, cutoff time.Time, out *ShowtimeReconcile) []*model.Booking {
+	var confirm []*model.Booking
+	for bid, seats := range pending {
+		oid, err := primitive.ObjectIDFromHex(bid)
+		if err != nil {
+			continue
+		}
+		b, err := s.bookings.FindByID(ctx, oid)
+		if err != nil || b.Status != model.BookingPending || !b.CreatedAt.Before(cutoff) {
+			continue
+		}
+		complete := len(seats) == len(b.SeatIDs)
+		for _, sid := range b.SeatIDs {
+			if !slices.Contains(seats, sid) {
+				complete = false
+			}
+		}
+		if complete {
+			confirm = append(confirm, b)
+			continue
+		}
+		for _, sid := range seats {
+			out.Unsafe = append(out.Unsafe, Fix{SeatID: sid, Action: ActionSkip, Have: bid, Note: "pending_partly_booked"})
+		}
+	}
+	sort.Slice(confirm, func(i, j int) bool { return confirm[i].ID.Hex() < confirm[j].ID.Hex() })
+	return confirm
+}
+
+type bookingEvent struct {
+	Type      string   `json:"type"`
+	BookingID string   `json:"booking_id"`
+	Showtime  string   `json:"showtime_id"`
+	UserID    string   `json:"user_id"`
+	SeatIDs   []string `json:"seat_ids"`
+	Amount    int64    `json:"amount"`
+	Currency  string   `json:"currency"`
+	At        int64    `json:"at"`
+}
+
+func (s *Service) apply(ctx context.Context, showtimeID string, changes []Fix, confirm []*model.Booking, out *ShowtimeReconcile) {
+	mc := make([]seatlock.MarkerChange, 0, len(changes))
+	for _, f := range changes {
+		mc = append(mc, seatlock.MarkerChange{SeatID: f.SeatID, From: f.Have, To: f.Want})
+	}
+	applied, err := s.seats.ApplyMarkers(ctx, showtimeID, mc)
+	if err != nil {
+		out.Error = err.Error()
+	}
+	done := make(map[string]bool, len(applied))
+	for _, ch := range applied {
+		done[ch.SeatID] = true
+	}
+	for _, f := range changes {
+		f.Applied = done[f.SeatID]
+		out.Repaired = append(out.Repaired, f)
+	}
+
+	// the confirm died after payment and Redis: finish it like the handler
+	for _, b := range confirm {
+		ok, err := s.bookings.CompletePending(ctx, b.ID, "mock_"+uuid.NewString())
+		if err != nil {
+			out.Error = err.Error()
+			continue
+		}
+		if !ok {
+			continue
+		}
+		out.Confirmed = append(out.Confirmed, b.ID.Hex())
+
+		ev := bookingEvent{
+			Type:      "booking.success",
+			BookingID: b.ID.Hex(),
+			Showtime:  b.ShowtimeID,
+			UserID:    b.UserID.Hex(),
+			SeatIDs:   b.SeatIDs,
+			Amount:    b.Amount,
+			Currency:  b.Currency,
+			At:        time.Now().Unix(),
+		}
+		if raw, err := json.Marshal(ev); err == nil {
+			_ = s.rdb.Publish(ctx, "booking-events", raw).Err()
+		}
+		notify.Publish(ctx, s.rdb, notify.UserEvent{
+			Type:       notify.PaymentSucceeded,
+			UserID:     b.UserID.Hex(),
+			ShowtimeID: b.ShowtimeID,
+			SeatIDs:    b.SeatIDs,
+			RequestID:  b.RequestID,
+			BookingID:  b.ID.Hex(),
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package seatrepair
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDropLocked(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	seats := seatlock.New(rdb, time.Minute)
+	if err := seats.BeginRebuild(ctx, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := seats.LockSeats(ctx, "st1", []string{"A2"}, "u1", "r1"); err != nil || !ok {
+		t.Fatalf("lock: ok=%v err=%v", ok, err)
+	}
+	s := New(rdb, seats, nil, nil, nil)
+
+	changes := []Fix{
+		{SeatID: "A1", Action: ActionAdd, Want: "b1"},
+		{SeatID: "A2", Action: ActionAdd, Want: "b1"},
+		{SeatID: "A3", Action: ActionRemove, Have: "b2"},
+	}
+	out := &ShowtimeReconcile{}
+	kept, err := s.dropLocked(ctx, "st1", changes, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a marker under a live hold would break that hold's confirm
+	if want := []Fix{{SeatID: "A1", Action: ActionAdd, Want: "b1"}, {SeatID: "A3", Action: ActionRemove, Have: "b2"}}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept = %+v, want %+v", kept, want)
+	}
+	if want := []Fix{{SeatID: "A2", Action: ActionSkip, Want: "b1", Note: "seat_locked"}}; !reflect.DeepEqual(out.Unsafe, want) {
+		t.Fatalf("unsafe = %+v, want %+v", out.Unsafe, want)
+	}
+}
+
+func TestReconcileClean(t *testing.T) {
+	if r := (&ShowtimeReconcile{Markers: 4, BookedSeats: 4}); !r.clean() {
+		t.Fatal("nothing found, but not clean")
+	}
+	if r := (&ShowtimeReconcile{StalePending: []string{"b1"}}); r.clean() {
+		t.Fatal("stale PENDING booking counts as clean")
+	}
+}
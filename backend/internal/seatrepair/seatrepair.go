@@ -0,0 +1,259 @@
+package seatrepair
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Support tools for seat state that got out of hand: force-release locks
+// a customer can't clear, and make the seatbooked: markers of a showtime
+// match its BOOKED bookings in Mongo (the source of truth). Every applied
+// action is written to audit_logs with the admin and the reason. The
+// reconciler (reconcile.go) does the safe part of the repair on its own.
+
+// Fix actions
+const (
+	ActionAdd     = "add"     // BOOKED seat without a marker
+	ActionRemove  = "remove"  // marker of a booking that doesn't hold the seat
+	ActionReplace = "replace" // marker of another booking than the BOOKED one
+	ActionSkip    = "skip"    // not safe to change; Note says why
+)
+
+// Fix is one seat whose marker disagrees with Mongo.
+type Fix struct {
+	SeatID  string `json:"seat_id"`
+	Action  string `json:"action"`
+	Have    string `json:"have,omitempty"` // booking id in Redis
+	Want    string `json:"want,omitempty"` // booking id per Mongo
+	Note    string `json:"note,omitempty"`
+	Applied bool   `json:"applied"`
+}
+
+// Report is the outcome of a repair (or what it would do, when DryRun).
+type Report struct {
+	ShowtimeID  string `json:"showtime_id"`
+	DryRun      bool   `json:"dry_run"`
+	Markers     int    `json:"markers"`      // seatbooked: keys found
+	BookedSeats int    `json:"booked_seats"` // seats of BOOKED bookings
+	Fixes       []Fix  `json:"fixes"`
+	Applied     int    `json:"applied"`
+}
+
+type Service struct {
+	rdb       *redis.Client
+	seats     *seatlock.Service
+	bookings  *repo.BookingRepo
+	showtimes *repo.ShowtimeRepo
+	audits    *repo.AuditRepo
+}
+
+func New(
+	rdb *redis.Client,
+	seats *seatlock.Service,
+	bookings *repo.BookingRepo,
+	showtimes *repo.ShowtimeRepo,
+	audits *repo.AuditRepo,
+) *Service {
+	return &Service{rdb: rdb, seats: seats, bookings: bookings, showtimes: showtimes, audits: audits}
+}
+
+// ForceRelease removes the locks on seatIDs, or on every seat held by owner
+// when seatIDs is empty, whoever holds them. With dryRun it only lists them.
+func (s *Service) ForceRelease(ctx context.Context, showtimeID string, seatIDs []string, owner, adminID, reason string, dryRun bool) ([]seatlock.LockInfo, error) {
+	locks, err := s.seats.ListLocks(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]seatlock.LockInfo, 0)
+	for _, l := range locks {
+		if len(seatIDs) > 0 && !slices.Contains(seatIDs, l.SeatID) {
+			continue
+		}
+		if owner != "" && l.Owner != owner {
+			continue
+		}
+		targets = append(targets, l)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].SeatID < targets[j].SeatID })
+	if dryRun || len(targets) == 0 {
+		return targets, nil
+	}
+
+	ids := make([]string, 0, len(targets))
+	for _, l := range targets {
+		ids = append(ids, l.SeatID)
+	}
+	released, err := s.seats.ForceRelease(ctx, showtimeID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(released) > 0 {
+		ids = ids[:0]
+		for _, l := range released {
+			ids = append(ids, l.SeatID)
+		}
+		s.audit(ctx, "admin.seats_force_released", showtimeID, adminID, ids, map[string]any{
+			"reason": reason,
+			"owner":  owner,
+			"locks":  released,
+		})
+	}
+	return released, nil
+}
+
+// Check compares the seatbooked: markers of showtimeID with its BOOKED
+// bookings and returns the fixes needed (nothing applied).
+func (s *Service) Check(ctx context.Context, showtimeID string) (*Report, error) {
+	booked, err := s.bookings.FindBookedByShowtime(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	markers, err := s.seats.BookedMarkers(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{ShowtimeID: showtimeID, Markers: len(markers), Fixes: make([]Fix, 0)}
+
+	want := make(map[string]string)
+	doubled := make(map[string]bool)
+	bookedIDs := make(map[string]bool, len(booked))
+	for _, b := range booked {
+		bookedIDs[b.ID.Hex()] = true
+		for _, sid := range b.SeatIDs {
+			rep.BookedSeats++
+			if _, taken := want[sid]; taken {
+				doubled[sid] = true
+			}
+			want[sid] = b.ID.Hex()
+		}
+	}
+
+	// bookings the markers point at that are not BOOKED
+	others := make(map[string]*model.Booking)
+	var lookup []primitive.ObjectID
+	for _, bid := range markers {
+		if bookedIDs[bid] {
+			continue
+		}
+		if oid, err := primitive.ObjectIDFromHex(bid); err == nil {
+			lookup = append(lookup, oid)
+		}
+	}
+	found, err := s.bookings.FindByIDs(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		others[found[i].ID.Hex()] = &found[i]
+	}
+
+	seats := make([]string, 0, len(want)+len(markers))
+	for sid := range want {
+		seats = append(seats, sid)
+	}
+	for sid := range markers {
+		if _, ok := want[sid]; !ok {
+			seats = append(seats, sid)
+		}
+	}
+	sort.Strings(seats)
+
+	for _, sid := range seats {
+		have, w := markers[sid], want[sid]
+		switch {
+		case doubled[sid]:
+			rep.Fixes = append(rep.Fixes, Fix{SeatID: sid, Action: ActionSkip, Have: have, Note: "double_booked"})
+		case have == w:
+			// in sync
+		case have == "":
+			rep.Fixes = append(rep.Fixes, Fix{SeatID: sid, Action: ActionAdd, Want: w, Note: "missing_marker"})
+		case w != "":
+			rep.Fixes = append(rep.Fixes, Fix{SeatID: sid, Action: ActionReplace, Have: have, Want: w, Note: "other_booking"})
+		default:
+			rep.Fixes = append(rep.Fixes, staleMarker(sid, have, others[have]))
+		}
+	}
+	return rep, nil
+}
+
+// staleMarker decides what to do with a marker no BOOKED booking backs.
+func staleMarker(seatID, have string, b *model.Booking) Fix {
+	f := Fix{SeatID: seatID, Action: ActionRemove, Have: have}
+	switch {
+	case b == nil:
+		f.Note = "booking_not_found"
+	case b.Status == model.BookingPending:
+		// confirm in flight, or it died between Redis and Mongo
+		f.Action, f.Note = ActionSkip, "booking_pending"
+	case b.Status == model.BookingBooked:
+		f.Note = "seat_not_in_booking"
+	default:
+		f.Note = "booking_" + strings.ToLower(string(b.Status))
+	}
+	return f
+}
+
+// Repair runs Check and, unless dryRun, applies every fix that isn't a
+// skip. Markers changed since the check are left alone.
+func (s *Service) Repair(ctx context.Context, showtimeID, adminID, reason string, dryRun bool) (*Report, error) {
+	rep, err := s.Check(ctx, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	rep.DryRun = dryRun
+	if dryRun {
+		return rep, nil
+	}
+
+	changes := make([]seatlock.MarkerChange, 0, len(rep.Fixes))
+	for _, f := range rep.Fixes {
+		if f.Action != ActionSkip {
+			changes = append(changes, seatlock.MarkerChange{SeatID: f.SeatID, From: f.Have, To: f.Want})
+		}
+	}
+	applied, err := s.seats.ApplyMarkers(ctx, showtimeID, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]bool, len(applied))
+	seatIDs := make([]string, 0, len(applied))
+	for _, ch := range applied {
+		done[ch.SeatID] = true
+		seatIDs = append(seatIDs, ch.SeatID)
+	}
+	for i := range rep.Fixes {
+		rep.Fixes[i].Applied = done[rep.Fixes[i].SeatID]
+	}
+	rep.Applied = len(applied)
+
+	if rep.Applied > 0 {
+		s.audit(ctx, "admin.seats_repaired", showtimeID, adminID, seatIDs, map[string]any{
+			"reason": reason,
+			"fixes":  rep.Fixes,
+		})
+	}
+	return rep, nil
+}
+
+func (s *Service) audit(ctx context.Context, typ, showtimeID, adminID string, seatIDs []string, payload map[string]any) {
+	payload["admin_id"] = adminID
+	_ = s.audits.Insert(ctx, &model.AuditLog{
+		Type:       typ,
+		ShowtimeID: showtimeID,
+		UserID:     adminID,
+		SeatIDs:    seatIDs,
+		Payload:    payload,
+		At:         time.Now(),
+	})
+}
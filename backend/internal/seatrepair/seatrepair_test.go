@@ -0,0 +1,30 @@
+package seatrepair
+
+import (
+	"cinema/internal/model"
+	"testing"
+)
+
+func TestStaleMarker(t *testing.T) {
+	tests := []struct {
+		name   string
+		b      *model.Booking
+		action string
+		note   string
+	}{
+		{name: "no booking", b: nil, action: ActionRemove, note: "booking_not_found"},
+		{name: "confirm in flight", b: &model.Booking{Status: model.BookingPending}, action: ActionSkip, note: "booking_pending"},
+		{name: "booked elsewhere", b: &model.Booking{Status: model.BookingBooked}, action: ActionRemove, note: "seat_not_in_booking"},
+		{name: "cancelled", b: &model.Booking{Status: model.BookingCancelled}, action: ActionRemove, note: "booking_cancelled"},
+		{name: "failed", b: &model.Booking{Status: model.BookingFailed}, action: ActionRemove, note: "booking_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := staleMarker("A1", "b1", tt.b)
+			if f.SeatID != "A1" || f.Have != "b1" || f.Action != tt.action || f.Note != tt.note {
+				t.Fatalf("staleMarker = %+v, want %s/%s", f, tt.action, tt.note)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package showtime
+
+import (
+	"cinema/internal/model"
+	"context"
+	"log"
+	"time"
+)
+
+const scheduleEvery = 10 * time.Second
+
+// Run moves showtimes through their lifecycle until ctx is cancelled:
+// sales close at SalesCloseAt, the screening starts at StartsAt, and once
+// it has ended (or was cancelled) its seat keys are deleted from Redis.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduleEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Service) tick(ctx context.Context) {
+	now := time.Now()
+	due, err := s.repo.FindDue(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("showtime scheduler failed:", err)
+		}
+		return
+	}
+
+	for i := range due {
+		if err := s.step(ctx, &due[i], now); err != nil && ctx.Err() == nil {
+			log.Printf("showtime %s: %v", due[i].ID, err)
+		}
+	}
+}
+
+// step applies every transition st is due for at now.
+func (s *Service) step(ctx context.Context, st *model.Showtime, now time.Time) error {
+	if st.Status == model.ShowtimeOnSale && !now.Before(st.SalesCloseAt) && now.Before(st.StartsAt) {
+		ok, err := s.repo.Transition(ctx, st.ID, model.ShowtimeOnSale, model.ShowtimeSalesClosed, nil)
+		if err != nil || !ok {
+			return err
+		}
+		return s.endSales(ctx, st.ID)
+	}
+
+	if (st.Status == model.ShowtimeOnSale || st.Status == model.ShowtimeSalesClosed) && !now.Before(st.StartsAt) {
+		ok, err := s.repo.Transition(ctx, st.ID, st.Status, model.ShowtimeStarted, nil)
+		if err != nil || !ok {
+			return err
+		}
+		st.Status = model.ShowtimeStarted
+		if err := s.endSales(ctx, st.ID); err != nil {
+			return err
+		}
+	}
+
+	if (st.Status == model.ShowtimeStarted || st.Status == model.ShowtimeCancelled) && !now.Before(st.EndsAt) && st.CleanedAt == nil {
+		n, err := s.seats.PurgeShowtime(ctx, st.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.MarkCleaned(ctx, st.ID); err != nil {
+			return err
+		}
+		log.Printf("showtime %s ended: %d seat keys removed", st.ID, n)
+	}
+	return nil
+}
+
+func (s *Service) endSales(ctx context.Context, id string) error {
+	if s.salesEnded == nil {
+		return nil
+	}
+	return s.salesEnded(ctx, id)
+}
@@ -0,0 +1,263 @@
+package showtime
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Showtime lifecycle: DRAFT -> ON_SALE (published by an admin) ->
+// SALES_CLOSED (at SalesCloseAt) -> STARTED (at StartsAt); CANCELLED from
+// any state before STARTED. Seats can be locked and bought only while
+// ON_SALE and inside [SalesOpenAt, SalesCloseAt). The scheduler (see Run)
+// moves showtimes along and removes their seat keys after EndsAt. Showtime
+// ids without a document (the demo SHOW1) are unmanaged: always on sale.
+
+// Error is a showtime rejection; Code is the API error string.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string { return "showtime: " + e.Code }
+
+var (
+	ErrNotFound        = &Error{Code: "showtime_not_found"}
+	ErrExists          = &Error{Code: "showtime_exists"}
+	ErrInvalidSchedule = &Error{Code: "invalid_schedule"}
+	ErrNotEditable     = &Error{Code: "showtime_not_editable"}
+	ErrNotPublished    = &Error{Code: "showtime_not_published"}
+	ErrSalesNotOpen    = &Error{Code: "sales_not_open"}
+	ErrSalesClosed     = &Error{Code: "sales_closed"}
+	ErrStarted         = &Error{Code: "showtime_started"}
+	ErrCancelled       = &Error{Code: "showtime_cancelled"}
+)
+
+// schedule fields can change until sales close
+var editable = []model.ShowtimeStatus{model.ShowtimeDraft, model.ShowtimeOnSale}
+
+type Service struct {
+	repo       *repo.ShowtimeRepo
+	seats      *seatlock.Service
+	salesEnded func(ctx context.Context, id string) error // nil = nothing to do
+}
+
+func New(r *repo.ShowtimeRepo, seats *seatlock.Service) *Service {
+	return &Service{repo: r, seats: seats}
+}
+
+// OnSalesEnded registers fn, called by the scheduler when a showtime moves
+// to SALES_CLOSED or STARTED (e.g. to drop its waitlist).
+func (s *Service) OnSalesEnded(fn func(ctx context.Context, id string) error) *Service {
+	s.salesEnded = fn
+	return s
+}
+
+// ValidSchedule reports SalesOpenAt < SalesCloseAt <= StartsAt < EndsAt.
+func ValidSchedule(st *model.Showtime) bool {
+	return st.SalesOpenAt.Before(st.SalesCloseAt) &&
+		!st.SalesCloseAt.After(st.StartsAt) &&
+		st.StartsAt.Before(st.EndsAt)
+}
+
+// SalesError says why st can't sell seats at now (nil = it can).
+func SalesError(st *model.Showtime, now time.Time) error {
+	switch st.Status {
+	case model.ShowtimeDraft:
+		return ErrNotPublished
+	case model.ShowtimeCancelled:
+		return ErrCancelled
+	case model.ShowtimeStarted:
+		return ErrStarted
+	case model.ShowtimeSalesClosed:
+		return ErrSalesClosed
+	}
+	// the scheduler may not have caught up yet
+	switch {
+	case !now.Before(st.StartsAt):
+		return ErrStarted
+	case !now.Before(st.SalesCloseAt):
+		return ErrSalesClosed
+	case now.Before(st.SalesOpenAt):
+		return ErrSalesNotOpen
+	}
+	return nil
+}
+
+// SalesOver reports whether err (from SalesError/CheckSales) means the
+// showtime will never sell seats again.
+func SalesOver(err error) bool {
+	return errors.Is(err, ErrSalesClosed) || errors.Is(err, ErrStarted) || errors.Is(err, ErrCancelled)
+}
+
+// CheckSales returns the showtime if seats of it can be sold at now, or the
+// reason they can't. Unmanaged showtimes (no document) return nil, nil.
+func (s *Service) CheckSales(ctx context.Context, id string, now time.Time) (*model.Showtime, error) {
+	st, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return st, SalesError(st, now)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*model.Showtime, error) {
+	st, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return st, err
+}
+
+// Hall returns the hall of a showtime ("" if unmanaged or not set); it is
+// the seatlock.HallLookup for hall-wide seat blocks.
+func (s *Service) Hall(ctx context.Context, id string) (string, error) {
+	st, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return st.Hall, nil
+}
+
+// HallShowtimes lists the ids of the showtimes in hall that haven't ended.
+func (s *Service) HallShowtimes(ctx context.Context, hall string) ([]string, error) {
+	return s.repo.FindHallIDs(ctx, hall, time.Now())
+}
+
+func (s *Service) List(ctx context.Context, f repo.ShowtimeFilter) ([]model.Showtime, int64, error) {
+	return s.repo.Find(ctx, f)
+}
+
+// Create inserts st as DRAFT. Sales open now and close at StartsAt unless set.
+func (s *Service) Create(ctx context.Context, st *model.Showtime) error {
+	if st.SalesOpenAt.IsZero() {
+		st.SalesOpenAt = time.Now()
+	}
+	if st.SalesCloseAt.IsZero() {
+		st.SalesCloseAt = st.StartsAt
+	}
+	if !ValidSchedule(st) {
+		return ErrInvalidSchedule
+	}
+	st.Status = model.ShowtimeDraft
+
+	err := s.repo.Create(ctx, st)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrExists
+	}
+	return err
+}
+
+// Schedule changes the times of a DRAFT or ON_SALE showtime (nil = keep).
+func (s *Service) Schedule(ctx context.Context, id string, startsAt, endsAt, salesOpenAt, salesCloseAt *time.Time) (*model.Showtime, error) {
+	cur, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *cur
+	set := bson.M{}
+	if startsAt != nil {
+		next.StartsAt, set["starts_at"] = *startsAt, *startsAt
+	}
+	if endsAt != nil {
+		next.EndsAt, set["ends_at"] = *endsAt, *endsAt
+	}
+	if salesOpenAt != nil {
+		next.SalesOpenAt, set["sales_open_at"] = *salesOpenAt, *salesOpenAt
+	}
+	if salesCloseAt != nil {
+		next.SalesCloseAt, set["sales_close_at"] = *salesCloseAt, *salesCloseAt
+	}
+	if !ValidSchedule(&next) {
+		return nil, ErrInvalidSchedule
+	}
+	if len(set) == 0 {
+		return cur, nil
+	}
+
+	st, err := s.repo.Update(ctx, id, editable, set)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotEditable
+	}
+	return st, err
+}
+
+// Cancel stops all sales of a showtime that hasn't started. Cancelling a
+// cancelled showtime returns it unchanged (the caller may resume the
+// follow-up work). An unmanaged showtime gets a CANCELLED document, ending
+// right away so the scheduler cleans up its seat keys.
+func (s *Service) Cancel(ctx context.Context, id, adminID, reason string) (*model.Showtime, error) {
+	now := time.Now()
+	set := bson.M{
+		"status":        model.ShowtimeCancelled,
+		"cancelled_at":  now,
+		"cancelled_by":  adminID,
+		"cancel_reason": reason,
+	}
+	st, err := s.repo.Update(ctx, id, []model.ShowtimeStatus{
+		model.ShowtimeDraft,
+		model.ShowtimeOnSale,
+		model.ShowtimeSalesClosed,
+	}, set)
+	if err == nil || !errors.Is(err, mongo.ErrNoDocuments) {
+		return st, err
+	}
+
+	cur, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		st := &model.Showtime{
+			ID:           id,
+			Status:       model.ShowtimeCancelled,
+			StartsAt:     now,
+			EndsAt:       now,
+			SalesOpenAt:  now,
+			SalesCloseAt: now,
+			CancelledAt:  &now,
+			CancelledBy:  adminID,
+			CancelReason: reason,
+			CreatedBy:    adminID,
+		}
+		err := s.repo.Create(ctx, st)
+		if mongo.IsDuplicateKeyError(err) {
+			return s.Cancel(ctx, id, adminID, reason) // created meanwhile
+		}
+		return st, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch cur.Status {
+	case model.ShowtimeCancelled:
+		return cur, nil
+	case model.ShowtimeStarted:
+		return nil, ErrStarted
+	}
+	return nil, ErrNotEditable
+}
+
+// Publish puts a DRAFT showtime on sale (bookable once SalesOpenAt passes).
+func (s *Service) Publish(ctx context.Context, id string) (*model.Showtime, error) {
+	ok, err := s.repo.Transition(ctx, id, model.ShowtimeDraft, model.ShowtimeOnSale, nil)
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotEditable
+	}
+	return st, nil
+}
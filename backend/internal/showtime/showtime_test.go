@@ -0,0 +1,68 @@
+package showtime
+
+import (
+	"cinema/internal/model"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSalesError(t *testing.T) {
+	opens := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	closes := time.Date(2026, 5, 10, 19, 30, 0, 0, time.UTC)
+	starts := time.Date(2026, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status model.ShowtimeStatus
+		now    time.Time
+		want   error
+	}{
+		{name: "on sale", status: model.ShowtimeOnSale, now: opens.Add(time.Hour)},
+		{name: "opens exactly now", status: model.ShowtimeOnSale, now: opens},
+		{name: "before the window", status: model.ShowtimeOnSale, now: opens.Add(-time.Minute), want: ErrSalesNotOpen},
+		{name: "window closed, scheduler behind", status: model.ShowtimeOnSale, now: closes, want: ErrSalesClosed},
+		{name: "started, scheduler behind", status: model.ShowtimeOnSale, now: starts, want: ErrStarted},
+		{name: "draft", status: model.ShowtimeDraft, now: opens.Add(time.Hour), want: ErrNotPublished},
+		{name: "cancelled", status: model.ShowtimeCancelled, now: opens.Add(time.Hour), want: ErrCancelled},
+		{name: "closed by the scheduler", status: model.ShowtimeSalesClosed, now: opens.Add(time.Hour), want: ErrSalesClosed},
+		{name: "started by the scheduler", status: model.ShowtimeStarted, now: opens.Add(time.Hour), want: ErrStarted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &model.Showtime{
+				Status:       tt.status,
+				SalesOpenAt:  opens,
+				SalesCloseAt: closes,
+				StartsAt:     starts,
+			}
+			if got := SalesError(st, tt.now); got != tt.want {
+				t.Fatalf("SalesError = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSalesOver(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: ErrSalesNotOpen, want: false},
+		{err: ErrNotPublished, want: false},
+		{err: ErrSalesClosed, want: true},
+		{err: ErrStarted, want: true},
+		{err: ErrCancelled, want: true},
+		{err: fmt.Errorf("cancel: %w", ErrStarted), want: true},
+		{err: errors.New("mongo down"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := SalesOver(tt.err); got != tt.want {
+			t.Errorf("SalesOver(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	recoverEvery = 30 * time.Second
+	// an acceptance still ACCEPTING after this was interrupted
+	staleAfter   = time.Minute
+	recoverBatch = 50
+)
+
+// Run finishes interrupted acceptances until ctx is cancelled: a crash
+// between the steps of Accept leaves the transfer ACCEPTING, and complete
+// picks it up from the step it stopped at.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(recoverEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverStale(ctx)
+		}
+	}
+}
+
+func (s *Service) recoverStale(ctx context.Context) {
+	stale, err := s.transfers.FindStaleAccepting(ctx, time.Now().Add(-staleAfter), recoverBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("transfer recovery failed:", err)
+		}
+		return
+	}
+	for i := range stale {
+		t := &stale[i]
+		_, err := s.complete(ctx, t)
+		var te *Error
+		if err != nil && !errors.As(err, &te) {
+			log.Printf("transfer %s: %v", t.ID.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package transfer
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/notify"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Ticket transfers. The owner of a booking offers some of its seats to an
+// email address or to whoever opens the claim link. On acceptance the seats
+// leave the sender's booking (its ticket version is bumped, so every old
+// ticket of it stops verifying), a new BOOKED booking is created for the
+// recipient, the seatbooked: markers move to it, and both sides are written
+// to audit_logs. Offers expire after DefaultTTL and can be withdrawn by the
+// sender until accepted; both sides get transfer.* private events.
+
+// Error is a transfer rejection; Code is the API error string.
+type Error struct {
+	Code string
+}
+
+func (e *Error) Error() string { return "transfer: " + e.Code }
+
+var (
+	ErrNotFound     = &Error{Code: "transfer_not_found"}
+	ErrNotOwner     = &Error{Code: "booking_not_found"}
+	ErrNotBooked    = &Error{Code: "booking_not_transferable"}
+	ErrSeatNotInBkg = &Error{Code: "seat_not_in_booking"}
+	ErrNotPending   = &Error{Code: "transfer_not_pending"}
+	ErrExpired      = &Error{Code: "transfer_expired"}
+	ErrNotRecipient = &Error{Code: "not_transfer_recipient"}
+	ErrSelf         = &Error{Code: "transfer_to_self"}
+)
+
+const DefaultTTL = 48 * time.Hour
+
+type Service struct {
+	rdb       *redis.Client
+	seats     *seatlock.Service
+	bookings  *repo.BookingRepo
+	transfers *repo.TransferRepo
+	users     *repo.UserRepo
+	audits    *repo.AuditRepo
+	ttl       time.Duration
+}
+
+func New(
+	rdb *redis.Client,
+	seats *seatlock.Service,
+	bookings *repo.BookingRepo,
+	transfers *repo.TransferRepo,
+	users *repo.UserRepo,
+	audits *repo.AuditRepo,
+) *Service {
+	return &Service{
+		rdb:       rdb,
+		seats:     seats,
+		bookings:  bookings,
+		transfers: transfers,
+		users:     users,
+		audits:    audits,
+		ttl:       DefaultTTL,
+	}
+}
+
+// NormalizeEmail trims and lowercases an address.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Offer creates a pending transfer of seatIDs of bookingID from userID.
+// toEmail may be empty (claim link only).
+func (s *Service) Offer(ctx context.Context, bookingID primitive.ObjectID, userID string, seatIDs []string, toEmail string) (*model.TicketTransfer, error) {
+	b, err := s.bookings.FindByID(ctx, bookingID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && b.UserID.Hex() != userID) {
+		return nil, ErrNotOwner
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.Status != model.BookingBooked {
+		return nil, ErrNotBooked
+	}
+	for _, sid := range seatIDs {
+		if !slices.Contains(b.SeatIDs, sid) {
+			return nil, ErrSeatNotInBkg
+		}
+	}
+
+	toEmail = NormalizeEmail(toEmail)
+	var recipient *model.User
+	if toEmail != "" {
+		recipient, err = s.users.FindByEmail(ctx, toEmail)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if recipient != nil && recipient.ID.Hex() == userID {
+			return nil, ErrSelf
+		}
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	t := &model.TicketTransfer{
+		BookingID:  b.ID,
+		ShowtimeID: b.ShowtimeID,
+		FromUserID: userID,
+		SeatIDs:    seatIDs,
+		ToEmail:    toEmail,
+		Token:      hex.EncodeToString(token),
+		ExpiresAt:  time.Now().Add(s.ttl),
+	}
+	if err := s.transfers.Create(ctx, t); err != nil {
+		return nil, err
+	}
+
+	// recipients who already have an account see it right away
+	if recipient != nil {
+		notify.Publish(ctx, s.rdb, notify.UserEvent{
+			Type:       notify.TransferOffered,
+			UserID:     recipient.ID.Hex(),
+			ShowtimeID: t.ShowtimeID,
+			SeatIDs:    t.SeatIDs,
+			TransferID: t.ID.Hex(),
+			ExpiresAt:  t.ExpiresAt.Unix(),
+		})
+	}
+	return t, nil
+}
+
+// Incoming lists pending transfers addressed to email.
+func (s *Service) Incoming(ctx context.Context, email string) ([]model.TicketTransfer, error) {
+	return s.transfers.FindPendingForEmail(ctx, NormalizeEmail(email))
+}
+
+// Get loads a transfer for viewer: the sender, the addressed email, or
+// anyone with the claim token.
+func (s *Service) Get(ctx context.Context, id primitive.ObjectID, viewer *model.User, token string) (*model.TicketTransfer, error) {
+	t, err := s.transfers.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.FromUserID == viewer.ID.Hex() || s.isRecipient(t, viewer, token) {
+		return t, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Service) isRecipient(t *model.TicketTransfer, u *model.User, token string) bool {
+	if t.ToEmail != "" && NormalizeEmail(u.Email) == t.ToEmail {
+		return true
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1
+}
+
+// Accept moves the transfer's seats to recipient and returns their new booking.
+func (s *Service) Accept(ctx context.Context, id primitive.ObjectID, recipient *model.User, token string) (*model.Booking, error) {
+	t, err := s.Get(ctx, id, recipient, token)
+	if err != nil {
+		return nil, err
+	}
+	toUser := recipient.ID.Hex()
+	if t.FromUserID == toUser {
+		return nil, ErrSelf
+	}
+	if !s.isRecipient(t, recipient, token) {
+		return nil, ErrNotRecipient
+	}
+	if time.Now().After(t.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	// 1) claim (only one acceptance wins); the new booking id is fixed here
+	// so every later step can be redone
+	nbID := primitive.NewObjectID()
+	ok, err := s.transfers.Transition(ctx, t.ID, model.TransferPending, model.TransferAccepting, bson.M{"to_user_id": toUser, "new_booking_id": nbID})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotPending
+	}
+	t.ToUserID = toUser
+	t.NewBookingID = &nbID
+
+	return s.complete(ctx, t)
+}
+
+// complete runs the steps of an acceptance claimed in t. Each one can run
+// again for the same transfer, so an error leaves t ACCEPTING and the
+// recovery sweep (Run) finishes it; a conflict undoes the claim.
+func (s *Service) complete(ctx context.Context, t *model.TicketTransfer) (*model.Booking, error) {
+	if t.NewBookingID == nil {
+		s.unclaim(ctx, t)
+		return nil, ErrNotPending
+	}
+
+	// 2) seats leave the sender's booking (old tickets invalid from here)
+	src, err := s.bookings.RemoveSeats(ctx, t.BookingID, t.SeatIDs, t.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		src, err = s.bookings.FindByID(ctx, t.BookingID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if err != nil || !slices.Contains(src.TransferIDs, t.ID) {
+			// cancelled, or seats already gone: the offer is void
+			_, _ = s.transfers.Transition(ctx, t.ID, model.TransferAccepting, model.TransferCancelled, nil)
+			return nil, ErrNotBooked
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3) recipient's booking (paid by the sender)
+	uid, _ := primitive.ObjectIDFromHex(t.ToUserID)
+	nb := &model.Booking{
+		ID:              *t.NewBookingID,
+		ShowtimeID:      t.ShowtimeID,
+		UserID:          uid,
+		SeatIDs:         t.SeatIDs,
+		Currency:        src.Currency,
+		RequestID:       "transfer-" + t.ID.Hex(),
+		TransferredFrom: &src.ID,
+	}
+	err = s.bookings.CreateBooked(ctx, nb)
+	if mongo.IsDuplicateKeyError(err) {
+		nb, err = s.bookings.FindByID(ctx, *t.NewBookingID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch nb.Status {
+	case model.BookingBooked:
+		// 4) Redis markers follow the seats
+		if err := s.moveMarkers(ctx, t, src, nb); err != nil {
+			if errors.Is(err, ErrNotBooked) {
+				s.rollback(ctx, t, src, nb)
+			}
+			return nil, err
+		}
+	case model.BookingFailed:
+		// an undo was interrupted: finish it
+		s.rollback(ctx, t, src, nb)
+		return nil, ErrNotBooked
+	}
+
+	// 5) done (the recipient may already have cancelled: it was theirs)
+	ok, err := s.transfers.Transition(ctx, t.ID, model.TransferAccepting, model.TransferAccepted, bson.M{"new_booking_id": nb.ID})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nb, nil
+	}
+	s.audit(ctx, t, src, nb)
+
+	notify.Publish(ctx, s.rdb, notify.UserEvent{
+		Type:       notify.TransferAccepted,
+		UserID:     t.FromUserID,
+		ShowtimeID: t.ShowtimeID,
+		SeatIDs:    t.SeatIDs,
+		BookingID:  src.ID.Hex(),
+		TransferID: t.ID.Hex(),
+	})
+	return nb, nil
+}
+
+// moveMarkers points the seats' seatbooked: markers at nb; markers that
+// already do (an earlier attempt) count as moved. ErrNotBooked if a seat
+// belongs to neither booking.
+func (s *Service) moveMarkers(ctx context.Context, t *model.TicketTransfer, src, nb *model.Booking) error {
+	moved, _, err := s.seats.ReassignBooked(ctx, t.ShowtimeID, t.SeatIDs, src.ID.Hex(), nb.ID.Hex(), t.ToUserID)
+	if err != nil || moved {
+		return err
+	}
+	markers, err := s.seats.BookedMarkers(ctx, t.ShowtimeID)
+	if err != nil {
+		return err
+	}
+	for _, sid := range t.SeatIDs {
+		if markers[sid] != nb.ID.Hex() {
+			return ErrNotBooked
+		}
+	}
+	return nil
+}
+
+// rollback undoes steps 3 and 2 and releases the claim.
+func (s *Service) rollback(ctx context.Context, t *model.TicketTransfer, src, nb *model.Booking) {
+	_ = s.bookings.MarkFailed(ctx, nb.ID)
+	if err := s.bookings.RestoreSeats(ctx, src.ID, t.SeatIDs, t.ID); err != nil {
+		return
+	}
+	s.unclaim(ctx, t)
+}
+
+// Cancel withdraws a pending transfer (sender only).
+func (s *Service) Cancel(ctx context.Context, id primitive.ObjectID, userID string) error {
+	t, err := s.transfers.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && t.FromUserID != userID) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	ok, err := s.transfers.Transition(ctx, t.ID, model.TransferPending, model.TransferCancelled, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotPending
+	}
+	return nil
+}
+
+func (s *Service) unclaim(ctx context.Context, t *model.TicketTransfer) {
+	_, _ = s.transfers.Transition(ctx, t.ID, model.TransferAccepting, model.TransferPending, bson.M{"to_user_id": "", "new_booking_id": nil})
+}
+
+// audit writes both sides of an accepted transfer to audit_logs.
+func (s *Service) audit(ctx context.Context, t *model.TicketTransfer, src, nb *model.Booking) {
+	now := time.Now()
+	payload := map[string]any{
+		"transfer_id":    t.ID.Hex(),
+		"from_user_id":   t.FromUserID,
+		"to_user_id":     nb.UserID.Hex(),
+		"from_booking":   src.ID.Hex(),
+		"to_booking":     nb.ID.Hex(),
+		"ticket_version": src.TicketVersion,
+	}
+	_ = s.audits.Insert(ctx, &model.AuditLog{
+		Type:       "ticket.transfer_out",
+		ShowtimeID: t.ShowtimeID,
+		BookingID:  src.ID.Hex(),
+		UserID:     t.FromUserID,
+		SeatIDs:    t.SeatIDs,
+		Payload:    payload,
+		At:         now,
+	})
+	_ = s.audits.Insert(ctx, &model.AuditLog{
+		Type:       "ticket.transfer_in",
+		ShowtimeID: t.ShowtimeID,
+		BookingID:  nb.ID.Hex(),
+		UserID:     nb.UserID.Hex(),
+		SeatIDs:    t.SeatIDs,
+		Payload:    payload,
+		At:         now,
+	})
+}
@@ -0,0 +1,46 @@
+package transfer
+
+import (
+	"cinema/internal/model"
+	"cinema/internal/seatlock"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoveMarkersIsRepeatable(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	s := &Service{rdb: rdb, seats: seatlock.New(rdb, time.Minute)}
+	src := &model.Booking{ID: primitive.NewObjectID()}
+	nb := &model.Booking{ID: primitive.NewObjectID()}
+	tr := &model.TicketTransfer{ShowtimeID: "st1", SeatIDs: []string{"A1", "A2"}, ToUserID: "u2"}
+	for _, sid := range tr.SeatIDs {
+		mr.Set("seatbooked:st1:"+sid, src.ID.Hex())
+	}
+
+	if err := s.moveMarkers(ctx, tr, src, nb); err != nil {
+		t.Fatalf("moveMarkers = %v", err)
+	}
+	// redone by the recovery sweep: the markers already point at nb
+	if err := s.moveMarkers(ctx, tr, src, nb); err != nil {
+		t.Fatalf("moveMarkers again = %v", err)
+	}
+	if v, _ := mr.Get("seatbooked:st1:A2"); v != nb.ID.Hex() {
+		t.Fatalf("A2 marker = %q", v)
+	}
+
+	// a seat that belongs to neither booking voids the transfer
+	mr.Set("seatbooked:st1:A2", "someone-else")
+	if err := s.moveMarkers(ctx, tr, src, nb); !errors.Is(err, ErrNotBooked) {
+		t.Fatalf("moveMarkers = %v, want %v", err, ErrNotBooked)
+	}
+}
@@ -0,0 +1,356 @@
+package waitlist
+
+import (
+	"cinema/internal/notify"
+	"cinema/internal/seatlock"
+	"cinema/internal/showtime"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Waitlist for sold-out showtimes. When seats free up, the worker offers
+// them to waiting users in FIFO order by locking them in the user's name
+// for a short window (a normal seat lock, claimed via booking confirm).
+// An unclaimed offer times out like any hold and the seats go to the next
+// user.
+//
+//	waitlist:<showtimeId>             ZSET   member = userId, score = joined ms
+//	                                         (minus priorityOffset for priority members)
+//	waitlistreq:<showtimeId>          HASH   userId -> Entry JSON
+//	waitlistoffer:<showtimeId>:<uid>  STRING Offer JSON, expires with the hold
+
+// MaxPartySize caps seats per waitlist entry.
+const MaxPartySize = 10
+
+// AnySeatType matches every row of the seat map.
+const AnySeatType = "any"
+
+// priority entries sort before every regular one, FIFO among themselves
+const priorityOffset = 1e13
+
+// max entries looked at per offer round
+const roundLimit = 200
+
+var (
+	ErrInvalidSeatType = errors.New("invalid seat type")
+	ErrOfferPending    = errors.New("offer pending")
+	ErrSeatsAvailable  = errors.New("seats available")
+)
+
+func queueKey(showtimeID string) string {
+	return fmt.Sprintf("waitlist:%s", showtimeID)
+}
+
+func entriesKey(showtimeID string) string {
+	return fmt.Sprintf("waitlistreq:%s", showtimeID)
+}
+
+func offerKey(showtimeID, userID string) string {
+	return fmt.Sprintf("waitlistoffer:%s:%s", showtimeID, userID)
+}
+
+type Entry struct {
+	UserID    string `json:"user_id"`
+	PartySize int    `json:"party_size"`
+	SeatType  string `json:"seat_type"`
+	JoinedAt  int64  `json:"joined_at"` // unix seconds
+	Priority  bool   `json:"priority,omitempty"`
+}
+
+// Offer is a reserved hold placed for a waitlisted user.
+type Offer struct {
+	SeatIDs   []string `json:"seat_ids"`
+	RequestID string   `json:"request_id"` // pass to bookings/confirm
+	ExpiresAt int64    `json:"expires_at"` // unix seconds
+}
+
+type Status struct {
+	Position int64  `json:"position,omitempty"` // 1-based, 0 = not queued
+	Entry    *Entry `json:"entry,omitempty"`
+	Offer    *Offer `json:"offer,omitempty"`
+}
+
+type Service struct {
+	rdb       *redis.Client
+	seats     *seatlock.Service
+	seatMap   *seatlock.SeatMap
+	offerTTL  time.Duration
+	showtimes *showtime.Service // sales window checks (nil = always on sale)
+}
+
+func New(rdb *redis.Client, seats *seatlock.Service, seatMap *seatlock.SeatMap, offerTTL time.Duration) *Service {
+	return &Service{rdb: rdb, seats: seats, seatMap: seatMap, offerTTL: offerTTL}
+}
+
+// WithShowtimes makes Join and offers respect the showtime lifecycle.
+func (s *Service) WithShowtimes(st *showtime.Service) *Service {
+	s.showtimes = st
+	return s
+}
+
+// checkSales returns the *showtime.Error if showtimeID can't sell seats now.
+func (s *Service) checkSales(ctx context.Context, showtimeID string) error {
+	if s.showtimes == nil {
+		return nil
+	}
+	_, err := s.showtimes.CheckSales(ctx, showtimeID, time.Now())
+	return err
+}
+
+// Join queues userID for partySize seats of seatType ("" = any); priority
+// users go ahead of regular ones. Joining again keeps the original position
+// and updates the preferences.
+func (s *Service) Join(ctx context.Context, showtimeID, userID string, partySize int, seatType string, priority bool) (int64, error) {
+	if seatType == "" {
+		seatType = AnySeatType
+	}
+	if seatType != AnySeatType && !s.seatMap.HasSeatType(seatType) {
+		return 0, ErrInvalidSeatType
+	}
+	if err := s.checkSales(ctx, showtimeID); err != nil {
+		return 0, err
+	}
+
+	if n, err := s.rdb.Exists(ctx, offerKey(showtimeID, userID)).Result(); err != nil {
+		return 0, err
+	} else if n == 1 {
+		return 0, ErrOfferPending
+	}
+
+	entry := Entry{
+		UserID:    userID,
+		PartySize: partySize,
+		SeatType:  seatType,
+		JoinedAt:  time.Now().Unix(),
+		Priority:  priority,
+	}
+
+	// only for sold-out showtimes: otherwise just lock the seats
+	free, err := s.freeSeats(ctx, showtimeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(s.candidates(entry, free)) > 0 {
+		return 0, ErrSeatsAvailable
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, entriesKey(showtimeID), userID, b)
+	score := float64(time.Now().UnixMilli())
+	if priority {
+		score -= priorityOffset
+	}
+	pipe.ZAddNX(ctx, queueKey(showtimeID), redis.Z{Score: score, Member: userID})
+	rank := pipe.ZRank(ctx, queueKey(showtimeID), userID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return rank.Val() + 1, nil
+}
+
+// Leave removes userID from the queue. A pending offer stays a normal hold
+// the user may confirm or release.
+func (s *Service) Leave(ctx context.Context, showtimeID, userID string) error {
+	pipe := s.rdb.TxPipeline()
+	pipe.ZRem(ctx, queueKey(showtimeID), userID)
+	pipe.HDel(ctx, entriesKey(showtimeID), userID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Clear drops the whole queue of a showtime (cancelled, or sales over).
+// Pending offers are seat holds and go with the showtime's locks.
+func (s *Service) Clear(ctx context.Context, showtimeID string) error {
+	return s.rdb.Del(ctx, queueKey(showtimeID), entriesKey(showtimeID)).Err()
+}
+
+// Status returns userID's queue position and/or pending offer.
+func (s *Service) Status(ctx context.Context, showtimeID, userID string) (*Status, error) {
+	pipe := s.rdb.Pipeline()
+	rankCmd := pipe.ZRank(ctx, queueKey(showtimeID), userID)
+	entryCmd := pipe.HGet(ctx, entriesKey(showtimeID), userID)
+	offerCmd := pipe.Get(ctx, offerKey(showtimeID, userID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	st := &Status{}
+	if rank, err := rankCmd.Result(); err == nil {
+		st.Position = rank + 1
+	}
+	if raw, err := entryCmd.Result(); err == nil {
+		var e Entry
+		if json.Unmarshal([]byte(raw), &e) == nil {
+			st.Entry = &e
+		}
+	}
+	if raw, err := offerCmd.Result(); err == nil {
+		var o Offer
+		if json.Unmarshal([]byte(raw), &o) == nil {
+			st.Offer = &o
+		}
+	}
+	return st, nil
+}
+
+// OfferRound offers the showtime's free seats to waiting users, FIFO.
+// A user whose party/seat type cannot be served yet keeps their position
+// and later users may be served first.
+func (s *Service) OfferRound(ctx context.Context, showtimeID string) (int, error) {
+	userIDs, err := s.rdb.ZRange(ctx, queueKey(showtimeID), 0, roundLimit-1).Result()
+	if err != nil || len(userIDs) == 0 {
+		return 0, err
+	}
+
+	// no holds or offer notifications for showtimes that can't be bought
+	var se *showtime.Error
+	if err := s.checkSales(ctx, showtimeID); errors.As(err, &se) {
+		if showtime.SalesOver(err) {
+			return 0, s.Clear(ctx, showtimeID)
+		}
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	free, err := s.freeSeats(ctx, showtimeID)
+	if err != nil || len(free) == 0 {
+		return 0, err
+	}
+
+	raws, err := s.rdb.HMGet(ctx, entriesKey(showtimeID), userIDs...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	offered := 0
+	for i, uid := range userIDs {
+		if len(free) == 0 {
+			break
+		}
+
+		raw, _ := raws[i].(string)
+		var entry Entry
+		if raw == "" || json.Unmarshal([]byte(raw), &entry) != nil {
+			// orphaned queue member
+			_ = s.rdb.ZRem(ctx, queueKey(showtimeID), uid).Err()
+			continue
+		}
+
+		ok, err := s.offer(ctx, showtimeID, entry, free)
+		if err != nil {
+			return offered, err
+		}
+		if ok {
+			offered++
+		}
+	}
+	return offered, nil
+}
+
+// offer tries each candidate block for entry and places the hold on the
+// first one that locks. Offered seats are removed from free.
+func (s *Service) offer(ctx context.Context, showtimeID string, entry Entry, free map[string]bool) (bool, error) {
+	rid := "waitlist-" + uuid.NewString()
+
+	for _, seatIDs := range s.candidates(entry, free) {
+		locked, conflicted, err := s.seats.LockSeatsWithOptions(ctx, showtimeID, seatIDs, entry.UserID, rid, seatlock.LockOptions{
+			TTL: s.offerTTL,
+		})
+		var violation *seatlock.RuleViolation
+		if errors.As(err, &violation) {
+			// e.g. would strand a single seat: try the next block
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !locked {
+			delete(free, conflicted)
+			continue
+		}
+
+		for _, sid := range seatIDs {
+			delete(free, sid)
+		}
+
+		o := Offer{
+			SeatIDs:   seatIDs,
+			RequestID: rid,
+			ExpiresAt: time.Now().Add(s.offerTTL).Unix(),
+		}
+		b, err := json.Marshal(o)
+		if err != nil {
+			return false, err
+		}
+
+		pipe := s.rdb.TxPipeline()
+		pipe.Set(ctx, offerKey(showtimeID, entry.UserID), b, s.offerTTL)
+		pipe.ZRem(ctx, queueKey(showtimeID), entry.UserID)
+		pipe.HDel(ctx, entriesKey(showtimeID), entry.UserID)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return false, err
+		}
+
+		notify.Publish(ctx, s.rdb, notify.UserEvent{
+			Type:       notify.WaitlistOffer,
+			UserID:     entry.UserID,
+			ShowtimeID: showtimeID,
+			SeatIDs:    seatIDs,
+			RequestID:  rid,
+			ExpiresAt:  o.ExpiresAt,
+		})
+		return true, nil
+	}
+	return false, nil
+}
+
+// candidates lists blocks of entry.PartySize adjacent free seats of the
+// wanted type, front rows first.
+func (s *Service) candidates(entry Entry, free map[string]bool) [][]string {
+	var out [][]string
+	for _, row := range s.seatMap.Rows() {
+		if entry.SeatType != AnySeatType && s.seatMap.RowType(row) != entry.SeatType {
+			continue
+		}
+		for _, seg := range s.seatMap.Segments(row) {
+			for i := 0; i+entry.PartySize <= len(seg); i++ {
+				block := seg[i : i+entry.PartySize]
+				if allFree(block, free) {
+					out = append(out, append([]string(nil), block...))
+				}
+			}
+		}
+	}
+	return out
+}
+
+func allFree(seatIDs []string, free map[string]bool) bool {
+	for _, sid := range seatIDs {
+		if !free[sid] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Service) freeSeats(ctx context.Context, showtimeID string) (map[string]bool, error) {
+	var all []string
+	for _, row := range s.seatMap.Rows() {
+		for _, seg := range s.seatMap.Segments(row) {
+			all = append(all, seg...)
+		}
+	}
+	return s.seats.FreeSeats(ctx, showtimeID, all)
+}
@@ -0,0 +1,123 @@
+package waitlist
+
+import (
+	"cinema/internal/seatlock"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestWaitlist(t *testing.T) (*Service, *seatlock.Service, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	m, err := seatlock.ParseSeatMap("A=SSS;B:premium=SS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seats := seatlock.New(rdb, time.Minute)
+	if err := seats.BeginRebuild(context.Background(), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	return New(rdb, seats, m, 30*time.Second), seats, rdb
+}
+
+func lock(t *testing.T, seats *seatlock.Service, owner string, seatIDs ...string) {
+	t.Helper()
+	ok, _, err := seats.LockSeats(context.Background(), "st1", seatIDs, owner, "r-"+owner)
+	if err != nil || !ok {
+		t.Fatalf("lock %v: ok=%v err=%v", seatIDs, ok, err)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	ctx := context.Background()
+	w, seats, _ := newTestWaitlist(t)
+
+	if _, err := w.Join(ctx, "st1", "u1", 2, "", false); !errors.Is(err, ErrSeatsAvailable) {
+		t.Fatalf("Join with free seats: err = %v", err)
+	}
+	if _, err := w.Join(ctx, "st1", "u1", 1, "balcony", false); !errors.Is(err, ErrInvalidSeatType) {
+		t.Fatalf("Join unknown type: err = %v", err)
+	}
+
+	// premium is sold out, standard still has seats
+	lock(t, seats, "other", "B1", "B2")
+	if _, err := w.Join(ctx, "st1", "u1", 2, "", false); !errors.Is(err, ErrSeatsAvailable) {
+		t.Fatalf("Join any: err = %v, want seats available", err)
+	}
+	if pos, err := w.Join(ctx, "st1", "u1", 2, "premium", false); err != nil || pos != 1 {
+		t.Fatalf("Join premium = %d, %v", pos, err)
+	}
+	if pos, err := w.Join(ctx, "st1", "u2", 1, "premium", false); err != nil || pos != 2 {
+		t.Fatalf("Join second = %d, %v", pos, err)
+	}
+	// joining again updates the entry, keeps the place
+	if pos, err := w.Join(ctx, "st1", "u1", 1, "premium", false); err != nil || pos != 1 {
+		t.Fatalf("Join again = %d, %v", pos, err)
+	}
+	st, err := w.Status(ctx, "st1", "u1")
+	if err != nil || st.Position != 1 || st.Entry.PartySize != 1 {
+		t.Fatalf("Status = %+v, %v", st, err)
+	}
+
+	// members go ahead of everyone already waiting
+	if pos, err := w.Join(ctx, "st1", "vip", 1, "premium", true); err != nil || pos != 1 {
+		t.Fatalf("Join priority = %d, %v", pos, err)
+	}
+	if st, _ := w.Status(ctx, "st1", "u1"); st.Position != 2 {
+		t.Fatalf("u1 position = %d after a priority join", st.Position)
+	}
+}
+
+func TestOfferRoundFIFO(t *testing.T) {
+	ctx := context.Background()
+	w, seats, rdb := newTestWaitlist(t)
+
+	lock(t, seats, "other", "A1", "A2", "A3", "B1", "B2")
+	for _, uid := range []string{"u1", "u2", "u3"} {
+		if _, err := w.Join(ctx, "st1", uid, 2, AnySeatType, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two adjacent seats free up: the first in line gets them
+	if err := seats.ReleaseSeats(ctx, "st1", []string{"B1", "B2"}, "other"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := w.OfferRound(ctx, "st1")
+	if err != nil || n != 1 {
+		t.Fatalf("OfferRound = %d, %v", n, err)
+	}
+
+	st, err := w.Status(ctx, "st1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Position != 0 || st.Offer == nil || !reflect.DeepEqual(st.Offer.SeatIDs, []string{"B1", "B2"}) {
+		t.Fatalf("u1 status = %+v", st)
+	}
+	// the offer is a real hold in u1's name
+	if v, _ := rdb.Get(ctx, "seatlock:st1:B1").Result(); !strings.HasPrefix(v, "u1:"+st.Offer.RequestID) {
+		t.Fatalf("B1 held by %q", v)
+	}
+	if _, err := w.Join(ctx, "st1", "u1", 2, "", false); !errors.Is(err, ErrOfferPending) {
+		t.Fatalf("Join with an offer: err = %v", err)
+	}
+	if st, _ := w.Status(ctx, "st1", "u2"); st.Position != 1 || st.Offer != nil {
+		t.Fatalf("u2 status = %+v, want first in line", st)
+	}
+
+	// no block of two left: nobody else is offered
+	if n, err := w.OfferRound(ctx, "st1"); err != nil || n != 0 {
+		t.Fatalf("second OfferRound = %d, %v", n, err)
+	}
+}
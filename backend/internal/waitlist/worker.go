@@ -0,0 +1,80 @@
+package waitlist
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// full pass over every queue, for seats freed without a seat event
+const roundEvery = 10 * time.Second
+
+// Run offers freed seats until ctx is cancelled: immediately on
+// released/timeout/unblocked seat events, and on a periodic pass over all queues.
+func (s *Service) Run(ctx context.Context) {
+	ps := s.rdb.PSubscribe(ctx, "seat-events:*")
+	defer func() { _ = ps.Close() }()
+	ch := ps.Channel()
+
+	ticker := time.NewTicker(roundEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var ev struct {
+				Type       string `json:"type"`
+				ShowtimeID string `json:"showtime_id"`
+			}
+			if json.Unmarshal([]byte(msg.Payload), &ev) != nil {
+				continue
+			}
+			if ev.Type != "released" && ev.Type != "timeout" && ev.Type != "unblocked" {
+				continue
+			}
+			s.round(ctx, ev.ShowtimeID)
+		case <-ticker.C:
+			s.roundAll(ctx)
+		}
+	}
+}
+
+func (s *Service) roundAll(ctx context.Context) {
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, "waitlist:*", 50).Result()
+		if err != nil {
+			return
+		}
+		for _, k := range keys {
+			s.round(ctx, strings.TrimPrefix(k, "waitlist:"))
+		}
+		cursor = next
+		if cursor == 0 {
+			return
+		}
+	}
+}
+
+func (s *Service) round(ctx context.Context, showtimeID string) {
+	if showtimeID == "" {
+		return
+	}
+	n, err := s.OfferRound(ctx, showtimeID)
+	if err != nil && err != redis.Nil && ctx.Err() == nil {
+		log.Println("waitlist offer round failed:", showtimeID, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("waitlist: %d offer(s) for showtime %s", n, showtimeID)
+	}
+}
@@ -0,0 +1,208 @@
+package waitroom
+
+import (
+	"cinema/internal/auth"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Virtual waiting room for high-demand on-sales. While a showtime's room is
+// active, users take a FIFO ticket and only `capacity` users at a time are
+// admitted; admitted users get a short-lived signed token that seat locking
+// requires.
+//
+//	waitroom:<showtimeId>        HASH  capacity (exists = room active)
+//	waitroomseq:<showtimeId>     INCR  ticket numbers
+//	waitroomq:<showtimeId>       ZSET  member = userId, score = ticket
+//	waitroomseen:<showtimeId>    ZSET  member = userId, score = last seen ms
+//	waitroomin:<showtimeId>      ZSET  member = userId, score = admitted until ms
+
+// a queued user not seen for this long is skipped at admission
+const staleAfter = 30 * time.Second
+
+func roomKey(showtimeID string) string {
+	return fmt.Sprintf("waitroom:%s", showtimeID)
+}
+
+func seqKey(showtimeID string) string {
+	return fmt.Sprintf("waitroomseq:%s", showtimeID)
+}
+
+func queueKey(showtimeID string) string {
+	return fmt.Sprintf("waitroomq:%s", showtimeID)
+}
+
+func seenKey(showtimeID string) string {
+	return fmt.Sprintf("waitroomseen:%s", showtimeID)
+}
+
+func admittedKey(showtimeID string) string {
+	return fmt.Sprintf("waitroomin:%s", showtimeID)
+}
+
+// Status is one user's view of the room.
+type Status struct {
+	Active         bool   `json:"active"`
+	Position       int64  `json:"position,omitempty"` // 1-based while queued
+	Admitted       bool   `json:"admitted"`
+	AdmissionToken string `json:"admission_token,omitempty"`
+	ExpiresAt      int64  `json:"expires_at,omitempty"` // unix seconds, admission end
+}
+
+type Service struct {
+	rdb      *redis.Client
+	jwtSvc   *auth.JWTService
+	admitTTL time.Duration
+}
+
+func New(rdb *redis.Client, jwtSvc *auth.JWTService, admitTTL time.Duration) *Service {
+	return &Service{rdb: rdb, jwtSvc: jwtSvc, admitTTL: admitTTL}
+}
+
+// Open activates the room with capacity concurrently admitted users
+// (or changes the capacity of an active room).
+func (s *Service) Open(ctx context.Context, showtimeID string, capacity int) error {
+	return s.rdb.HSet(ctx, roomKey(showtimeID), "capacity", capacity).Err()
+}
+
+// Close deactivates the room; locking no longer needs admission.
+func (s *Service) Close(ctx context.Context, showtimeID string) error {
+	return s.rdb.Del(ctx,
+		roomKey(showtimeID),
+		seqKey(showtimeID),
+		queueKey(showtimeID),
+		seenKey(showtimeID),
+		admittedKey(showtimeID),
+	).Err()
+}
+
+// Active reports whether the showtime's room is on.
+func (s *Service) Active(ctx context.Context, showtimeID string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, roomKey(showtimeID)).Result()
+	return n == 1, err
+}
+
+// Enter gives userID a ticket (once) and returns their status.
+// Calling it again (polling, WebSocket ticks) keeps the ticket alive.
+func (s *Service) Enter(ctx context.Context, showtimeID, userID string) (*Status, error) {
+	active, err := s.Active(ctx, showtimeID)
+	if err != nil || !active {
+		return &Status{}, err
+	}
+
+	now := time.Now()
+	if until, err := s.rdb.ZScore(ctx, admittedKey(showtimeID), userID).Result(); err == nil && int64(until) > now.UnixMilli() {
+		return s.admitted(showtimeID, userID, time.UnixMilli(int64(until)))
+	}
+
+	if err := s.rdb.ZAdd(ctx, seenKey(showtimeID), redis.Z{Score: float64(now.UnixMilli()), Member: userID}).Err(); err != nil {
+		return nil, err
+	}
+
+	rank, err := s.rdb.ZRank(ctx, queueKey(showtimeID), userID).Result()
+	if err == redis.Nil {
+		keys := []string{seqKey(showtimeID), queueKey(showtimeID), admittedKey(showtimeID)}
+		if _, err := luaTicket.Run(ctx, s.rdb, keys, userID, time.Now().UnixMilli()).Result(); err != nil {
+			return nil, err
+		}
+		rank, err = s.rdb.ZRank(ctx, queueKey(showtimeID), userID).Result()
+		if err == redis.Nil {
+			// admitted between the checks
+			until, zerr := s.rdb.ZScore(ctx, admittedKey(showtimeID), userID).Result()
+			if zerr == nil && int64(until) > time.Now().UnixMilli() {
+				return s.admitted(showtimeID, userID, time.UnixMilli(int64(until)))
+			}
+			return nil, fmt.Errorf("waitroom: no ticket for %s", userID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Status{Active: true, Position: rank + 1}, nil
+}
+
+func (s *Service) admitted(showtimeID, userID string, until time.Time) (*Status, error) {
+	token, err := s.jwtSvc.SignAdmission(userID, showtimeID, until)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		Active:         true,
+		Admitted:       true,
+		AdmissionToken: token,
+		ExpiresAt:      until.Unix(),
+	}, nil
+}
+
+// CheckAdmission verifies token for a lock attempt. No room = always allowed.
+func (s *Service) CheckAdmission(ctx context.Context, showtimeID, userID, token string) (required bool, err error) {
+	active, err := s.Active(ctx, showtimeID)
+	if err != nil || !active {
+		return false, err
+	}
+	return true, s.jwtSvc.VerifyAdmission(token, userID, showtimeID)
+}
+
+// ticket once per user: next sequence number, unless already queued or
+// admitted in the meantime. An admission that has run out (not purged yet)
+// is dropped so the user queues again.
+// KEYS: seq, queue, admitted
+// ARGV: user id, now ms
+var luaTicket = redis.NewScript(`
+local admittedUntil = redis.call("ZSCORE", KEYS[3], ARGV[1])
+if admittedUntil and tonumber(admittedUntil) <= tonumber(ARGV[2]) then
+  redis.call("ZREM", KEYS[3], ARGV[1])
+  admittedUntil = nil
+end
+if redis.call("ZSCORE", KEYS[2], ARGV[1]) or admittedUntil then
+  return 0
+end
+local n = redis.call("INCR", KEYS[1])
+redis.call("ZADD", KEYS[2], n, ARGV[1])
+return n
+`)
+
+// admit frees expired slots, then moves live queue heads into the room.
+// KEYS: queue, seen, admitted, room
+// ARGV: now ms, admitted until ms, stale before ms
+var luaAdmit = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local untilMs = tonumber(ARGV[2])
+local staleBefore = tonumber(ARGV[3])
+
+local capacity = tonumber(redis.call("HGET", KEYS[4], "capacity") or "0")
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now)
+
+local free = capacity - redis.call("ZCARD", KEYS[3])
+local admitted = 0
+while free > 0 do
+  local head = redis.call("ZPOPMIN", KEYS[1])
+  if #head == 0 then
+    break
+  end
+  local uid = head[1]
+  local seen = tonumber(redis.call("ZSCORE", KEYS[2], uid) or "0")
+  redis.call("ZREM", KEYS[2], uid)
+  -- left the page: drop the ticket instead of wasting a slot
+  if seen >= staleBefore then
+    redis.call("ZADD", KEYS[3], untilMs, uid)
+    free = free - 1
+    admitted = admitted + 1
+  end
+end
+return admitted
+`)
+
+// AdmitOnce admits queued users of one showtime up to its capacity.
+func (s *Service) AdmitOnce(ctx context.Context, showtimeID string) (int64, error) {
+	now := time.Now()
+	return luaAdmit.Run(ctx, s.rdb,
+		[]string{queueKey(showtimeID), seenKey(showtimeID), admittedKey(showtimeID), roomKey(showtimeID)},
+		now.UnixMilli(),
+		now.Add(s.admitTTL).UnixMilli(),
+		now.Add(-staleAfter).UnixMilli(),
+	).Int64()
+}
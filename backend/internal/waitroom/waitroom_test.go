@@ -0,0 +1,120 @@
+package waitroom
+
+import (
+	"cinema/internal/auth"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRoom(t *testing.T) (*Service, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(rdb, auth.NewJWTService("test-secret-test-secret-test-secret"), time.Minute), rdb
+}
+
+func TestNoRoom(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestRoom(t)
+
+	st, err := s.Enter(ctx, "st1", "u1")
+	if err != nil || st.Active {
+		t.Fatalf("Enter = %+v, %v; want inactive", st, err)
+	}
+	if required, err := s.CheckAdmission(ctx, "st1", "u1", ""); required || err != nil {
+		t.Fatalf("CheckAdmission = %v, %v; want not required", required, err)
+	}
+}
+
+func TestAdmitFIFO(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestRoom(t)
+	if err := s.Open(ctx, "st1", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, uid := range []string{"u1", "u2", "u1"} {
+		st, err := s.Enter(ctx, "st1", uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// re-entering keeps the ticket
+		if want := int64(i%2 + 1); st.Position != want || st.Admitted {
+			t.Fatalf("Enter %s = %+v, want position %d", uid, st, want)
+		}
+	}
+	if required, err := s.CheckAdmission(ctx, "st1", "u1", ""); !required || err == nil {
+		t.Fatalf("CheckAdmission before admission = %v, %v", required, err)
+	}
+
+	if n, err := s.AdmitOnce(ctx, "st1"); err != nil || n != 1 {
+		t.Fatalf("AdmitOnce = %d, %v", n, err)
+	}
+	st, err := s.Enter(ctx, "st1", "u1")
+	if err != nil || !st.Admitted || st.AdmissionToken == "" {
+		t.Fatalf("u1 = %+v, %v; want admitted", st, err)
+	}
+	if _, err := s.CheckAdmission(ctx, "st1", "u1", st.AdmissionToken); err != nil {
+		t.Fatalf("u1 token rejected: %v", err)
+	}
+	if _, err := s.CheckAdmission(ctx, "st1", "u2", st.AdmissionToken); err == nil {
+		t.Fatal("u1 token accepted for u2")
+	}
+
+	// room is full
+	if n, _ := s.AdmitOnce(ctx, "st1"); n != 0 {
+		t.Fatalf("AdmitOnce over capacity = %d", n)
+	}
+	if st, _ := s.Enter(ctx, "st1", "u2"); st.Position != 1 || st.Admitted {
+		t.Fatalf("u2 = %+v, want first in line", st)
+	}
+
+	if err := s.Close(ctx, "st1"); err != nil {
+		t.Fatal(err)
+	}
+	if required, _ := s.CheckAdmission(ctx, "st1", "u2", ""); required {
+		t.Fatal("admission required after Close")
+	}
+}
+
+func TestAdmitSkipsStale(t *testing.T) {
+	ctx := context.Background()
+	s, rdb := newTestRoom(t)
+	if err := s.Open(ctx, "st1", 1); err != nil {
+		t.Fatal(err)
+	}
+	s.Enter(ctx, "st1", "u1")
+	s.Enter(ctx, "st1", "u2")
+	// u1 left the page long ago
+	rdb.ZAdd(ctx, seenKey("st1"), redis.Z{Score: 1, Member: "u1"})
+
+	if n, err := s.AdmitOnce(ctx, "st1"); err != nil || n != 1 {
+		t.Fatalf("AdmitOnce = %d, %v", n, err)
+	}
+	if st, _ := s.Enter(ctx, "st1", "u2"); !st.Admitted {
+		t.Fatalf("u2 = %+v, want admitted in u1's place", st)
+	}
+	if st, _ := s.Enter(ctx, "st1", "u1"); st.Admitted || st.Position != 1 {
+		t.Fatalf("u1 = %+v, want a fresh ticket", st)
+	}
+}
+
+func TestEnterAfterAdmissionRanOut(t *testing.T) {
+	ctx := context.Background()
+	s, rdb := newTestRoom(t)
+	if err := s.Open(ctx, "st1", 1); err != nil {
+		t.Fatal(err)
+	}
+	// admitted once, not purged by the worker yet
+	rdb.ZAdd(ctx, admittedKey("st1"), redis.Z{Score: 1, Member: "u1"})
+
+	st, err := s.Enter(ctx, "st1", "u1")
+	if err != nil || st.Admitted || st.Position != 1 {
+		t.Fatalf("Enter = %+v, %v; want queued again", st, err)
+	}
+}
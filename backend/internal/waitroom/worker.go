@@ -0,0 +1,45 @@
+package waitroom
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+const admitEvery = time.Second
+
+// Run admits queued users of every active room until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(admitEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.admitAll(ctx)
+		}
+	}
+}
+
+func (s *Service) admitAll(ctx context.Context) {
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, "waitroom:*", 50).Result()
+		if err != nil {
+			return
+		}
+		for _, k := range keys {
+			showtimeID := strings.TrimPrefix(k, "waitroom:")
+			if _, err := s.AdmitOnce(ctx, showtimeID); err != nil && ctx.Err() == nil {
+				log.Println("waitroom admit failed:", showtimeID, err)
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return
+		}
+	}
+}
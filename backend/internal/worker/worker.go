@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"cinema/internal/audit"
+	"cinema/internal/concession"
+	"cinema/internal/config"
+	"cinema/internal/leader"
+	"cinema/internal/loyalty"
+	"cinema/internal/refund"
+	"cinema/internal/repo"
+	"cinema/internal/seatlock"
+	"cinema/internal/seatrepair"
+	"cinema/internal/showtime"
+	"cinema/internal/transfer"
+	"cinema/internal/waitlist"
+	"cinema/internal/waitroom"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Deps are the shared dependencies of the background workers.
+type Deps struct {
+	Cfg         config.Config
+	Redis       *redis.Client
+	Audits      *repo.AuditRepo
+	Waitlist    *waitlist.Service
+	Room        *waitroom.Service
+	Concessions *concession.Service
+	Loyalty     *loyalty.Service
+	Showtimes   *showtime.Service
+	Refunds     *refund.Service
+	Reconciler  *seatrepair.Service
+	Transfers   *transfer.Service
+}
+
+// NewElector builds the lease used to pick the single worker instance.
+func NewElector(d Deps) *leader.Elector {
+	leaseTTL := time.Duration(d.Cfg.LeaderLeaseSeconds) * time.Second
+	return leader.New(d.Redis, "workers", d.Cfg.InstanceID, leaseTTL)
+}
+
+// Start campaigns with elector and runs the singleton workers while leader.
+// The returned channel is closed once ctx is cancelled and every worker has
+// returned (audit writes flushed).
+func Start(ctx context.Context, elector *leader.Elector, d Deps) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, func(ctx context.Context) {
+			runSingletons(ctx, d)
+		})
+	}()
+	return done
+}
+
+func runSingletons(ctx context.Context, d Deps) {
+	var wg sync.WaitGroup
+	wg.Add(11)
+
+	// audit: seat-events:* + booking-events -> audit_logs
+	go func() {
+		defer wg.Done()
+		audit.Run(ctx, d.Redis, d.Audits)
+	}()
+
+	// lock expiry -> timeout events
+	go func() {
+		defer wg.Done()
+		if d.Cfg.SeatExpiryMode == seatlock.ExpiryModeNotify {
+			reconcileEvery := time.Duration(d.Cfg.SeatReconcileIntervalSecs) * time.Second
+			seatlock.StartExpiryListener(ctx, d.Redis, reconcileEvery)
+		} else {
+			seatlock.StartTimeoutSweeper(ctx, d.Redis)
+		}
+	}()
+
+	// freed seats -> waitlist offers
+	go func() {
+		defer wg.Done()
+		d.Waitlist.Run(ctx)
+	}()
+
+	// waiting rooms: admit queued users up to capacity
+	go func() {
+		defer wg.Done()
+		d.Room.Run(ctx)
+	}()
+
+	// concession stock held by ended seat holds -> back to the catalog
+	go func() {
+		defer wg.Done()
+		d.Concessions.Run(ctx)
+	}()
+
+	// booking-events -> loyalty points
+	go func() {
+		defer wg.Done()
+		d.Loyalty.Run(ctx, d.Redis)
+	}()
+
+	// showtime lifecycle: close sales, start, clean up seat keys
+	go func() {
+		defer wg.Done()
+		d.Showtimes.Run(ctx)
+	}()
+
+	// cancelled showtimes -> booking cancellations + refunds
+	go func() {
+		defer wg.Done()
+		d.Refunds.Run(ctx)
+	}()
+
+	// seatbooked: markers vs BOOKED bookings: safe repairs, report the rest
+	go func() {
+		defer wg.Done()
+		d.Reconciler.Run(ctx)
+	}()
+
+	// Redis lost its data: rebuild seatbooked: from Mongo (lock/confirm wait)
+	go func() {
+		defer wg.Done()
+		d.Reconciler.Guard(ctx)
+	}()
+
+	// ticket transfers left ACCEPTING by a crash -> finish or undo them
+	go func() {
+		defer wg.Done()
+		d.Transfers.Run(ctx)
+	}()
+
+	wg.Wait()
+}